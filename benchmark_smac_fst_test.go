@@ -0,0 +1,73 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func init() {
+	initBenchmark()
+	wordFile := "demo/allwords.txt"
+
+	autoComplete, err := NewAutoCompleteFSTF(wordFile, 0, 0)
+	if err != nil {
+		os.Exit(-1)
+	}
+	AcFST = autoComplete
+}
+
+var AcFST AutoCompleteFST
+
+func BenchmarkFSTCompleteWords(b *testing.B) {
+
+	for i := 0; i < b.N; i++ {
+		w := testWords[rand.Intn(wordsInTestData)]
+		AcFST.Complete(w)
+	}
+}
+
+func BenchmarkFSTPrefixes(b *testing.B) {
+
+	for i := 0; i < b.N; i++ {
+		p := testPrefixes[rand.Intn(prefixesInTestData)]
+		AcFST.Complete(p)
+	}
+}
+
+func BenchmarkFSTMemoryAllWords(b *testing.B) {
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewAutoCompleteFSTS(testWords, 0, 0)
+	}
+	reportRetained(b, func() interface{} {
+		autoComplete, _ := NewAutoCompleteFSTS(testWords, 0, 0)
+		return &autoComplete
+	})
+}
+
+func BenchmarkFSTMapAllWords(b *testing.B) {
+
+	tempFile, err := ioutil.TempFile(os.TempDir(), "smac")
+	if err != nil {
+		b.Fatal(err)
+	}
+	fName := tempFile.Name()
+	tempFile.Close()
+	defer os.Remove(fName)
+
+	if err = AcFST.WriteFST(fName); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		autoComplete, _ := NewAutoCompleteFSTM(fName, 0, 0)
+		autoComplete.Close()
+	}
+}
@@ -4,6 +4,7 @@
 package smac
 
 import (
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"testing"
@@ -12,9 +13,7 @@ import (
 func init() {
 	initBenchmark()
 
-	goPath := os.Getenv("GOPATH")
-
-	wordFile := goPath + "/src/github.com/pierods/smac/demo/allwords.txt"
+	wordFile := "demo/allwords.txt"
 
 	ac, err := NewAutoCompleteLinoF(wordFile, 4, 10, 90)
 	if err != nil {
@@ -70,4 +69,53 @@ func BenchmarkLinoMix(b *testing.B) {
 	result = r
 }
 
+func BenchmarkLinoMemoryAllWords(b *testing.B) {
+
+	dictionary := make([]string, len(testWords))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		copy(dictionary, testWords)
+		NewAutoCompleteLinoS(dictionary, 4, 10, 90)
+	}
+	reportRetained(b, func() interface{} {
+		copy(dictionary, testWords)
+		autoComplete, _ := NewAutoCompleteLinoS(dictionary, 4, 10, 90)
+		return &autoComplete
+	})
+}
+
+// Startup benchmarks: building the autocompleter from the dictionary file, and loading it from an index.
+
+func BenchmarkLinoStartupDictionary(b *testing.B) {
+
+	wordFile := "demo/allwords.txt"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewAutoCompleteLinoF(wordFile, 4, 10, 90)
+	}
+}
+
+func BenchmarkLinoStartupIndex(b *testing.B) {
+
+	tempFile, err := ioutil.TempFile(os.TempDir(), "smac")
+	if err != nil {
+		b.Fatal(err)
+	}
+	fName := tempFile.Name()
+	tempFile.Close()
+	defer os.Remove(fName)
+
+	if err = AcLino.WriteIndex(fName); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		autoComplete, _ := NewAutoCompleteLinoE(4, 10, 90)
+		if err = autoComplete.LoadIndex(fName); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 var result []string
@@ -0,0 +1,51 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func init() {
+	initBenchmark()
+	wordFile := "demo/allwords.txt"
+
+	autoComplete, err := NewAutoCompleteRadixF(wordFile, 0, 0)
+	if err != nil {
+		os.Exit(-1)
+	}
+	AcRadix = autoComplete
+}
+
+var AcRadix AutoCompleteRadix
+
+func BenchmarkRadixCompleteWords(b *testing.B) {
+
+	for i := 0; i < b.N; i++ {
+		w := testWords[rand.Intn(wordsInTestData)]
+		AcRadix.Complete(w)
+	}
+}
+
+func BenchmarkRadixPrefixes(b *testing.B) {
+
+	for i := 0; i < b.N; i++ {
+		p := testPrefixes[rand.Intn(prefixesInTestData)]
+		AcRadix.Complete(p)
+	}
+}
+
+func BenchmarkRadixMemoryAllWords(b *testing.B) {
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewAutoCompleteRadixS(testWords, 0, 0)
+	}
+	reportRetained(b, func() interface{} {
+		autoComplete, _ := NewAutoCompleteRadixS(testWords, 0, 0)
+		return &autoComplete
+	})
+}
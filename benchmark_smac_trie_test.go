@@ -4,18 +4,18 @@
 package smac
 
 import (
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"testing"
 )
 
+const benchAlphabet = "abcdefghijklmnopqrstuvwxyz1234567890'/&\""
+
 func init() {
 	initBenchmark()
-	goPath := os.Getenv("GOPATH")
-
-	wordFile := goPath + "/src/github.com/pierods/smac/demo/allwords.txt"
+	wordFile := "demo/allwords.txt"
 
-	benchAlphabet := "abcdefghijklmnopqrstuvwxyz1234567890'/&\""
 	autoComplete, err := NewAutoCompleteTrieF(benchAlphabet, wordFile, 0, 0)
 	if err != nil {
 		os.Exit(-1)
@@ -41,3 +41,109 @@ func BenchmarkTriePrefixes(b *testing.B) {
 		AcTrie.Complete(p)
 	}
 }
+
+// Startup benchmarks: building the trie from the dictionary file, and loading it from an index.
+
+func BenchmarkTrieStartupDictionary(b *testing.B) {
+
+	wordFile := "demo/allwords.txt"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewAutoCompleteTrieF(benchAlphabet, wordFile, 0, 0)
+	}
+}
+
+func BenchmarkTrieStartupIndex(b *testing.B) {
+
+	tempFile, err := ioutil.TempFile(os.TempDir(), "smac")
+	if err != nil {
+		b.Fatal(err)
+	}
+	fName := tempFile.Name()
+	tempFile.Close()
+	defer os.Remove(fName)
+
+	if err = AcTrie.WriteIndex(fName); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		autoComplete, _ := NewAutoCompleteTrieE(benchAlphabet, 0, 0)
+		if err = autoComplete.LoadIndex(fName); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Memory benchmarks (retained-B is the size of the trie). With dense child links, all words took 939MB in 3.1M
+// allocations, and the trie for just 100 mixed-script words took 774MB.
+
+func BenchmarkTrieMemoryAllWords(b *testing.B) {
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewAutoCompleteTrieS(benchAlphabet, testWords, 0, 0)
+	}
+	reportRetained(b, func() interface{} {
+		autoComplete, _ := NewAutoCompleteTrieS(benchAlphabet, testWords, 0, 0)
+		return &autoComplete
+	})
+}
+
+// the mixed-script dictionary spells one word out of two of the test data in cyrillic, and adds an emoji to one word
+// out of ten, so that the alphabet spans from '"' to '😀'.
+var mixedScriptAlphabet = benchAlphabet + "абвгдежзийклмнопрстуфхцчшщъыьэюя😀"
+
+func mixedScriptWords(n int) []string {
+
+	latin := []rune("abcdefghijklmnopqrstuvwxyz")
+	cyrillic := []rune("абцдефгхийклмнопярстужвшыз")
+
+	var words []string
+	for i := 0; i < n && i < wordsInTestData; i++ {
+		word := []rune(testWords[i*(wordsInTestData/n)])
+		if i%2 == 1 {
+			for j, r := range word {
+				for k, l := range latin {
+					if r == l {
+						word[j] = cyrillic[k]
+					}
+				}
+			}
+		}
+		if i%10 == 0 {
+			word = append(word, '😀')
+		}
+		words = append(words, string(word))
+	}
+	return words
+}
+
+func BenchmarkTrieMemoryMixedScript100(b *testing.B) {
+
+	words := mixedScriptWords(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewAutoCompleteTrieS(mixedScriptAlphabet, words, 0, 0)
+	}
+	reportRetained(b, func() interface{} {
+		autoComplete, _ := NewAutoCompleteTrieS(mixedScriptAlphabet, words, 0, 0)
+		return &autoComplete
+	})
+}
+
+func BenchmarkTrieMemoryMixedScript100k(b *testing.B) {
+
+	words := mixedScriptWords(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewAutoCompleteTrieS(mixedScriptAlphabet, words, 0, 0)
+	}
+	reportRetained(b, func() interface{} {
+		autoComplete, _ := NewAutoCompleteTrieS(mixedScriptAlphabet, words, 0, 0)
+		return &autoComplete
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+// convert converts the save files of smac to and from JSON Lines and CSV:
+//
+//	convert -from gob -to jsonl smac.save smac.jsonl
+//	convert -from csv -to gob -engine trie smac.csv smac.save
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pierods/smac"
+)
+
+var formats = map[string]smac.ExportFormat{"jsonl": smac.ExportJSONLines, "csv": smac.ExportCSV}
+
+func main() {
+
+	from := flag.String("from", "gob", "format of the input: gob (a save file), jsonl or csv")
+	to := flag.String("to", "jsonl", "format of the output: gob (a save file), jsonl or csv")
+	engine := flag.String("engine", "LiNo", "engine of the save file written: LiNo, trie, radix or FST")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: convert [-from format] [-to format] [-engine engine] input output")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := convert(flag.Arg(0), flag.Arg(1), *from, *to, *engine); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
+
+func convert(input, output, from, to, engine string) error {
+
+	in, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// output is replaced atomically, so that it can be input too.
+	return smac.SaveFile(output, func(writer io.Writer) error {
+		return transcode(bufio.NewReader(in), writer, from, to, engine)
+	})
+}
+
+func transcode(reader io.Reader, writer io.Writer, from, to, engine string) error {
+
+	switch {
+	case from == "gob" && to != "gob":
+		format, ok := formats[to]
+		if !ok {
+			return errors.New("Unknown format: " + to)
+		}
+		return smac.ExportSave(reader, writer, format)
+	case from != "gob" && to == "gob":
+		format, ok := formats[from]
+		if !ok {
+			return errors.New("Unknown format: " + from)
+		}
+		return smac.ImportSave(reader, writer, format, engine)
+	}
+	return errors.New("Can only convert from gob to jsonl or csv, or from jsonl or csv to gob")
+}
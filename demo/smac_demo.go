@@ -89,7 +89,7 @@ func main() {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
-	autoComplete = &autoCompleteL
+	autoComplete = smac.NewConcurrent(&autoCompleteL)
 
 	watcher := watch(homeFile)
 	defer watcher.Close()
@@ -0,0 +1,10 @@
+module github.com/pierods/smac
+
+go 1.18
+
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	golang.org/x/text v0.14.0
+)
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,68 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+// merge merges the save files of several smac autocompleters into one:
+//
+//	merge -accepts max -learn last -o merged.save node1.save node2.save node3.save
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pierods/smac"
+)
+
+var (
+	acceptsPolicies = map[string]smac.AcceptsPolicy{"sum": smac.SumAccepts, "max": smac.MaxAccepts}
+	learnPolicies   = map[string]smac.LearnPolicy{"last": smac.LastWriterWins, "learn": smac.LearnWins, "unlearn": smac.UnLearnWins}
+)
+
+func main() {
+
+	accepts := flag.String("accepts", "sum", "how to combine accept counts: sum or max")
+	learn := flag.String("learn", "last", "which of a learn and an unlearn of a word wins: last, learn or unlearn")
+	output := flag.String("o", "merged.save", "save file to write")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: merge [-accepts policy] [-learn policy] [-o output] input...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	acceptsPolicy, ok := acceptsPolicies[*accepts]
+	if !ok || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	learnPolicy, ok := learnPolicies[*learn]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := merge(*output, smac.MergeOptions{Accepts: acceptsPolicy, Learn: learnPolicy}, flag.Args()); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
+
+func merge(output string, options smac.MergeOptions, inputs []string) error {
+
+	readers := make([]io.Reader, len(inputs))
+	for i, input := range inputs {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers[i] = bufio.NewReader(f)
+	}
+
+	// output is replaced atomically, so that it can be one of the inputs.
+	return smac.SaveFile(output, func(writer io.Writer) error {
+		return smac.MergeSaves(writer, options, readers...)
+	})
+}
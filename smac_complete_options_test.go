@@ -0,0 +1,146 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCompleteWith(t *testing.T) {
+
+	words := []string{"aaa", "aaab", "aaac", "aaabbb", "aaad", "aaabb", "abbbbb"}
+	lino, _ := NewAutoCompleteLinoS(words, 2, 0, 0)
+	trie, _ := NewAutoCompleteTrieS("abcd", words, 0, 0)
+	radix, _ := NewAutoCompleteRadixS(words, 0, 0)
+	automaton, _ := NewAutoCompleteFSTS(words, 0, 0)
+
+	t.Log("Given the need to set completion options for a single call")
+	{
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			autoComplete.Accept("aaad")
+			all, _ := autoComplete.Complete("aaa")
+			ac, _ := autoComplete.CompleteWith("aaa", CompleteOptions{})
+			if !reflect.DeepEqual(ac, all) || len(all) != 6 || all[0] != "aaad" {
+				t.Log(ac, all)
+				t.Fatal("Should be able to complete as Complete does with no options on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete as Complete does with no options on a "+name+" autocompleter", checkMark)
+
+			unboosted, _ := autoComplete.CompleteWith("aaa", CompleteOptions{NoBoost: true})
+			if len(unboosted) != 6 || unboosted[0] != "aaa" {
+				t.Log(unboosted)
+				t.Fatal("Should be able to not boost accepted words on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to not boost accepted words on a "+name+" autocompleter", checkMark)
+
+			ac, _ = autoComplete.CompleteWith("aaa", CompleteOptions{Limit: 2, NoBoost: true})
+			if !reflect.DeepEqual(ac, unboosted[:2]) {
+				t.Log(ac)
+				t.Fatal("Should be able to limit results on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to limit results on a "+name+" autocompleter", checkMark)
+
+			var paged []string
+			for offset := 0; offset < 8; offset += 2 {
+				ac, _ = autoComplete.CompleteWith("aaa", CompleteOptions{Limit: 2, Offset: offset, NoBoost: true})
+				paged = append(paged, ac...)
+			}
+			if !reflect.DeepEqual(paged, unboosted) {
+				t.Log(paged)
+				t.Fatal("Should be able to page through completions on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to page through completions on a "+name+" autocompleter", checkMark)
+
+			ac, _ = autoComplete.CompleteWith("aaa", CompleteOptions{MinLength: 4, MaxLength: 5})
+			if len(ac) != 4 {
+				t.Log(ac)
+				t.Fatal("Should be able to bound the length of words on a "+name+" autocompleter", ballotX)
+			}
+			for _, word := range ac {
+				if length := utf8.RuneCountInString(word); length < 4 || length > 5 {
+					t.Fatal("Should be able to bound the length of words on a "+name+" autocompleter", ballotX)
+				}
+			}
+			t.Log("Should be able to bound the length of words on a "+name+" autocompleter", checkMark)
+
+			ac, _ = autoComplete.CompleteWith("aaa", CompleteOptions{Radius: 2})
+			if len(ac) >= len(all) {
+				t.Log(ac)
+				t.Fatal("Should be able to override the radius on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to override the radius on a "+name+" autocompleter", checkMark)
+
+			if _, err := autoComplete.CompleteWith("aaa", CompleteOptions{Offset: -1}); err == nil {
+				t.Fatal("Should be able to reject negative options on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to reject negative options on a "+name+" autocompleter", checkMark)
+		}
+
+		ac, _ := lino.CompleteWith("aaa", CompleteOptions{Radius: 2, MinLength: 6})
+		if len(ac) != 0 {
+			t.Log(ac)
+			t.Fatal("Should be able to bound the scan by the radius when bounding the length of words on a LiNo autocompleter", ballotX)
+		}
+		t.Log("Should be able to bound the scan by the radius when bounding the length of words on a LiNo autocompleter", checkMark)
+	}
+}
+
+func TestCompleteScored(t *testing.T) {
+
+	words := []string{"aaa", "aaab", "aaac", "aaabbb", "aaad", "aaabb", "abbbbb"}
+	lino, _ := NewAutoCompleteLinoS(words, 2, 0, 0)
+	trie, _ := NewAutoCompleteTrieS("abcde", words, 0, 0)
+	radix, _ := NewAutoCompleteRadixS(words, 0, 0)
+	automaton, _ := NewAutoCompleteFSTS(words, 0, 0)
+
+	t.Log("Given the need to know how completions matched and were ranked")
+	{
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			autoComplete.Accept("aaad")
+			autoComplete.Accept("aaad")
+			autoComplete.Learn("aaae")
+
+			ac, _ := autoComplete.CompleteWith("aaa", CompleteOptions{})
+			scored, _ := autoComplete.CompleteScored("aaa", CompleteOptions{})
+			if len(scored) != len(ac) {
+				t.Log(scored)
+				t.Fatal("Should be able to complete as CompleteWith does on a "+name+" autocompleter", ballotX)
+			}
+			for i, result := range scored {
+				if result.Word != ac[i] || result.MatchLength != 3 || result.Distance != 0 || result.Learnt != (result.Word == "aaae") {
+					t.Log(result)
+					t.Fatal("Should be able to complete as CompleteWith does on a "+name+" autocompleter", ballotX)
+				}
+			}
+			t.Log("Should be able to complete as CompleteWith does on a "+name+" autocompleter", checkMark)
+
+			if scored[0] != (Result{Word: "aaad", Accepts: 2, Score: 2, MatchLength: 3}) {
+				t.Log(scored[0])
+				t.Fatal("Should be able to score accepted words on a "+name+" autocompleter", ballotX)
+			}
+			scored, _ = autoComplete.CompleteScored("aaad", CompleteOptions{NoBoost: true})
+			if len(scored) != 1 || scored[0].Accepts != 2 || scored[0].Score != 0 {
+				t.Log(scored)
+				t.Fatal("Should be able to score accepted words on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to score accepted words on a "+name+" autocompleter", checkMark)
+
+			ac, _ = autoComplete.CompleteFuzzy("aaab", 1)
+			scored, _ = autoComplete.CompleteScored("aaab", CompleteOptions{MaxEdits: 1})
+			if len(scored) != len(ac) || scored[0].Distance != 0 || scored[0].MatchLength != 4 || scored[len(scored)-1].Distance != 1 {
+				t.Log(scored)
+				t.Fatal("Should be able to report the edit distance of fuzzy completions on a "+name+" autocompleter", ballotX)
+			}
+			for i, result := range scored {
+				if result.Word != ac[i] {
+					t.Log(scored)
+					t.Fatal("Should be able to report the edit distance of fuzzy completions on a "+name+" autocompleter", ballotX)
+				}
+			}
+			t.Log("Should be able to report the edit distance of fuzzy completions on a "+name+" autocompleter", checkMark)
+		}
+	}
+}
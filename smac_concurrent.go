@@ -0,0 +1,136 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io"
+	"sync"
+)
+
+// concurrentAutoComplete guards an AutoComplete with a read/write lock: completions and saves, which only read the
+// engine, run in parallel, while Accept, Learn, UnLearn and Retrieve are serialized.
+type concurrentAutoComplete struct {
+	lock         sync.RWMutex
+	autoComplete AutoComplete
+}
+
+// NewConcurrent returns an AutoComplete that is safe for concurrent use, wrapping ac. ac must not be used directly
+// afterwards.
+func NewConcurrent(ac AutoComplete) AutoComplete {
+	return &concurrentAutoComplete{
+		autoComplete: ac,
+	}
+}
+
+// Accept : see description in AutoComplete interface
+func (c *concurrentAutoComplete) Accept(acceptedWord string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.Accept(acceptedWord)
+}
+
+// Learn : see description in AutoComplete interface
+func (c *concurrentAutoComplete) Learn(word string, keys ...string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.Learn(word, keys...)
+}
+
+// LearnWithPayload : see description in AutoComplete interface
+func (c *concurrentAutoComplete) LearnWithPayload(word string, payload interface{}, keys ...string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.LearnWithPayload(word, payload, keys...)
+}
+
+// SetWeight : see description in AutoComplete interface
+func (c *concurrentAutoComplete) SetWeight(word string, weight float64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.SetWeight(word, weight)
+}
+
+// UnLearn : see description in AutoComplete interface
+func (c *concurrentAutoComplete) UnLearn(word string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.UnLearn(word)
+}
+
+// Complete : see description in AutoComplete interface
+func (c *concurrentAutoComplete) Complete(word string) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.Complete(word)
+}
+
+// CompleteWith : see description in AutoComplete interface
+func (c *concurrentAutoComplete) CompleteWith(stem string, opts CompleteOptions) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.CompleteWith(stem, opts)
+}
+
+// CompleteFuzzy : see description in AutoComplete interface
+func (c *concurrentAutoComplete) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.CompleteFuzzy(stem, maxEdits)
+}
+
+// CompleteScored : see description in AutoComplete interface
+func (c *concurrentAutoComplete) CompleteScored(stem string, opts CompleteOptions) ([]Result, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.CompleteScored(stem, opts)
+}
+
+// CompleteQuery : see description in AutoComplete interface
+func (c *concurrentAutoComplete) CompleteQuery(query string) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.CompleteQuery(query)
+}
+
+// AcceptSequence : see description in AutoComplete interface
+func (c *concurrentAutoComplete) AcceptSequence(words []string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.AcceptSequence(words)
+}
+
+// Predict : see description in AutoComplete interface
+func (c *concurrentAutoComplete) Predict(previousWords []string, stem string) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.Predict(previousWords, stem)
+}
+
+// Save : see description in AutoComplete interface
+func (c *concurrentAutoComplete) Save(fileName string) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.Save(fileName)
+}
+
+// SaveTo : see description in AutoComplete interface
+func (c *concurrentAutoComplete) SaveTo(writer io.Writer) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.autoComplete.SaveTo(writer)
+}
+
+// Retrieve : see description in AutoComplete interface
+func (c *concurrentAutoComplete) Retrieve(fileName string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.Retrieve(fileName)
+}
+
+// RetrieveFrom : see description in AutoComplete interface
+func (c *concurrentAutoComplete) RetrieveFrom(reader io.Reader) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.autoComplete.RetrieveFrom(reader)
+}
@@ -0,0 +1,80 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+// hammer runs Complete, CompleteFuzzy, Learn, UnLearn, Accept and Save on autoComplete from several goroutines at
+// once. Run with -race to detect unguarded accesses.
+func hammer(t *testing.T, autoComplete AutoComplete) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				word := fmt.Sprintf("chair%d", (g*200+i)%50)
+				switch i % 6 {
+				case 0:
+					autoComplete.Learn(word)
+				case 1:
+					autoComplete.Accept(word)
+				case 2:
+					autoComplete.UnLearn(word)
+				case 3:
+					autoComplete.CompleteFuzzy("chiar", 1)
+				case 4:
+					autoComplete.Save(fmt.Sprintf("%s/%d", dir, g))
+				default:
+					autoComplete.Complete("chair")
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if err = autoComplete.Learn("table"); err != nil {
+		t.Fatal("Should be able to use the autocompleter after concurrent use", ballotX)
+	}
+	if ac, _ := autoComplete.Complete("tab"); len(ac) != 1 {
+		t.Fatal("Should be able to use the autocompleter after concurrent use", ballotX)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+
+	words := []string{"chair", "chairman", "chairperson", "chalk", "cheer"}
+
+	t.Log("Given the need to use an autocompleter from many goroutines")
+	{
+		lino, _ := NewAutoCompleteLinoS(words, 3, 0, 0)
+		hammer(t, NewConcurrent(&lino))
+		t.Log("Should be able to use a LiNo autocompleter concurrently", checkMark)
+
+		trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz0123456789", words, 0, 0)
+		hammer(t, NewConcurrent(&trie))
+		t.Log("Should be able to use a trie autocompleter concurrently", checkMark)
+
+		radix, _ := NewAutoCompleteRadixS(words, 0, 0)
+		hammer(t, NewConcurrent(&radix))
+		t.Log("Should be able to use a radix autocompleter concurrently", checkMark)
+
+		automaton, _ := NewAutoCompleteFSTS(words, 0, 0)
+		hammer(t, NewConcurrent(&automaton))
+		t.Log("Should be able to use an FST autocompleter concurrently", checkMark)
+	}
+}
@@ -4,6 +4,11 @@
 // Package smac is a small autocomplete engine with an emphasis on simplicity and performance.
 package smac
 
+import (
+	"io"
+	"time"
+)
+
 // The default result size (number of hits for a given stem) and radius (max length of words the autocompleter will
 // descend to while searching)
 const (
@@ -21,8 +26,31 @@ type AutoComplete interface {
 	Accept(acceptedWord string) error
 
 	// Learn will add a word to an autocompleter. If the word is also accepted for completion, Accept should also be called
-	// on the same word
-	Learn(word string) error
+	// on the same word.
+	// If keys are given, word is an entry matched by each of keys instead of by itself ("Dr. Strangelove (1964)" by
+	// "strangelove" and "dr strangelove"), which is completed once whichever keys match. UnLearn of the entry applies to
+	// all of its keys. The entry has accepts of its own, which do not promote the other words its keys match and are
+	// dropped when it is unlearnt.
+	Learn(word string, keys ...string) error
+
+	// LearnWithPayload is like Learn, also attaching payload to word, to be returned along with it by CompleteScored. If
+	// word is already in the dictionary, its payload is replaced. Words sharing a key share its payload. Payloads are
+	// saved by Save with encoding/gob, so their types must be registered with gob.Register, unless they are basic types.
+	LearnWithPayload(word string, payload interface{}, keys ...string) error
+
+	// SetWeight replaces the static weight of word (see Record), which ranks it among the words with the same score.
+	// Words sharing a key share its weight. The weights set are saved by Save, while the ones of the bootstrap dictionary
+	// are not.
+	SetWeight(word string, weight float64) error
+
+	// AcceptSequence teaches the n-gram model of an autocompleter that words were accepted one after the other, counting
+	// each word after the one and the two before it. AcceptCorpus teaches it a whole text.
+	AcceptSequence(words []string) error
+
+	// Predict is like Complete, for a stem typed after previousWords: the words seen after the last two of previousWords
+	// in the accepted sequences come first, by how likely they are to follow them, then the ones seen after the last one,
+	// then the completions of Complete. A stem of "" predicts the next word.
+	Predict(previousWords []string, stem string) ([]string, error)
 
 	// UnLearn will remove a word from an autocompleter.
 	UnLearn(word string) error
@@ -31,13 +59,135 @@ type AutoComplete interface {
 	// constructing autoComplete, and the max length of matches depends on the value of the radius parameter used.
 	// Matches are returned by default in order of length first and alpabetical second. The exceptions are words that were previously accepted as completions
 	// (frequently used words) which bubble up to the top of the list, in order of frequency first and alphabetical second.
+	// Among the words with the same accepts, the ones with a weight (see Record) come first, heaviest first.
+	// WithRanker replaces accept counts with a score of one's own. Accepted and weighted words are looked for among all
+	// the matches within the radius, however far down the list they are.
 	Complete(word string) ([]string, error)
 
-	// Save will save to file everything an autocompleter has learnt, which is, new words, removed words and word accepts.
+	// CompleteWith is like Complete, with opts overriding for a single call the result size and radius the
+	// autocompleter was constructed with, and adding paging, length bounds and the choice of not boosting accepted words.
+	CompleteWith(stem string, opts CompleteOptions) ([]string, error)
+
+	// CompleteFuzzy returns a slice of words whose prefix is within maxEdits edits of a stem word, so that a mistyped stem
+	// ("chiar") still yields completions ("chair", "chairman"...). An edit is the insertion, deletion or substitution of a rune,
+	// or the transposition of two adjacent runes. Matches are returned closest first; within the same distance they are
+	// ordered as in Complete. A maxEdits of 0 behaves like Complete.
+	CompleteFuzzy(stem string, maxEdits int) ([]string, error)
+
+	// CompleteScored is like CompleteWith, returning along with every word how it matched and was ranked.
+	CompleteScored(stem string, opts CompleteOptions) ([]Result, error)
+
+	// CompleteQuery completes a query of several tokens ("red running sh") against a dictionary of phrases: the last
+	// token is completed as a stem, and only the phrases holding each of the other tokens as a whole are returned. All
+	// the phrases holding the longest of the other tokens are looked at, regardless of the radius, and ranked by the
+	// Ranker of the autocompleter, accepted phrases first by default. Query and phrases are tokenized by the Tokenizer
+	// of the autocompleter, or by BoundaryTokenizer; without WithTokenizer, phrases are only found from their start, and
+	// the query is completed as a whole, as a stem of them.
+	CompleteQuery(query string) ([]string, error)
+
+	// Save will save to file everything an autocompleter has learnt, which is, new words, removed words, word accepts and
+	// the n-gram model.
 	// It is up to the client to decide when to call Save (possibly just before shutdown).
+	// The file is replaced atomically: should saving fail, or the process crash, the previous file is left as it was.
 	Save(fileName string) error
 
+	// SaveTo writes what Save saves to writer.
+	SaveTo(writer io.Writer) error
+
 	// Retrieve will re-teach an autocompleter that has just been created all the learnt words, deleted words and accepted words.
 	// It is up to the client to decide when to call Retrieve (possibly just after initialization)
 	Retrieve(fileName string) error
+
+	// RetrieveFrom re-teaches an autocompleter what SaveTo wrote to a writer, reading it from reader.
+	RetrieveFrom(reader io.Reader) error
+}
+
+// CompleteOptions are the options of a single call to CompleteWith. The zero value completes as Complete does.
+type CompleteOptions struct {
+	// Limit is the max number of words returned. If 0, the result size of the autocompleter is used.
+	Limit int
+	// Radius is the radius to use instead of the one of the autocompleter, if not 0.
+	Radius int
+	// Offset is the number of words to skip, to page through completions: the words returned are the ones Complete
+	// would return from Offset on, with a result size of Offset+Limit. Unless NoBoost is set, accepted words further
+	// down bubble up as pages are turned, and may show up on more than one page.
+	Offset int
+	// MinLength and MaxLength bound the length in runes of the words returned. A MaxLength of 0 means no bound.
+	MinLength int
+	MaxLength int
+	// NoBoost keeps accepted words in their place, instead of bubbling them up to the top of the list.
+	NoBoost bool
+	// MaxEdits, if not 0, completes the prefixes within MaxEdits edits of the stem, as CompleteFuzzy does.
+	MaxEdits int
+}
+
+// Result is a completion returned by CompleteScored.
+type Result struct {
+	Word string
+	// Accepts is the number of times Word was accepted.
+	Accepts int
+	// Score is the weight Word was ranked by among the words at the same Distance: the score the Ranker of the
+	// autocompleter gave it (by default its accept count, decayed if there is a half-life), or 0 if NoBoost was set.
+	Score float64
+	// MatchLength is the length in runes of the normalized prefix of Word that matched the stem.
+	MatchLength int
+	// Learnt tells whether Word was learnt, as opposed to coming from the bootstrap dictionary.
+	Learnt bool
+	// Distance is the number of edits between the matched prefix and the stem, for fuzzy completions.
+	Distance int
+	// Payload is the payload Word was loaded or learnt with, if any.
+	Payload interface{}
+	// Infix tells whether Word matched from one of its tokens rather than from its start (see WithTokenizer).
+	Infix bool
+}
+
+// Record is an entry of a dictionary: a word, the payload it carries (see LearnWithPayload), the keys it is matched
+// by, if not by itself (see Learn), and its static weight, such as its frequency in a corpus. Among the words ranked
+// with the same score, the heaviest come first; words sharing a key share the highest of their weights.
+type Record struct {
+	Word    string
+	Payload interface{}
+	Keys    []string
+	Weight  float64
+}
+
+// Normalizer maps a word to the key it is matched by. Words sharing a key are completed together, and are returned
+// in the form they were learnt in. A Normalizer must preserve prefixes: the key of a prefix of a word must be a prefix
+// of the key of the word.
+type Normalizer interface {
+	Normalize(word string) string
+}
+
+// Ranker scores the words found for a stem (see WithRanker). Complete returns the words with the highest score first, and
+// the words with the same score by weight (see Record), then in the order it found them in. A Ranker must be safe for
+// concurrent use.
+type Ranker interface {
+	Rank(candidate Candidate) float64
+}
+
+// Candidate is a word found for a stem, to be scored by a Ranker.
+type Candidate struct {
+	Word string
+	// Key is the normalized key Word was found under.
+	Key string
+	// Length is the length of Word in runes.
+	Length int
+	// Accepts is the number of times Word was accepted.
+	Accepts int
+	// Decayed is Accepts decayed by the half-life of the autocompleter (see WithHalfLife), or Accepts if it has none.
+	Decayed float64
+	// LastAccepted is the time Word was last accepted at, zero if it never was or if the autocompleter has no half-life.
+	LastAccepted time.Time
+	// MatchLength is the length in runes of the normalized prefix of Word that matched the stem.
+	MatchLength int
+	// Learnt tells whether Word was learnt, as opposed to coming from the bootstrap dictionary.
+	Learnt bool
+	// Weight is the static weight of Word (see Record).
+	Weight float64
+}
+
+// Tokenizer splits a word into its tokens, in order, for completion to match the word from any of them on (see
+// WithTokenizer).
+type Tokenizer interface {
+	Tokenize(word string) []string
 }
@@ -0,0 +1,121 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"math"
+	"time"
+)
+
+// decayedAccepts is an accept count that halves every half-life: Score is the count as of At.
+type decayedAccepts struct {
+	Score float64
+	At    time.Time
+}
+
+// decay keeps the decayed accept counts of the keys of an autocompleter, if it was given a half-life (see
+// WithHalfLife). The plain accept counts are kept anyway, to be returned by CompleteScored.
+type decay struct {
+	halfLife time.Duration
+	clock    func() time.Time
+	accepts  map[string]decayedAccepts
+}
+
+func newDecay(cfg config) decay {
+
+	clock := cfg.clock
+	if clock == nil {
+		clock = time.Now
+	}
+	return decay{
+		halfLife: cfg.halfLife,
+		clock:    clock,
+	}
+}
+
+// decayed returns accepts as of now.
+func (d *decay) decayed(accepts decayedAccepts, now time.Time) float64 {
+
+	if accepts.Score == 0 {
+		return 0
+	}
+	return accepts.Score * math.Exp2(-float64(now.Sub(accepts.At))/float64(d.halfLife))
+}
+
+// accept adds an accept to key.
+func (d *decay) accept(key string) {
+
+	if d.halfLife == 0 {
+		return
+	}
+	if d.accepts == nil {
+		d.accepts = make(map[string]decayedAccepts)
+	}
+	now := d.clock()
+	d.accepts[key] = decayedAccepts{
+		Score: d.decayed(d.accepts[key], now) + 1,
+		At:    now,
+	}
+}
+
+// score returns the score key is ranked by: accepts, its accept count, or its decayed accept count if there is a
+// half-life.
+func (d *decay) score(key string, accepts int) float64 {
+
+	if d.halfLife == 0 {
+		return float64(accepts)
+	}
+	return d.decayed(d.accepts[key], d.clock())
+}
+
+// candidate returns the Candidate of the words of key, with accepts accepts, for a Ranker to score.
+func (d *decay) candidate(key string, accepts int) Candidate {
+
+	candidate := Candidate{
+		Key:     key,
+		Accepts: accepts,
+		Decayed: d.score(key, accepts),
+	}
+	if accepted, exists := d.accepts[key]; exists {
+		candidate.LastAccepted = accepted.At
+	}
+	return candidate
+}
+
+// get returns the decayed accept count of key to be saved, nil if there is none.
+func (d *decay) get(key string) *decayedAccepts {
+
+	if accepts, exists := d.accepts[key]; exists {
+		return &accepts
+	}
+	return nil
+}
+
+// set sets the decayed accept count of key, as retrieved.
+func (d *decay) set(key string, accepts decayedAccepts) {
+
+	if d.halfLife == 0 {
+		return
+	}
+	if d.accepts == nil {
+		d.accepts = make(map[string]decayedAccepts)
+	}
+	d.accepts[key] = accepts
+}
+
+// forget drops the decayed accept count of key, once key is removed.
+func (d *decay) forget(key string) {
+	delete(d.accepts, key)
+}
+
+// copy returns a copy of d, to be modified by a fork.
+func (d decay) copy() decay {
+
+	copied := d
+	copied.accepts = make(map[string]decayedAccepts, len(d.accepts))
+	for key, accepts := range d.accepts {
+		copied.accepts[key] = accepts
+	}
+	return copied
+}
@@ -0,0 +1,92 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func decayEngines(clock func() time.Time) map[string]AutoComplete {
+
+	words := []string{"yak", "yam", "yes"}
+	options := []Option{WithHalfLife(time.Hour), WithClock(clock)}
+	lino, _ := NewAutoCompleteLinoS(words, 2, 0, 0, options...)
+	trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz", words, 0, 0, options...)
+	radix, _ := NewAutoCompleteRadixS(words, 0, 0, options...)
+	automaton, _ := NewAutoCompleteFSTS(words, 0, 0, options...)
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}
+}
+
+func TestDecay(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Log("Given the need to rank recently accepted words first")
+	{
+		autoCompletes := decayEngines(clock)
+		for _, autoComplete := range autoCompletes {
+			for i := 0; i < 4; i++ {
+				autoComplete.Accept("yam")
+			}
+		}
+		now = now.Add(3 * time.Hour)
+		for name, autoComplete := range autoCompletes {
+			autoComplete.Accept("yak")
+
+			results, _ := autoComplete.CompleteScored("ya", CompleteOptions{})
+			if len(results) != 2 || results[0].Word != "yak" || results[0].Score != 1 || results[1].Score != 0.5 || results[1].Accepts != 4 {
+				t.Log(results)
+				t.Fatal("Should be able to rank words by their decayed accepts on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank words by their decayed accepts on a "+name+" autocompleter", checkMark)
+
+			autoComplete.Accept("yak")
+			results, _ = autoComplete.CompleteScored("yak", CompleteOptions{})
+			if len(results) != 1 || results[0].Score != 2 || results[0].Accepts != 2 {
+				t.Log(results)
+				t.Fatal("Should be able to add accepts to a decayed score on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to add accepts to a decayed score on a "+name+" autocompleter", checkMark)
+		}
+
+		retrieved := decayEngines(clock)
+		for name, autoComplete := range autoCompletes {
+			fileName := dir + "/" + name
+			if err = autoComplete.Save(fileName); err != nil {
+				t.Fatal(err)
+			}
+		}
+		now = now.Add(time.Hour)
+		for name, autoComplete := range retrieved {
+			if err = autoComplete.Retrieve(dir + "/" + name); err != nil {
+				t.Fatal(err)
+			}
+			results, _ := autoComplete.CompleteScored("ya", CompleteOptions{})
+			if len(results) != 2 || results[0].Word != "yak" || results[0].Score != 1 || results[1].Score != 0.25 {
+				t.Log(results)
+				t.Fatal("Should be able to keep decaying accepts after a restart on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to keep decaying accepts after a restart on a "+name+" autocompleter", checkMark)
+
+			autoComplete.UnLearn("yak")
+			autoComplete.Learn("yak")
+			results, _ = autoComplete.CompleteScored("yak", CompleteOptions{})
+			if len(results) != 1 || results[0].Score != 0 {
+				t.Log(results)
+				t.Fatal("Should be able to forget the decayed accepts of unlearnt words on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to forget the decayed accepts of unlearnt words on a "+name+" autocompleter", checkMark)
+		}
+	}
+}
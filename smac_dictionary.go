@@ -0,0 +1,125 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// records streams the records of a dictionary to add, and returns the first error add returns.
+type records func(add func(record Record) error) error
+
+// recordSlice returns the records of dictionary.
+func recordSlice(dictionary []Record) records {
+	return func(add func(record Record) error) error {
+		for _, record := range dictionary {
+			if err := add(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// readerRecords returns the records of the dictionary read from reader (see readRecords).
+func readerRecords(reader io.Reader, cfg config) records {
+	return func(add func(record Record) error) error {
+		return readRecords(reader, cfg, add)
+	}
+}
+
+// readDictionary reads the dictionary file fileName, passing each of its records to add (see readRecords).
+func readDictionary(fileName string, cfg config, add func(record Record) error) error {
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return readRecords(f, cfg, add)
+}
+
+// readRecords reads a dictionary from reader, passing each of its records to add: a record per line, or per CSV record
+// with WithCSV. gzip and bzip2 input is decompressed. Comments and, with WithSkipBlankLines, blank lines are skipped;
+// records without a word are an error. Errors are prefixed by the number of the line they were found at.
+func readRecords(reader io.Reader, cfg config, add func(record Record) error) error {
+
+	buffered := bufio.NewReader(reader)
+	magic, _ := buffered.Peek(3)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		reader = bzip2.NewReader(buffered)
+	default:
+		reader = buffered
+	}
+
+	if cfg.csv {
+		csvReader := csv.NewReader(reader)
+		csvReader.FieldsPerRecord = -1
+		for {
+			fields, err := csvReader.Read()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			line, _ := csvReader.FieldPos(0)
+			if cfg.isComment(fields[0]) {
+				continue
+			}
+			record, err := cfg.csvRecord(fields)
+			if err != nil {
+				return lineError(line, err)
+			}
+			if err = addRecord(record, add); err != nil {
+				return lineError(line, err)
+			}
+		}
+	}
+
+	lineScanner := bufio.NewScanner(reader)
+	for line := 1; lineScanner.Scan(); line++ {
+		text := lineScanner.Text()
+		if cfg.isComment(text) || (cfg.skipBlankLines && strings.TrimSpace(text) == "") {
+			continue
+		}
+		record, err := cfg.record(text)
+		if err != nil {
+			return lineError(line, err)
+		}
+		if err = addRecord(record, add); err != nil {
+			return lineError(line, err)
+		}
+	}
+	return lineScanner.Err()
+}
+
+func addRecord(record Record, add func(record Record) error) error {
+
+	if len(record.Word) == 0 {
+		return errors.New("Empty word in dictionary")
+	}
+	return add(record)
+}
+
+func lineError(line int, err error) error {
+	return errors.New("Line " + strconv.Itoa(line) + ": " + err.Error())
+}
@@ -3,14 +3,85 @@
 
 package smac
 
+import (
+	"encoding/gob"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
 type wordAccepts struct {
 	Word    string
 	Accepts int
+	// New tells a word the autocompleter has learnt from a word of its dictionary.
+	New     bool
+	Payload interface{}
+	Keys    []string
+	// Weight, if not nil, is the weight Word was given by SetWeight.
+	Weight *float64
+	// Decay is the decayed accept count of Word, with a half-life (see WithHalfLife).
+	Decay *decayedAccepts
+	// Context, if not empty, makes the record a count of the n-gram model: the times Word was seen after Context.
+	Context []string
+	// Changed is when Word was last learnt or unlearnt, the zero time if it is not known.
+	Changed time.Time
+	// SetAccepts makes Accepts replace the accept count of Word even if it is 0, as Import does with ImportReplace.
+	SetAccepts bool
+}
+
+// wordEncoder encodes the wordAccepts written by Save, keeping the first error, and the words written.
+type wordEncoder struct {
+	enc   *gob.Encoder
+	err   error
+	words map[string]bool
+}
+
+func (encoder *wordEncoder) encode(wA wordAccepts) {
+	if encoder.err == nil {
+		encoder.err = encoder.enc.Encode(wA)
+	}
+	if len(wA.Context) == 0 {
+		if encoder.words == nil {
+			encoder.words = make(map[string]bool)
+		}
+		encoder.words[wA.Word] = true
+	}
+}
+
+// wordRecords returns the records of a dictionary of words.
+func wordRecords(dictionary []string) []Record {
+	records := make([]Record, len(dictionary))
+	for i, word := range dictionary {
+		records[i].Word = word
+	}
+	return records
+}
+
+// recordWords returns the words of records.
+func recordWords(records []Record) []string {
+	words := make([]string, len(records))
+	for i, record := range records {
+		words[i] = record.Word
+	}
+	return words
 }
+
+// wordHit is a word in a sOLILI. Hits are ordered by weight, and then by order of insertion into a topHits, while the
+// other fields are carried through to Result.
 type wordHit struct {
-	word    string
-	accepts int
-	next    *wordHit
+	word   string
+	weight float64
+	// staticWeight is the weight of the word (see Record), which orders the hits of the same weight.
+	staticWeight float64
+	accepts      int
+	learnt       bool
+	matchLength  int
+	distance     int
+	infix        bool
+	payload      interface{}
+	order        int
+	next         *wordHit
 }
 
 type sOLILI struct {
@@ -18,37 +89,17 @@ type sOLILI struct {
 	end   *wordHit
 }
 
-func (list *sOLILI) insert(word string, accepts int) {
-	hit := &wordHit{
-		word:    word,
-		accepts: accepts,
-	}
-	if list.start == nil {
-		list.start = hit
-		list.end = hit
-		return
-	}
-
-	if accepts == 0 {
-		list.end.next = hit
-		list.end = hit
+// append appends the hits of other after the ones of list, whatever their weight.
+func (list *sOLILI) append(other sOLILI) {
+	if other.start == nil {
 		return
 	}
-
-	if hit.accepts > list.start.accepts {
-		hit.next = list.start
-		list.start = hit
+	if list.start == nil {
+		*list = other
 		return
 	}
-	cursor := list.start
-	for cursor.next != nil {
-		if hit.accepts > cursor.next.accepts {
-			break
-		}
-		cursor = cursor.next
-	}
-	hit.next = cursor.next
-	cursor.next = hit
+	list.end.next = other.start
+	list.end = other.end
 }
 
 func (list *sOLILI) flush() []string {
@@ -78,3 +129,334 @@ func (list *sOLILI) flushL(limit int) []string {
 	}
 	return slice
 }
+
+// surfaceForms holds the forms a normalized key was learnt in, in order of learning. A nil surfaceForms stands for the
+// key itself, which spares memory in the common case of words being their own key.
+type surfaceForms []string
+
+func (forms surfaceForms) list(key string) []string {
+	if forms == nil {
+		return []string{key}
+	}
+	return forms
+}
+
+func (forms surfaceForms) contains(key, form string) bool {
+	for _, f := range forms.list(key) {
+		if f == form {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds form to the forms of key, and reports whether it was not there already.
+func (forms *surfaceForms) add(key, form string) bool {
+	if forms.contains(key, form) {
+		return false
+	}
+	list := forms.list(key)
+	*forms = append(list[:len(list):len(list)], form)
+	return true
+}
+
+// remove removes form from the forms of key, and reports whether it was there.
+func (forms *surfaceForms) remove(key, form string) bool {
+	list := forms.list(key)
+	for i, f := range list {
+		if f == form {
+			remaining := append(append([]string{}, list[:i]...), list[i+1:]...)
+			if len(remaining) == 1 && remaining[0] == key {
+				remaining = nil
+			}
+			*forms = remaining
+			return true
+		}
+	}
+	return false
+}
+
+// results returns up to limit hits of list as results.
+func (list *sOLILI) results(limit int) []Result {
+
+	results := []Result{}
+	for cursor := list.start; cursor != nil && len(results) < limit; cursor = cursor.next {
+		results = append(results, Result{
+			Word:        cursor.word,
+			Accepts:     cursor.accepts,
+			Score:       cursor.weight,
+			MatchLength: cursor.matchLength,
+			Learnt:      cursor.learnt,
+			Distance:    cursor.distance,
+			Payload:     cursor.payload,
+			Infix:       cursor.infix,
+		})
+	}
+	return results
+}
+
+// entryKeys returns the distinct keys the entry word is matched by, normalized by normalizer: keys if any are given, or
+// the key of word.
+func entryKeys(normalizer Normalizer, word string, keys []string) ([]string, error) {
+
+	if len(keys) == 0 {
+		keys = []string{word}
+	}
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		key = normalizer.Normalize(key)
+		if key == "" {
+			return nil, errors.New("Empty word")
+		}
+		if !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, key)
+		}
+	}
+	return normalized, nil
+}
+
+// tokenKeys returns the keys of word when it is tokenized by tokenizer (see WithTokenizer): word itself, and its tokens
+// from each one but the first on, joined by spaces. It returns nil if there is no tokenizer or a single token.
+func tokenKeys(tokenizer Tokenizer, word string) []string {
+
+	if tokenizer == nil {
+		return nil
+	}
+	tokens := tokenizer.Tokenize(word)
+	if len(tokens) < 2 {
+		return nil
+	}
+	keys := []string{word}
+	for i := 1; i < len(tokens); i++ {
+		keys = append(keys, strings.Join(tokens[i:], " "))
+	}
+	return keys
+}
+
+// entryHits collects the k heaviest hits of a completion, completing each entry once and ranking the words matched from
+// one of their tokens below the others.
+type entryHits struct {
+	words   topHits
+	infixes topHits
+	seen    entrySet
+}
+
+func newEntryHits(k int) entryHits {
+	return entryHits{
+		words:   newTopHits(k),
+		infixes: newTopHits(k),
+	}
+}
+
+// insert inserts hit, for a form found under key on completing stem, and reports whether it did. An entry matched by
+// its own key as well is matched from a token when found under another key, and is skipped if its own key matches too.
+func (hits *entryHits) insert(hit *wordHit, key, stem string, normalizer Normalizer, entries map[string][]string) bool {
+
+	if keys, isEntry := entries[hit.word]; isEntry {
+		own := normalizer.Normalize(hit.word)
+		if key != own && containsString(keys, own) {
+			if strings.HasPrefix(own, stem) {
+				return false
+			}
+			hit.infix = true
+		}
+	}
+	if !hits.seen.first(hit.word, entries) {
+		return false
+	}
+	if hit.infix {
+		hits.infixes.insert(hit)
+	} else {
+		hits.words.insert(hit)
+	}
+	return true
+}
+
+// list returns the hits collected, the ones matched from a token last.
+func (hits *entryHits) list() sOLILI {
+	words := hits.words.list()
+	words.append(hits.infixes.list())
+	return words
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// entrySet holds the entries completed so far, as an entry is reached through each of its keys.
+type entrySet map[string]bool
+
+// first reports whether form is not an entry of entries, or is one not seen before.
+func (seen *entrySet) first(form string, entries map[string][]string) bool {
+
+	if _, isEntry := entries[form]; !isEntry {
+		return true
+	}
+	if (*seen)[form] {
+		return false
+	}
+	if *seen == nil {
+		*seen = make(entrySet)
+	}
+	(*seen)[form] = true
+	return true
+}
+
+// copyEntries copies the keys of entries. Slices of keys are never modified, so they are shared.
+func copyEntries(entries map[string][]string) map[string][]string {
+	copied := make(map[string][]string, len(entries))
+	for word, keys := range entries {
+		copied[word] = keys
+	}
+	return copied
+}
+
+// entryAccepts holds the accept counts of entries, which are kept apart from the ones of their keys: accepting an entry
+// does not promote the other words its keys match, and unlearning it drops its accepts.
+type entryAccepts struct {
+	accepts map[string]int
+	decay   decay
+}
+
+func newEntryAccepts(cfg config) entryAccepts {
+	return entryAccepts{
+		accepts: make(map[string]int),
+		decay:   newDecay(cfg),
+	}
+}
+
+// accept adds an accept to entry.
+func (e *entryAccepts) accept(entry string) {
+
+	if e.accepts == nil {
+		e.accepts = make(map[string]int)
+	}
+	e.accepts[entry]++
+	e.decay.accept(entry)
+}
+
+// candidate returns candidate, the Candidate of the words of a key, for form: with the accepts of form if it is one of
+// entries.
+func (e *entryAccepts) candidate(form string, candidate Candidate, entries map[string][]string) Candidate {
+
+	if _, isEntry := entries[form]; !isEntry {
+		return candidate
+	}
+	accepted := e.decay.candidate(form, e.accepts[form])
+	candidate.Accepts = accepted.Accepts
+	candidate.Decayed = accepted.Decayed
+	candidate.LastAccepted = accepted.LastAccepted
+	return candidate
+}
+
+// saved returns the accept counts of form to be saved, given the ones of its key: its own if it is one of entries.
+func (e *entryAccepts) saved(form string, accepts int, decayed *decayedAccepts, entries map[string][]string) (int, *decayedAccepts) {
+
+	if _, isEntry := entries[form]; !isEntry {
+		return accepts, decayed
+	}
+	return e.accepts[form], e.decay.get(form)
+}
+
+// retrieve sets the accept counts of entry as retrieved in wA.
+func (e *entryAccepts) retrieve(entry string, wA wordAccepts) {
+
+	if wA.Accepts > 0 || wA.SetAccepts {
+		if e.accepts == nil {
+			e.accepts = make(map[string]int)
+		}
+		e.accepts[entry] = wA.Accepts
+	}
+	if wA.Decay != nil {
+		e.decay.set(entry, *wA.Decay)
+	}
+}
+
+// forget drops the accept counts of entry, once it is unlearnt.
+func (e *entryAccepts) forget(entry string) {
+	delete(e.accepts, entry)
+	e.decay.forget(entry)
+}
+
+// copy returns a copy of e, to be modified by a fork.
+func (e entryAccepts) copy() entryAccepts {
+
+	copied := entryAccepts{accepts: make(map[string]int, len(e.accepts)), decay: e.decay.copy()}
+	for entry, accepts := range e.accepts {
+		copied.accepts[entry] = accepts
+	}
+	return copied
+}
+
+// copyWordSet copies a set of learnt or unlearnt words.
+func copyWordSet(words map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(words))
+	for word := range words {
+		copied[word] = true
+	}
+	return copied
+}
+
+// resolve fills in the options left to 0 with the result size and radius of an autocompleter.
+func (opts CompleteOptions) resolve(resultSize, radius int) (CompleteOptions, error) {
+
+	if opts.Limit < 0 || opts.Radius < 0 || opts.Offset < 0 || opts.MinLength < 0 || opts.MaxLength < 0 || opts.MaxEdits < 0 {
+		return opts, errors.New("Negative completion option")
+	}
+	if opts.Limit == 0 {
+		opts.Limit = resultSize
+	}
+	if opts.Radius == 0 {
+		opts.Radius = radius
+	}
+	return opts, nil
+}
+
+// admits reports whether a word of length runes is within the length bounds of opts.
+func (opts CompleteOptions) admits(length int) bool {
+	return length >= opts.MinLength && (opts.MaxLength == 0 || length <= opts.MaxLength)
+}
+
+// maxLength returns the max length of words to descend to, for the engines whose radius is a word length.
+func (opts CompleteOptions) maxLength() int {
+	if opts.MaxLength > 0 && opts.MaxLength < opts.Radius {
+		return opts.MaxLength
+	}
+	return opts.Radius
+}
+
+// scanLimit returns how many words the engines whose radius is a word length must find, in order of length first and
+// alphabetical second, to complete with opts: all of them within the radius, for the heaviest to come first wherever they
+// are found, unless NoBoost keeps words in the order they are found in.
+func (opts CompleteOptions) scanLimit() int {
+	if opts.NoBoost {
+		return opts.Offset + opts.Limit
+	}
+	return math.MaxInt
+}
+
+// page returns the words of list from Offset on, up to Limit.
+func (opts CompleteOptions) page(list sOLILI) []string {
+	return opts.skip(list).flushL(opts.Limit)
+}
+
+// pageResults returns the results of list from Offset on, up to Limit.
+func (opts CompleteOptions) pageResults(list sOLILI) []Result {
+	return opts.skip(list).results(opts.Limit)
+}
+
+func (opts CompleteOptions) skip(list sOLILI) *sOLILI {
+
+	cursor := list.start
+	for i := 0; cursor != nil && i < opts.Offset; i++ {
+		cursor = cursor.next
+	}
+	return &sOLILI{start: cursor, end: list.end}
+}
@@ -0,0 +1,1147 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// fst is a read-only view of a minimal acyclic automaton in the FST file format, which is read in place so that a
+// memory-mapped file needs no decoding.
+type fst struct {
+	data        []byte
+	states      []byte
+	transitions []byte
+	formTable   []byte
+	formData    []byte
+	root        uint32
+}
+
+func readFST(data []byte) (*fst, error) {
+
+	if len(data) < fstHeaderSize || string(data[:len(fstMagic)]) != fstMagic {
+		return nil, errors.New("Not an FST file")
+	}
+	header := data[len(fstMagic):fstHeaderSize]
+	if binary.LittleEndian.Uint32(header) != fstVersion {
+		return nil, errors.New("Unsupported FST version")
+	}
+	stateCount := uint64(binary.LittleEndian.Uint32(header[4:]))
+	transitionCount := uint64(binary.LittleEndian.Uint32(header[8:]))
+	root := binary.LittleEndian.Uint32(header[12:])
+	formsCount := uint64(binary.LittleEndian.Uint32(header[16:]))
+	formsLength := uint64(binary.LittleEndian.Uint32(header[20:]))
+
+	statesEnd := fstHeaderSize + stateCount*fstStateSize
+	transitionsEnd := statesEnd + transitionCount*fstTransitionSize
+	formsEnd := transitionsEnd + formsCount*fstFormsSize
+	if uint64(len(data)) != formsEnd+formsLength || uint64(root) >= stateCount {
+		return nil, errors.New("Corrupted FST file")
+	}
+
+	automaton := &fst{
+		data:        data,
+		states:      data[fstHeaderSize:statesEnd],
+		transitions: data[statesEnd:transitionsEnd],
+		formTable:   data[transitionsEnd:formsEnd],
+		formData:    data[formsEnd:],
+		root:        root,
+	}
+	if !automaton.valid() {
+		return nil, errors.New("Corrupted FST file")
+	}
+	return automaton, nil
+}
+
+// valid tells whether the transitions of the states and the records of the forms table are within bounds. States are
+// frozen after the states they lead to, so that every transition leads to a state of lower id: checking it rules out
+// cycles as well, on which key would never end.
+func (automaton *fst) valid() bool {
+
+	transitionCount := uint64(len(automaton.transitions) / fstTransitionSize)
+	for id := uint32(0); id < uint32(len(automaton.states)/fstStateSize); id++ {
+		state := automaton.state(id)
+		if uint64(state.first)+uint64(state.count) > transitionCount {
+			return false
+		}
+		for i := uint32(0); i < state.count; i++ {
+			if automaton.transition(state.first+i).target >= id {
+				return false
+			}
+		}
+	}
+
+	words := automaton.state(automaton.root).words
+	var previous uint32
+	for i := 0; i < len(automaton.formTable)/fstFormsSize; i++ {
+		record := automaton.formTable[i*fstFormsSize:]
+		ordinal := binary.LittleEndian.Uint32(record)
+		if ordinal >= words || (i > 0 && ordinal <= previous) {
+			return false
+		}
+		previous = ordinal
+		start, end := automaton.formSpan(i)
+		if start > end || end > uint32(len(automaton.formData)) {
+			return false
+		}
+		for form := automaton.formData[start:end]; len(form) > 0; {
+			if len(form) < 4 || uint64(binary.LittleEndian.Uint32(form)) > uint64(len(form)-4) {
+				return false
+			}
+			form = form[4+binary.LittleEndian.Uint32(form):]
+		}
+	}
+	return true
+}
+
+func (automaton *fst) state(id uint32) fstState {
+	record := automaton.states[id*fstStateSize:]
+	return fstState{
+		first: binary.LittleEndian.Uint32(record),
+		count: binary.LittleEndian.Uint32(record[4:]),
+		final: binary.LittleEndian.Uint32(record[8:])&1 == 1,
+		words: binary.LittleEndian.Uint32(record[12:]),
+	}
+}
+
+func (automaton *fst) transition(i uint32) fstTransition {
+	record := automaton.transitions[i*fstTransitionSize:]
+	return fstTransition{
+		r:      rune(binary.LittleEndian.Uint32(record)),
+		target: binary.LittleEndian.Uint32(record[4:]),
+		output: binary.LittleEndian.Uint32(record[8:]),
+	}
+}
+
+// formSpan returns where the forms of the i-th record of the forms table begin and end in the forms data.
+func (automaton *fst) formSpan(i int) (uint32, uint32) {
+
+	start := binary.LittleEndian.Uint32(automaton.formTable[i*fstFormsSize+4:])
+	if (i+1)*fstFormsSize == len(automaton.formTable) {
+		return start, uint32(len(automaton.formData))
+	}
+	return start, binary.LittleEndian.Uint32(automaton.formTable[(i+1)*fstFormsSize+4:])
+}
+
+// formsAt decodes the forms of the i-th record of the forms table.
+func (automaton *fst) formsAt(i int) surfaceForms {
+
+	start, end := automaton.formSpan(i)
+	var forms surfaceForms
+	for data := automaton.formData[start:end]; len(data) > 0; {
+		length := binary.LittleEndian.Uint32(data)
+		forms = append(forms, string(data[4:4+length]))
+		data = data[4+length:]
+	}
+	return forms
+}
+
+// forms returns the surface forms of the word ordinal, nil if the word is its only form. They are found by binary
+// search in the forms table, and decoded only when asked for.
+func (automaton *fst) forms(ordinal uint32) surfaceForms {
+
+	count := len(automaton.formTable) / fstFormsSize
+	i := sort.Search(count, func(i int) bool {
+		return binary.LittleEndian.Uint32(automaton.formTable[i*fstFormsSize:]) >= ordinal
+	})
+	if i == count || binary.LittleEndian.Uint32(automaton.formTable[i*fstFormsSize:]) != ordinal {
+		return nil
+	}
+	return automaton.formsAt(i)
+}
+
+// step returns the transition of state on r. Transitions are sorted by rune, so it is found by binary search.
+func (automaton *fst) step(state fstState, r rune) (fstTransition, bool) {
+
+	i := sort.Search(int(state.count), func(i int) bool {
+		return automaton.transition(state.first+uint32(i)).r >= r
+	})
+	if i == int(state.count) {
+		return fstTransition{}, false
+	}
+	t := automaton.transition(state.first + uint32(i))
+	return t, t.r == r
+}
+
+// walk follows stem from the root, returning the state it leads to and the ordinal accumulated along the way.
+func (automaton *fst) walk(stem string) (fstState, uint32, bool) {
+
+	state := automaton.state(automaton.root)
+	var ordinal uint32
+	for _, r := range stem {
+		t, ok := automaton.step(state, r)
+		if !ok {
+			return fstState{}, 0, false
+		}
+		state = automaton.state(t.target)
+		ordinal += t.output
+	}
+	return state, ordinal, true
+}
+
+// lookup returns the ordinal of key, the position of key in the sorted dictionary.
+func (automaton *fst) lookup(key string) (uint32, bool) {
+
+	state, ordinal, ok := automaton.walk(key)
+	return ordinal, ok && state.final
+}
+
+// key returns the key of ordinal, the inverse of lookup: at each state, the path goes through the last transition
+// whose output does not exceed what is left of ordinal.
+func (automaton *fst) key(ordinal uint32) string {
+
+	state := automaton.state(automaton.root)
+	var key []rune
+	for !state.final || ordinal > 0 {
+		if state.count == 0 {
+			break
+		}
+		next := automaton.transition(state.first)
+		for i := uint32(1); i < state.count; i++ {
+			t := automaton.transition(state.first + i)
+			if t.output > ordinal {
+				break
+			}
+			next = t
+		}
+		key = append(key, next.r)
+		ordinal -= next.output
+		state = automaton.state(next.target)
+	}
+	return string(key)
+}
+
+// AutoCompleteFST is an implementation of AutoComplete for huge, static dictionaries. Words are kept in a minimal
+// acyclic finite-state automaton, which shares both prefixes and suffixes and can be memory-mapped from a file built
+// once by BuildFST or WriteFST. Learnt words, unlearnt words and accepts are kept in a small mutable overlay on top of
+// the automaton, which is never modified.
+type AutoCompleteFST struct {
+	fst     *fst
+	overlay AutoCompleteRadix
+	accepts map[string]int
+	removed map[string]bool
+	// payloads holds the payloads of the dictionary the automaton was built from, and newPayloads the ones given to
+	// the words of the automaton afterwards.
+	payloads    map[string]interface{}
+	newPayloads map[string]interface{}
+	// weights holds the weights of the dictionary the automaton was built from, and newWeights the ones set afterwards.
+	weights    map[string]float64
+	newWeights map[string]float64
+	// entries holds the keys of the entries of the automaton (see Learn), whose forms are stored under keys other than
+	// their own. The entries learnt afterwards are kept by the overlay.
+	entries map[string][]string
+	// ngrams is the n-gram model taught by AcceptSequence.
+	ngrams nGrams
+	// decay holds the decayed accept counts of the keys of the automaton; the overlay keeps its own.
+	decay decay
+	// entryAccepts holds the accept counts of the entries of the automaton, apart from the ones of their keys.
+	entryAccepts entryAccepts
+	// changes holds when words were last learnt or unlearnt (see Merge), both in the automaton and in the overlay.
+	changes    changes
+	resultSize int
+	radius     int
+	normalizer Normalizer
+	unmap      func() error
+}
+
+// NewAutoCompleteFSTF returns a new autocompleter.
+//
+// dictionaryFileName is the name of a dictionary file (a file containing words) to be used for completion. The file is
+// read in memory; use BuildFST and NewAutoCompleteFSTM for dictionaries that do not fit in memory.
+//
+// resultSize is the number of hits returned. If 0 is used, it defaults to DEF_RESULTS_SIZE
+//
+// radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
+//
+// New words can be added to it by using the Learn() function. With WithPayloadSeparator, every line holds a word and its
+// payload; with WithWeightSeparator, a word and its weight. WithCSV reads a CSV file instead. The file may be compressed
+// with gzip or bzip2.
+func NewAutoCompleteFSTF(dictionaryFileName string, resultSize, radius uint, options ...Option) (AutoCompleteFST, error) {
+
+	var nAc AutoCompleteFST
+
+	f, err := os.Open(dictionaryFileName)
+	if err != nil {
+		return nAc, err
+	}
+	defer f.Close()
+
+	return NewAutoCompleteFSTR(f, resultSize, radius, options...)
+}
+
+// NewAutoCompleteFSTR returns a new autocompleter, as NewAutoCompleteFSTF does, from a dictionary read from reader,
+// which may be compressed with gzip or bzip2.
+//
+// Lines starting with the prefix of WithCommentPrefix are skipped, and so are blank lines with WithSkipBlankLines.
+// Errors on bad input report the number of the line they were found at.
+func NewAutoCompleteFSTR(reader io.Reader, resultSize, radius uint, options ...Option) (AutoCompleteFST, error) {
+	return buildAutoCompleteFST(readerRecords(reader, newConfig(options)), resultSize, radius, options)
+}
+
+// NewAutoCompleteFSTS returns a new autocompleter.
+//
+// dictionary is a slice of words to be used for completion. It does not need to be sorted.
+//
+// resultSize is the number of hits returned. If 0 is used, it defaults to DEF_RESULTS_SIZE
+//
+// radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
+//
+// New words can be added to it by using the Learn() function
+func NewAutoCompleteFSTS(dictionary []string, resultSize, radius uint, options ...Option) (AutoCompleteFST, error) {
+	return NewAutoCompleteFSTP(wordRecords(dictionary), resultSize, radius, options...)
+}
+
+// NewAutoCompleteFSTP returns a new autocompleter, as NewAutoCompleteFSTS does, from a dictionary of records. The
+// payload of each word is returned along with it by CompleteScored, and words with keys are entries matched by their
+// keys (see Learn). Payloads are kept in memory, and are not written by WriteFST; entries are.
+func NewAutoCompleteFSTP(dictionary []Record, resultSize, radius uint, options ...Option) (AutoCompleteFST, error) {
+	return buildAutoCompleteFST(recordSlice(dictionary), resultSize, radius, options)
+}
+
+func buildAutoCompleteFST(dictionary records, resultSize, radius uint, options []Option) (AutoCompleteFST, error) {
+
+	var nAc AutoCompleteFST
+
+	config := newConfig(options)
+	forms := make(map[string][]string)
+	payloads := make(map[string]interface{})
+	weights := make(map[string]float64)
+	var keys []string
+	err := dictionary(func(record Record) error {
+		recordKeys := []string{config.normalizer.Normalize(record.Word)}
+		entry := record.Keys
+		if len(entry) == 0 {
+			entry = tokenKeys(config.tokenizer, record.Word)
+		}
+		if len(entry) > 0 {
+			var err error
+			if recordKeys, err = entryKeys(config.normalizer, record.Word, entry); err != nil {
+				return err
+			}
+		}
+		for _, key := range recordKeys {
+			if key == "" {
+				return errors.New("Empty word in dictionary")
+			}
+			if _, exists := forms[key]; !exists {
+				keys = append(keys, key)
+			}
+			forms[key] = append(forms[key], record.Word)
+			if record.Payload != nil {
+				payloads[key] = record.Payload
+			}
+			if record.Weight > weights[key] {
+				weights[key] = record.Weight
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nAc, err
+	}
+	sort.Strings(keys)
+
+	builder := newFSTBuilder(config.normalizer)
+	for _, key := range keys {
+		for _, word := range forms[key] {
+			if err := builder.addForm(key, word); err != nil {
+				return nAc, err
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	if err := builder.finish(&buffer); err != nil {
+		return nAc, err
+	}
+	automaton, err := readFST(buffer.Bytes())
+	if err != nil {
+		return nAc, err
+	}
+
+	autoComplete, err := newAutoCompleteFST(automaton, nil, resultSize, radius, config)
+	autoComplete.payloads = payloads
+	autoComplete.weights = weights
+	return autoComplete, err
+}
+
+// NewAutoCompleteFSTM returns a new autocompleter, memory-mapping the automaton in fstFileName, as written by BuildFST
+// or WriteFST. The automaton is not built again, but the file is checked in one pass, so that startup time grows with
+// the size of the dictionary, if far more slowly than building it (about 4ms for 355k words). Close should be called
+// once the autocompleter is no longer used.
+//
+// resultSize is the number of hits returned. If 0 is used, it defaults to DEF_RESULTS_SIZE
+//
+// radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
+//
+// options must include the same Normalizer the automaton was built with.
+//
+// New words can be added to it by using the Learn() function
+func NewAutoCompleteFSTM(fstFileName string, resultSize, radius uint, options ...Option) (AutoCompleteFST, error) {
+
+	var nAc AutoCompleteFST
+
+	data, unmap, err := mapFile(fstFileName)
+	if err != nil {
+		return nAc, err
+	}
+	automaton, err := readFST(data)
+	if err != nil {
+		unmap()
+		return nAc, err
+	}
+
+	return newAutoCompleteFST(automaton, unmap, resultSize, radius, newConfig(options))
+}
+
+func newAutoCompleteFST(automaton *fst, unmap func() error, resultSize, radius uint, config config) (AutoCompleteFST, error) {
+
+	if resultSize == 0 {
+		resultSize = DefaultResultSize
+	}
+	if radius == 0 {
+		radius = DefaultRadius
+	}
+
+	overlay, err := NewAutoCompleteRadixE(resultSize, radius, WithNormalizer(config.normalizer), WithTokenizer(config.tokenizer),
+		WithHalfLife(config.halfLife), WithClock(config.clock), WithRanker(config.ranker))
+	if err != nil {
+		return AutoCompleteFST{}, err
+	}
+
+	return AutoCompleteFST{
+		fst:          automaton,
+		overlay:      overlay,
+		accepts:      make(map[string]int),
+		removed:      make(map[string]bool),
+		payloads:     make(map[string]interface{}),
+		newPayloads:  make(map[string]interface{}),
+		weights:      make(map[string]float64),
+		newWeights:   make(map[string]float64),
+		entries:      fstEntries(automaton, config.normalizer),
+		decay:        newDecay(config),
+		entryAccepts: newEntryAccepts(config),
+		changes:      newChanges(config),
+		resultSize:   int(resultSize),
+		radius:       int(radius),
+		normalizer:   config.normalizer,
+		unmap:        unmap,
+	}, nil
+}
+
+// fstEntries returns the keys of the entries of automaton. An entry is a form stored under a key other than its own,
+// which is one of its keys too if the form is stored under it as well.
+func fstEntries(automaton *fst, normalizer Normalizer) map[string][]string {
+
+	entries := make(map[string][]string)
+	for i := 0; i < len(automaton.formTable)/fstFormsSize; i++ {
+		record := automaton.formTable[i*fstFormsSize:]
+		if binary.LittleEndian.Uint32(record[8:])&1 == 0 {
+			continue
+		}
+		key := automaton.key(binary.LittleEndian.Uint32(record))
+		for _, form := range automaton.formsAt(i) {
+			if normalizer.Normalize(form) != key {
+				entries[form] = append(entries[form], key)
+			}
+		}
+	}
+	for form, keys := range entries {
+		own := normalizer.Normalize(form)
+		if ordinal, ok := automaton.lookup(own); ok && automaton.forms(ordinal).contains(own, form) {
+			keys = append(keys, own)
+		}
+		sort.Strings(keys)
+		entries[form] = keys
+	}
+	return entries
+}
+
+// WriteFST writes the automaton of autoComplete to fileName, to be loaded with NewAutoCompleteFSTM. The overlay is not
+// written: Save should be used for learnt words and accepts. The file is replaced atomically, so that an autocompleter
+// mapping it keeps the automaton it was loaded with.
+func (autoComplete *AutoCompleteFST) WriteFST(fileName string) error {
+	return SaveFile(fileName, func(writer io.Writer) error {
+		_, err := writer.Write(autoComplete.fst.data)
+		return err
+	})
+}
+
+// Close releases the memory-mapped automaton. autoComplete must not be used afterwards.
+func (autoComplete *AutoCompleteFST) Close() error {
+
+	if autoComplete.unmap == nil {
+		return nil
+	}
+	err := autoComplete.unmap()
+	autoComplete.unmap = nil
+	return err
+}
+
+// fork returns a copy of autoComplete to apply changes to, leaving autoComplete untouched. Only the overlay is copied,
+// as the automaton is never modified.
+func (autoComplete *AutoCompleteFST) fork() forker {
+
+	forked := *autoComplete
+	forked.overlay = *autoComplete.overlay.fork().(*AutoCompleteRadix)
+	forked.accepts = make(map[string]int, len(autoComplete.accepts))
+	for key, accepts := range autoComplete.accepts {
+		forked.accepts[key] = accepts
+	}
+	forked.removed = copyWordSet(autoComplete.removed)
+	forked.newPayloads = make(map[string]interface{}, len(autoComplete.newPayloads))
+	for key, payload := range autoComplete.newPayloads {
+		forked.newPayloads[key] = payload
+	}
+	forked.newWeights = make(map[string]float64, len(autoComplete.newWeights))
+	for key, weight := range autoComplete.newWeights {
+		forked.newWeights[key] = weight
+	}
+	forked.ngrams = autoComplete.ngrams.copy()
+	forked.decay = autoComplete.decay.copy()
+	forked.entryAccepts = autoComplete.entryAccepts.copy()
+	forked.changes = autoComplete.changes.copy()
+	return &forked
+}
+
+// now : see description in clocked interface
+func (autoComplete *AutoCompleteFST) now() time.Time {
+	return autoComplete.changes.clock()
+}
+
+// at : see description in clocked interface
+func (autoComplete *AutoCompleteFST) at(at time.Time, change func() error) error {
+	return atTime(at, change, &autoComplete.decay.clock, &autoComplete.entryAccepts.decay.clock, &autoComplete.changes.clock, &autoComplete.overlay.decay.clock, &autoComplete.overlay.entryAccepts.decay.clock, &autoComplete.overlay.changes.clock)
+}
+
+// visibleForms returns the forms of the automaton word key, ordinal that have not been unlearnt.
+func (autoComplete *AutoCompleteFST) visibleForms(key string, ordinal uint32) []string {
+
+	forms := autoComplete.fst.forms(ordinal).list(key)
+	if len(autoComplete.removed) == 0 {
+		return forms
+	}
+	var visible []string
+	for _, form := range forms {
+		if !autoComplete.removed[form] {
+			visible = append(visible, form)
+		}
+	}
+	return visible
+}
+
+// fstForms returns the visible forms key has in the automaton.
+func (autoComplete *AutoCompleteFST) fstForms(key string) []string {
+
+	ordinal, ok := autoComplete.fst.lookup(key)
+	if !ok {
+		return nil
+	}
+	return autoComplete.visibleForms(key, ordinal)
+}
+
+// overlayWord returns the overlay entry of key, or nil if key has not been learnt.
+func (autoComplete *AutoCompleteFST) overlayWord(key string) *radixWord {
+
+	node := autoComplete.overlay.find(key)
+	if node == nil {
+		return nil
+	}
+	return node.word
+}
+
+// payload returns the payload of the automaton word key.
+func (autoComplete *AutoCompleteFST) payload(key string) interface{} {
+
+	if payload, ok := autoComplete.newPayloads[key]; ok {
+		return payload
+	}
+	return autoComplete.payloads[key]
+}
+
+// setPayload sets the payload of key, in the automaton and in the overlay, wherever key is.
+func (autoComplete *AutoCompleteFST) setPayload(key string, payload interface{}) {
+
+	if len(autoComplete.fstForms(key)) > 0 {
+		autoComplete.newPayloads[key] = payload
+	}
+	if autoComplete.overlayWord(key) != nil {
+		autoComplete.overlay.mutableWord(key).payload = payload
+	}
+}
+
+// weight returns the weight of the automaton word key.
+func (autoComplete *AutoCompleteFST) weight(key string) float64 {
+
+	if weight, ok := autoComplete.newWeights[key]; ok {
+		return weight
+	}
+	return autoComplete.weights[key]
+}
+
+// setWeight sets the weight of key, in the automaton and in the overlay, wherever key is.
+func (autoComplete *AutoCompleteFST) setWeight(key string, weight float64) {
+
+	if len(autoComplete.fstForms(key)) > 0 {
+		autoComplete.newWeights[key] = weight
+	}
+	if autoComplete.overlayWord(key) != nil {
+		autoComplete.overlay.mutableWord(key).weight = weight
+		autoComplete.overlay.newWeights[key] = true
+	}
+}
+
+func (autoComplete *AutoCompleteFST) contains(key, word string) bool {
+
+	if ordinal, ok := autoComplete.fst.lookup(key); ok && autoComplete.fst.forms(ordinal).contains(key, word) && !autoComplete.removed[word] {
+		return true
+	}
+	learnt := autoComplete.overlayWord(key)
+	return learnt != nil && learnt.forms.contains(key, word)
+}
+
+// keysOf returns the keys word is matched by, and whether word is in the dictionary.
+func (autoComplete *AutoCompleteFST) keysOf(word string) ([]string, bool) {
+
+	if keys, isEntry := autoComplete.entries[word]; isEntry && !autoComplete.removed[word] {
+		return keys, true
+	}
+	if keys, isEntry := autoComplete.overlay.entries[word]; isEntry {
+		return keys, true
+	}
+	key := autoComplete.normalizer.Normalize(word)
+	return []string{key}, autoComplete.contains(key, word)
+}
+
+// Accept : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) Accept(acceptedWord string) error {
+
+	if _, isEntry := autoComplete.entries[acceptedWord]; isEntry && !autoComplete.removed[acceptedWord] {
+		autoComplete.entryAccepts.accept(acceptedWord)
+		return nil
+	}
+	if _, isEntry := autoComplete.overlay.entries[acceptedWord]; isEntry {
+		return autoComplete.overlay.Accept(acceptedWord)
+	}
+	key := autoComplete.normalizer.Normalize(acceptedWord)
+	if len(autoComplete.fstForms(key)) > 0 {
+		autoComplete.accepts[key]++
+		autoComplete.decay.accept(key)
+		return nil
+	}
+	return autoComplete.overlay.Accept(acceptedWord)
+}
+
+// Learn : see description in AutoComplete interface. Learning a word of the automaton that was unlearnt restores it.
+func (autoComplete *AutoCompleteFST) Learn(word string, keys ...string) error {
+
+	if _, isEntry := autoComplete.entries[word]; isEntry {
+		if !autoComplete.removed[word] {
+			return errors.New("Word already in dictionary")
+		}
+		delete(autoComplete.removed, word)
+		autoComplete.changes.record(word)
+		return nil
+	}
+	key := autoComplete.normalizer.Normalize(word)
+	if key == "" {
+		return errors.New("Empty word")
+	}
+	if ordinal, ok := autoComplete.fst.lookup(key); ok && autoComplete.fst.forms(ordinal).contains(key, word) {
+		if !autoComplete.removed[word] {
+			return errors.New("Word already in dictionary")
+		}
+		delete(autoComplete.removed, word)
+		autoComplete.changes.record(word)
+		return nil
+	}
+	if err := autoComplete.overlay.Learn(word, keys...); err != nil {
+		return err
+	}
+	autoComplete.changes.record(word)
+	return nil
+}
+
+// LearnWithPayload : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) LearnWithPayload(word string, payload interface{}, keys ...string) error {
+
+	wordKeys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		if err := autoComplete.Learn(word, keys...); err != nil {
+			return err
+		}
+		wordKeys, _ = autoComplete.keysOf(word)
+	}
+	for _, key := range wordKeys {
+		autoComplete.setPayload(key, payload)
+	}
+	return nil
+}
+
+// SetWeight : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) SetWeight(word string, weight float64) error {
+
+	keys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		return errors.New("Word not in dictionary")
+	}
+	for _, key := range keys {
+		autoComplete.setWeight(key, weight)
+	}
+	return nil
+}
+
+// UnLearn : see description in AutoComplete interface. If word is one of several forms sharing a key, only that form is
+// removed; otherwise the key is removed along with all of its forms, but for the entries it is a key of.
+func (autoComplete *AutoCompleteFST) UnLearn(word string) error {
+
+	if keys, isEntry := autoComplete.entries[word]; isEntry && !autoComplete.removed[word] {
+		autoComplete.removed[word] = true
+		for _, key := range keys {
+			autoComplete.forget(key)
+		}
+		autoComplete.entryAccepts.forget(word)
+		autoComplete.changes.record(word)
+		return nil
+	}
+	if _, isEntry := autoComplete.overlay.entries[word]; isEntry {
+		return autoComplete.overlayUnLearn(word, []string{word})
+	}
+
+	key := autoComplete.normalizer.Normalize(word)
+	var forms, learntForms []string
+	for _, form := range autoComplete.fstForms(key) {
+		if _, isEntry := autoComplete.entries[form]; !isEntry {
+			forms = append(forms, form)
+		}
+	}
+	if learnt := autoComplete.overlayWord(key); learnt != nil {
+		for _, form := range learnt.forms.list(key) {
+			if _, isEntry := autoComplete.overlay.entries[form]; !isEntry {
+				learntForms = append(learntForms, form)
+			}
+		}
+	}
+	if len(forms)+len(learntForms) == 0 {
+		return errors.New("Word not in dictionary")
+	}
+
+	for _, form := range forms {
+		if form == word {
+			forms, learntForms = []string{word}, nil
+			break
+		}
+	}
+	for _, form := range learntForms {
+		if form == word {
+			return autoComplete.overlayUnLearn(word, []string{word})
+		}
+	}
+	for _, form := range forms {
+		autoComplete.removed[form] = true
+		autoComplete.changes.record(form)
+	}
+	autoComplete.forget(key)
+	if len(learntForms) > 0 {
+		return autoComplete.overlayUnLearn(word, learntForms)
+	}
+	return nil
+}
+
+// overlayUnLearn unlearns word from the overlay, which removes forms.
+func (autoComplete *AutoCompleteFST) overlayUnLearn(word string, forms []string) error {
+
+	if err := autoComplete.overlay.UnLearn(word); err != nil {
+		return err
+	}
+	for _, form := range forms {
+		autoComplete.changes.record(form)
+	}
+	return nil
+}
+
+// forget drops the accepts, the payload and the weight of key once all of its forms in the automaton have been
+// unlearnt.
+func (autoComplete *AutoCompleteFST) forget(key string) {
+
+	if len(autoComplete.fstForms(key)) == 0 {
+		delete(autoComplete.accepts, key)
+		delete(autoComplete.newPayloads, key)
+		delete(autoComplete.newWeights, key)
+		autoComplete.decay.forget(key)
+	}
+}
+
+// Complete : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) Complete(stem string) ([]string, error) {
+
+	return autoComplete.CompleteWith(stem, CompleteOptions{})
+}
+
+// CompleteWith : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) CompleteWith(stem string, opts CompleteOptions) ([]string, error) {
+
+	result, opts, err := autoComplete.completeList(stem, opts)
+	if err != nil {
+		return nil, err
+	}
+	return opts.page(result), nil
+}
+
+// CompleteScored : see description in AutoComplete interface. Only the words of the overlay count as learnt.
+func (autoComplete *AutoCompleteFST) CompleteScored(stem string, opts CompleteOptions) ([]Result, error) {
+
+	result, opts, err := autoComplete.completeList(stem, opts)
+	if err != nil {
+		return nil, err
+	}
+	return opts.pageResults(result), nil
+}
+
+// CompleteQuery : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) CompleteQuery(query string) ([]string, error) {
+	return completeQuery(autoComplete, autoComplete.overlay.tokenizer, autoComplete.normalizer, autoComplete.resultSize, query)
+}
+
+// AcceptSequence : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) AcceptSequence(words []string) error {
+	return autoComplete.ngrams.accept(autoComplete.normalizer, words)
+}
+
+// Predict : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) Predict(previousWords []string, stem string) ([]string, error) {
+	return autoComplete.ngrams.predict(autoComplete, autoComplete.normalizer, autoComplete.resultSize, previousWords, stem)
+}
+
+func (autoComplete *AutoCompleteFST) completeList(stem string, opts CompleteOptions) (sOLILI, CompleteOptions, error) {
+
+	opts, err := opts.resolve(autoComplete.resultSize, autoComplete.radius)
+	if err != nil {
+		return sOLILI{}, opts, err
+	}
+	walk := func(automaton *levenshteinAutomaton, matches *[]fuzzyMatch) {
+		autoComplete.fuzzyWalk(autoComplete.fst.state(autoComplete.fst.root), automaton, automaton.start(), nil, opts.MaxEdits+1, matches)
+		autoComplete.overlay.fuzzyWalk(autoComplete.overlay.root, automaton, automaton.start(), nil, opts.MaxEdits+1, matches)
+	}
+	complete := func(prefix string) sOLILI {
+		return autoComplete.complete(prefix, opts)
+	}
+	return completeFuzzy(autoComplete.normalizer.Normalize(stem), opts, walk, complete), opts, nil
+}
+
+type fstEntry struct {
+	key    string
+	length int
+	forms  []string
+}
+
+type fstBranch struct {
+	state   fstState
+	path    []rune
+	ordinal uint32
+}
+
+// completeEntries returns, in order of length first and alphabetical second, the words of the automaton whose key
+// starts with stem and whose length is within the bounds of opts, up to the scan limit of opts. As every transition
+// holds a single rune, a breadth-first visit yields that order.
+func (autoComplete *AutoCompleteFST) completeEntries(stem string, opts CompleteOptions) []fstEntry {
+
+	var entries []fstEntry
+	state, ordinal, ok := autoComplete.fst.walk(stem)
+	if !ok {
+		return entries
+	}
+
+	queue := []fstBranch{{
+		state:   state,
+		path:    []rune(stem),
+		ordinal: ordinal,
+	}}
+
+	maxLength := opts.maxLength()
+	for len(queue) > 0 && len(entries) < opts.scanLimit() {
+		branch := queue[0]
+		queue = queue[1:]
+		if branch.state.final && opts.admits(len(branch.path)) {
+			key := string(branch.path)
+			if forms := autoComplete.visibleForms(key, branch.ordinal); len(forms) > 0 {
+				entries = append(entries, fstEntry{
+					key:    key,
+					length: len(branch.path),
+					forms:  forms,
+				})
+			}
+		}
+		if len(branch.path) >= maxLength {
+			continue
+		}
+		for i := uint32(0); i < branch.state.count; i++ {
+			t := autoComplete.fst.transition(branch.state.first + i)
+			queue = append(queue, fstBranch{
+				state:   autoComplete.fst.state(t.target),
+				path:    append(branch.path[:len(branch.path):len(branch.path)], t.r),
+				ordinal: branch.ordinal + t.output,
+			})
+		}
+	}
+	return entries
+}
+
+// complete merges the completions of the automaton and of the overlay, which is a lot smaller, in order of length
+// first and alphabetical second.
+func (autoComplete *AutoCompleteFST) complete(stem string, opts CompleteOptions) sOLILI {
+
+	words := newEntryHits(opts.Offset + opts.Limit)
+	matchLength := utf8.RuneCountInString(stem)
+	entries := autoComplete.completeEntries(stem, opts)
+	learnt := autoComplete.overlay.completeEntries(stem, opts)
+
+	i, j := 0, 0
+	for n := 0; n < opts.scanLimit() && (i < len(entries) || j < len(learnt)); n++ {
+		order := 0
+		switch {
+		case i == len(entries):
+			order = 1
+		case j == len(learnt):
+			order = -1
+		case entries[i].length != learnt[j].length:
+			order = entries[i].length - learnt[j].length
+		case entries[i].key < learnt[j].key:
+			order = -1
+		case entries[i].key > learnt[j].key:
+			order = 1
+		}
+
+		var candidate, fstCandidate, learntCandidate Candidate
+		if order <= 0 {
+			fstCandidate = autoComplete.decay.candidate(entries[i].key, autoComplete.accepts[entries[i].key])
+			fstCandidate.Weight = autoComplete.weight(entries[i].key)
+		}
+		if order >= 0 {
+			learntCandidate = autoComplete.overlay.decay.candidate(learnt[j].key, learnt[j].word.accepts)
+			learntCandidate.Weight = learnt[j].word.weight
+		}
+		switch {
+		case order < 0:
+			candidate = fstCandidate
+		case order > 0:
+			candidate = learntCandidate
+		default:
+			candidate = mergeCandidates(fstCandidate, learntCandidate)
+		}
+		if order <= 0 {
+			payload := autoComplete.payload(entries[i].key)
+			for _, form := range entries[i].forms {
+				words.insert(opts.hit(autoComplete.overlay.ranker, form, autoComplete.entryAccepts.candidate(form, candidate, autoComplete.entries), payload, false, matchLength), entries[i].key, stem, autoComplete.normalizer, autoComplete.entries)
+			}
+			i++
+		}
+		if order >= 0 {
+			for _, form := range learnt[j].word.forms.list(learnt[j].key) {
+				words.insert(opts.hit(autoComplete.overlay.ranker, form, autoComplete.overlay.entryAccepts.candidate(form, candidate, autoComplete.overlay.entries), learnt[j].word.payload, true, matchLength), learnt[j].key, stem, autoComplete.normalizer, autoComplete.overlay.entries)
+			}
+			j++
+		}
+	}
+	return words.list()
+}
+
+// CompleteFuzzy : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	return autoComplete.CompleteWith(stem, CompleteOptions{MaxEdits: maxEdits})
+}
+
+func (autoComplete *AutoCompleteFST) fuzzyWalk(state fstState, automaton *levenshteinAutomaton, lState levenshteinState, prefix []rune, best int, matches *[]fuzzyMatch) {
+
+	if len(prefix) >= autoComplete.radius {
+		return
+	}
+	for i := uint32(0); i < state.count; i++ {
+		t := autoComplete.fst.transition(state.first + i)
+		childPrefix := append(prefix[:len(prefix):len(prefix)], t.r)
+		childState, childBest, descend := automaton.visit(lState, t.r, childPrefix, best, matches)
+		if descend {
+			autoComplete.fuzzyWalk(autoComplete.fst.state(t.target), automaton, childState, childPrefix, childBest, matches)
+		}
+	}
+}
+
+// Save : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) Save(fileName string) error {
+	return SaveFile(fileName, autoComplete.SaveTo)
+}
+
+// SaveTo : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) SaveTo(writer io.Writer) error {
+	return writeSave(writer, autoComplete.saveHeader(), autoComplete.saveBody)
+}
+
+// saveHeader describes autoComplete in the header of its save files.
+func (autoComplete *AutoCompleteFST) saveHeader() saveHeader {
+	return saveHeader{Engine: "FST", Normalizer: normalizerName(autoComplete.normalizer), ResultSize: autoComplete.resultSize, Radius: autoComplete.radius}
+}
+
+// saveBody writes the body of a save file of autoComplete.
+func (autoComplete *AutoCompleteFST) saveBody(writer io.Writer) error {
+
+	enc := &wordEncoder{enc: gob.NewEncoder(writer)}
+	var written, learnt entrySet
+
+	changed := make(map[string]bool)
+	for key := range autoComplete.accepts {
+		changed[key] = true
+	}
+	for key := range autoComplete.newPayloads {
+		changed[key] = true
+	}
+	for key := range autoComplete.newWeights {
+		changed[key] = true
+	}
+	for entry := range autoComplete.entryAccepts.accepts {
+		for _, key := range autoComplete.entries[entry] {
+			changed[key] = true
+		}
+	}
+	for key := range changed {
+		var weight *float64
+		if newWeight, ok := autoComplete.newWeights[key]; ok {
+			weight = &newWeight
+		}
+		_, newPayload := autoComplete.newPayloads[key]
+		for _, w := range autoComplete.fstForms(key) {
+			_, accepted := autoComplete.accepts[key]
+			if _, isEntry := autoComplete.entries[w]; isEntry {
+				_, accepted = autoComplete.entryAccepts.accepts[w]
+			}
+			if !accepted && !newPayload && weight == nil {
+				continue
+			}
+			accepts, decayed := autoComplete.entryAccepts.saved(w, autoComplete.accepts[key], autoComplete.decay.get(key), autoComplete.entries)
+			if written.first(w, autoComplete.entries) {
+				enc.encode(wordAccepts{
+					Word:    w,
+					Accepts: accepts,
+					Decay:   decayed,
+					Payload: autoComplete.newPayloads[key],
+					Weight:  weight,
+					Changed: autoComplete.changes.get(w),
+				})
+			}
+		}
+	}
+
+	autoComplete.overlay.walk(autoComplete.overlay.root, "", func(key string, word *radixWord) {
+		var weight *float64
+		if autoComplete.overlay.newWeights[key] {
+			weight = &word.weight
+		}
+		for _, w := range word.forms.list(key) {
+			if learnt.first(w, autoComplete.overlay.entries) {
+				accepts, decayed := autoComplete.overlay.entryAccepts.saved(w, word.accepts, autoComplete.overlay.decay.get(key), autoComplete.overlay.entries)
+				enc.encode(wordAccepts{
+					Word:    w,
+					Accepts: accepts,
+					New:     true,
+					Decay:   decayed,
+					Payload: word.payload,
+					Weight:  weight,
+					Keys:    autoComplete.overlay.entries[w],
+					Changed: autoComplete.changes.get(w),
+				})
+			}
+		}
+	})
+
+	for w := range autoComplete.removed {
+		enc.encode(wordAccepts{Word: w, Accepts: -1, Changed: autoComplete.changes.get(w)})
+	}
+	autoComplete.changes.save(enc, func(word string) bool {
+		_, isWord := autoComplete.keysOf(word)
+		return isWord
+	})
+	autoComplete.ngrams.save(enc)
+	return enc.err
+}
+
+// Retrieve : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) Retrieve(fileName string) error {
+	return retrieveFile(fileName, autoComplete.RetrieveFrom)
+}
+
+// RetrieveFrom : see description in AutoComplete interface
+func (autoComplete *AutoCompleteFST) RetrieveFrom(reader io.Reader) error {
+	return readSave(reader, autoComplete.saveHeader(), autoComplete.retrieveBody)
+}
+
+// retrieveBody reads the body of a save file.
+func (autoComplete *AutoCompleteFST) retrieveBody(reader io.Reader) error {
+
+	var err error
+	dec := gob.NewDecoder(reader)
+	for {
+		var wA wordAccepts
+		if err = dec.Decode(&wA); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if len(wA.Context) > 0 {
+			autoComplete.ngrams.retrieve(wA)
+			continue
+		}
+		keys, isWord := autoComplete.keysOf(wA.Word)
+		if !isWord {
+			err = autoComplete.Learn(wA.Word, wA.Keys...)
+			if err != nil {
+				return err
+			}
+			keys, _ = autoComplete.keysOf(wA.Word)
+		}
+		isEntry := true
+		if _, isFSTEntry := autoComplete.entries[wA.Word]; isFSTEntry && !autoComplete.removed[wA.Word] {
+			autoComplete.entryAccepts.retrieve(wA.Word, wA)
+		} else if _, isLearntEntry := autoComplete.overlay.entries[wA.Word]; isLearntEntry {
+			autoComplete.overlay.entryAccepts.retrieve(wA.Word, wA)
+		} else {
+			isEntry = false
+		}
+		for _, key := range keys {
+			if wA.Payload != nil {
+				autoComplete.setPayload(key, wA.Payload)
+			}
+			if wA.Weight != nil {
+				autoComplete.setWeight(key, *wA.Weight)
+			}
+			if isEntry {
+				continue
+			}
+			if wA.Accepts > 0 || wA.SetAccepts {
+				if len(autoComplete.fstForms(key)) > 0 {
+					autoComplete.accepts[key] = wA.Accepts
+				} else {
+					autoComplete.overlay.mutableWord(key).accepts = wA.Accepts
+				}
+			}
+			if wA.Decay != nil {
+				if len(autoComplete.fstForms(key)) > 0 {
+					autoComplete.decay.set(key, *wA.Decay)
+				} else {
+					autoComplete.overlay.decay.set(key, *wA.Decay)
+				}
+			}
+		}
+		if wA.Accepts < 0 {
+			autoComplete.UnLearn(wA.Word)
+		}
+		autoComplete.changes.set(wA.Word, wA.Changed)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// The FST file is a header followed by the state table, the transition table, the forms table and the forms data. All
+// integers are little endian uint32, so that the tables can be read in place from a memory-mapped file:
+//
+//	header      magic, version, state count, transition count, root state, forms count, forms data length
+//	state       first transition, transition count, flags (1 = final), number of words below the state
+//	transition  rune, target state, output
+//	forms       word ordinal, offset of its forms in the forms data, flags (1 = holds an entry)
+//	forms data  length and bytes of each surface form
+//
+// The forms table only has the words that are not their only form, sorted by ordinal; the forms of a word end where
+// the ones of the next begin.
+//
+// The output of a transition is the number of words that come alphabetically before any word through it, counting
+// from its state; adding up the outputs along the path of a word yields its ordinal.
+const (
+	fstMagic          = "SMACFST\x00"
+	fstVersion        = 2
+	fstHeaderSize     = 32
+	fstStateSize      = 16
+	fstTransitionSize = 12
+	fstFormsSize      = 12
+)
+
+type fstState struct {
+	first, count, words uint32
+	final               bool
+}
+
+type fstTransition struct {
+	r              rune
+	target, output uint32
+}
+
+// fstForms are the surface forms of the word ordinal, which holds an entry if one of them is not its own key.
+type fstForms struct {
+	ordinal uint32
+	forms   surfaceForms
+	entry   bool
+}
+
+// fstUnfrozen is a state on the path of the last word added, which can still gain transitions.
+type fstUnfrozen struct {
+	final       bool
+	transitions []fstTransition
+}
+
+// fstBuilder builds a minimal acyclic automaton from keys added in sorted order, freezing the states of a word as soon
+// as the next word leaves its path and merging each frozen state with an equivalent one if it has already been seen.
+type fstBuilder struct {
+	normalizer  Normalizer
+	states      []fstState
+	transitions []fstTransition
+	register    map[string]uint32
+	unfrozen    []*fstUnfrozen
+	previous    []rune
+	lastKey     string
+	words       uint32
+	forms       []fstForms
+}
+
+func newFSTBuilder(normalizer Normalizer) *fstBuilder {
+	return &fstBuilder{
+		normalizer: normalizer,
+		register:   make(map[string]uint32),
+		unfrozen:   []*fstUnfrozen{{}},
+	}
+}
+
+// add adds word to the automaton. Words must come in order of their key; forms sharing a key must be consecutive.
+func (builder *fstBuilder) add(word string) error {
+	return builder.addForm(builder.normalizer.Normalize(word), word)
+}
+
+// addForm adds word to the automaton under key, which is the key of word unless word is an entry (see Learn).
+func (builder *fstBuilder) addForm(key, word string) error {
+
+	if key == "" {
+		return errors.New("Empty word in dictionary")
+	}
+	if builder.words > 0 {
+		if key == builder.lastKey {
+			builder.addSurface(key, word)
+			return nil
+		}
+		if key < builder.lastKey {
+			return errors.New("Dictionary not sorted: " + word)
+		}
+	}
+
+	runes := []rune(key)
+	common := 0
+	for common < len(runes) && common < len(builder.previous) && runes[common] == builder.previous[common] {
+		common++
+	}
+	builder.freeze(common)
+
+	for _, r := range runes[common:] {
+		parent := builder.unfrozen[len(builder.unfrozen)-1]
+		parent.transitions = append(parent.transitions, fstTransition{r: r})
+		builder.unfrozen = append(builder.unfrozen, &fstUnfrozen{})
+	}
+	builder.unfrozen[len(builder.unfrozen)-1].final = true
+
+	if key != word {
+		builder.forms = append(builder.forms, fstForms{
+			ordinal: builder.words,
+			forms:   surfaceForms{word},
+			entry:   builder.normalizer.Normalize(word) != key,
+		})
+	}
+	builder.previous = runes
+	builder.lastKey = key
+	builder.words++
+	return nil
+}
+
+// addSurface adds word to the surface forms of the last word added, whose key is key.
+func (builder *fstBuilder) addSurface(key, word string) {
+
+	var forms surfaceForms
+	last := len(builder.forms) - 1
+	if last >= 0 && builder.forms[last].ordinal == builder.words-1 {
+		forms = builder.forms[last].forms
+	} else {
+		last = -1
+	}
+	if !forms.add(key, word) {
+		return
+	}
+	if last < 0 {
+		builder.forms = append(builder.forms, fstForms{ordinal: builder.words - 1})
+		last = len(builder.forms) - 1
+	}
+	builder.forms[last].forms = forms
+	if builder.normalizer.Normalize(word) != key {
+		builder.forms[last].entry = true
+	}
+}
+
+// freeze freezes the unfrozen states deeper than depth.
+func (builder *fstBuilder) freeze(depth int) {
+
+	for len(builder.unfrozen)-1 > depth {
+		state := builder.unfrozen[len(builder.unfrozen)-1]
+		builder.unfrozen = builder.unfrozen[:len(builder.unfrozen)-1]
+		parent := builder.unfrozen[len(builder.unfrozen)-1]
+		parent.transitions[len(parent.transitions)-1].target = builder.intern(state)
+	}
+}
+
+// intern returns the frozen state equivalent to state, creating it if there is none.
+func (builder *fstBuilder) intern(state *fstUnfrozen) uint32 {
+
+	signature := make([]byte, 1, 1+8*len(state.transitions))
+	if state.final {
+		signature[0] = 1
+	}
+	for _, t := range state.transitions {
+		signature = appendUint32(signature, uint32(t.r))
+		signature = appendUint32(signature, t.target)
+	}
+	if id, exists := builder.register[string(signature)]; exists {
+		return id
+	}
+
+	var words uint32
+	if state.final {
+		words = 1
+	}
+	first := uint32(len(builder.transitions))
+	for _, t := range state.transitions {
+		t.output = words
+		words += builder.states[t.target].words
+		builder.transitions = append(builder.transitions, t)
+	}
+	builder.states = append(builder.states, fstState{
+		first: first,
+		count: uint32(len(state.transitions)),
+		words: words,
+		final: state.final,
+	})
+	id := uint32(len(builder.states) - 1)
+	builder.register[string(signature)] = id
+	return id
+}
+
+// finish freezes the remaining states and writes the automaton to w.
+func (builder *fstBuilder) finish(w io.Writer) error {
+
+	builder.freeze(0)
+	root := builder.intern(builder.unfrozen[0])
+	builder.unfrozen = nil
+	builder.register = nil
+
+	var formsLength uint32
+	for _, f := range builder.forms {
+		for _, form := range f.forms {
+			formsLength += 4 + uint32(len(form))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	header := []byte(fstMagic)
+	header = appendUint32(header, fstVersion)
+	header = appendUint32(header, uint32(len(builder.states)))
+	header = appendUint32(header, uint32(len(builder.transitions)))
+	header = appendUint32(header, root)
+	header = appendUint32(header, uint32(len(builder.forms)))
+	header = appendUint32(header, formsLength)
+	bw.Write(header)
+
+	record := make([]byte, 0, fstStateSize)
+	for _, s := range builder.states {
+		var flags uint32
+		if s.final {
+			flags = 1
+		}
+		record = appendUint32(record[:0], s.first)
+		record = appendUint32(record, s.count)
+		record = appendUint32(record, flags)
+		record = appendUint32(record, s.words)
+		bw.Write(record)
+	}
+	for _, t := range builder.transitions {
+		record = appendUint32(record[:0], uint32(t.r))
+		record = appendUint32(record, t.target)
+		record = appendUint32(record, t.output)
+		bw.Write(record)
+	}
+	var offset uint32
+	for _, f := range builder.forms {
+		var flags uint32
+		if f.entry {
+			flags = 1
+		}
+		record = appendUint32(record[:0], f.ordinal)
+		record = appendUint32(record, offset)
+		record = appendUint32(record, flags)
+		bw.Write(record)
+		for _, form := range f.forms {
+			offset += 4 + uint32(len(form))
+		}
+	}
+	for _, f := range builder.forms {
+		for _, form := range f.forms {
+			bw.Write(appendUint32(record[:0], uint32(len(form))))
+			bw.WriteString(form)
+		}
+	}
+	return bw.Flush()
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// BuildFST builds the automaton of an AutoCompleteFST from a dictionary file and writes it to fstFileName, to be
+// loaded with NewAutoCompleteFSTM. The file is replaced atomically (see SaveFile), as autocompleters may be mapping it.
+//
+// Words in sortedDictionaryFileName must be sorted by key (their normalized form), and forms sharing a key must be
+// consecutive.
+//
+// options must include the same Normalizer that will be used by NewAutoCompleteFSTM. Payloads and weights, if a payload
+// or weight separator is set, are not part of the automaton and are skipped. A Tokenizer is not supported, as the keys
+// of the tokens of words would not come in order.
+func BuildFST(sortedDictionaryFileName, fstFileName string, options ...Option) error {
+
+	cfg := newConfig(options)
+	if cfg.tokenizer != nil {
+		return errors.New("Tokenizer not supported by BuildFST")
+	}
+
+	builder := newFSTBuilder(cfg.normalizer)
+	err := readDictionary(sortedDictionaryFileName, cfg, func(record Record) error {
+		return builder.add(record.Word)
+	})
+	if err != nil {
+		return err
+	}
+
+	return SaveFile(fstFileName, builder.finish)
+}
@@ -0,0 +1,41 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package smac
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mapFile maps fileName read-only in memory, returning its contents and the function that unmaps them.
+func mapFile(fileName string) ([]byte, func() error, error) {
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return []byte{}, func() error { return nil }, nil
+	}
+	if int64(int(size)) != size {
+		return nil, nil, errors.New("File too large to be mapped: " + fileName)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,19 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package smac
+
+import "io/ioutil"
+
+// mapFile reads fileName in memory, on platforms where it cannot be memory-mapped.
+func mapFile(fileName string) ([]byte, func() error, error) {
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
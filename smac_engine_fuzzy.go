@@ -0,0 +1,145 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+// levenshteinAutomaton recognizes prefixes within maxEdits edits (insertions, deletions, substitutions and transpositions
+// of adjacent runes) of a stem. It is fed one rune at a time while walking the prefixes of a dictionary, so that a whole
+// branch can be discarded as soon as none of its prefixes can match anymore.
+type levenshteinAutomaton struct {
+	stem     []rune
+	maxEdits int
+}
+
+// levenshteinState is the state of the automaton after reading a prefix. row holds the edit distances between the prefix
+// and every prefix of the stem; prevRow and prevRune are kept to detect transpositions.
+type levenshteinState struct {
+	row      []int
+	prevRow  []int
+	prevRune rune
+}
+
+type fuzzyMatch struct {
+	prefix   string
+	distance int
+}
+
+func newLevenshteinAutomaton(stem string, maxEdits int) *levenshteinAutomaton {
+	return &levenshteinAutomaton{
+		stem:     []rune(stem),
+		maxEdits: maxEdits,
+	}
+}
+
+func (a *levenshteinAutomaton) start() levenshteinState {
+	row := make([]int, len(a.stem)+1)
+	for i := range row {
+		row[i] = i
+	}
+	return levenshteinState{
+		row: row,
+	}
+}
+
+func (a *levenshteinAutomaton) step(state levenshteinState, r rune) levenshteinState {
+	row := make([]int, len(a.stem)+1)
+	row[0] = state.row[0] + 1
+
+	for i := 1; i <= len(a.stem); i++ {
+		cost := 1
+		if a.stem[i-1] == r {
+			cost = 0
+		}
+		row[i] = minInt(minInt(state.row[i]+1, row[i-1]+1), state.row[i-1]+cost)
+		if i > 1 && state.prevRow != nil && r == a.stem[i-2] && state.prevRune == a.stem[i-1] {
+			row[i] = minInt(row[i], state.prevRow[i-2]+1)
+		}
+	}
+	return levenshteinState{
+		row:      row,
+		prevRow:  state.row,
+		prevRune: r,
+	}
+}
+
+// distance is the edit distance between the prefix read so far and the stem.
+func (state levenshteinState) distance() int {
+	return state.row[len(state.row)-1]
+}
+
+// lowerBound is the smallest distance any extension of the prefix read so far can have from the stem.
+func (state levenshteinState) lowerBound() int {
+	lower := state.row[0]
+	for _, d := range state.row[1:] {
+		lower = minInt(lower, d)
+	}
+	return lower
+}
+
+// visit feeds r to the automaton on top of state and reports whether prefix (which already ends with r) is a match. A
+// prefix is reported only if it is strictly closer to the stem than its closest matching ancestor (best), since its
+// completions are otherwise already covered by the ancestor. descend tells whether extensions of prefix can still
+// produce a better match.
+func (a *levenshteinAutomaton) visit(state levenshteinState, r rune, prefix []rune, best int, matches *[]fuzzyMatch) (next levenshteinState, nextBest int, descend bool) {
+	next = a.step(state, r)
+	nextBest = best
+	if d := next.distance(); d < best {
+		*matches = append(*matches, fuzzyMatch{
+			prefix:   string(prefix),
+			distance: d,
+		})
+		nextBest = d
+	}
+	return next, nextBest, next.lowerBound() < nextBest
+}
+
+// completeFuzzy completes stem, which must be normalized, with complete if opts.MaxEdits is 0, and otherwise runs walk to
+// find the prefixes of the dictionary within opts.MaxEdits edits of stem and assembles their completions.
+func completeFuzzy(stem string, opts CompleteOptions, walk func(automaton *levenshteinAutomaton, matches *[]fuzzyMatch), complete func(prefix string) sOLILI) sOLILI {
+
+	if opts.MaxEdits == 0 {
+		return complete(stem)
+	}
+	automaton := newLevenshteinAutomaton(stem, opts.MaxEdits)
+	var matches []fuzzyMatch
+	walk(automaton, &matches)
+	return fuzzyCollect(matches, opts, complete)
+}
+
+// fuzzyCollect assembles the first Offset+Limit completions of matches, closest matches first. Within the same
+// distance, accepted words bubble up to the top as they do for Complete.
+func fuzzyCollect(matches []fuzzyMatch, opts CompleteOptions, complete func(prefix string) sOLILI) sOLILI {
+
+	seen := make(map[string]bool)
+	result := sOLILI{}
+	size := 0
+
+	for distance := 0; distance <= opts.MaxEdits && size < opts.Offset+opts.Limit; distance++ {
+		tier := newTopHits(opts.Offset + opts.Limit - size)
+		for _, match := range matches {
+			if match.distance != distance {
+				continue
+			}
+			completions := complete(match.prefix)
+			for cursor := completions.start; cursor != nil; {
+				hit := cursor
+				cursor = cursor.next
+				if !seen[hit.word] {
+					seen[hit.word] = true
+					hit.distance = distance
+					tier.insert(hit)
+				}
+			}
+		}
+		size += tier.size()
+		result.append(tier.list())
+	}
+	return result
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -4,31 +4,56 @@
 package smac
 
 import (
-	"bufio"
 	"encoding/gob"
 	"errors"
 	"io"
 	"os"
 	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type liNo struct {
 	accepts int
+	weight  float64
 	next    string
+	forms   surfaceForms
+	payload interface{}
 }
 
 // AutoCompleteLiNo is a "list node" implementation of AutoComplete
 type AutoCompleteLiNo struct {
-	wordMap        map[string]*liNo
-	head           string
-	tail           string
-	resultSize     int
-	radius         int
-	removedWords   map[string]bool
-	newWords       map[string]bool
+	wordMap      map[string]*liNo
+	head         string
+	tail         string
+	resultSize   int
+	radius       int
+	removedWords map[string]bool
+	newWords     map[string]bool
+	newPayloads  map[string]bool
+	newWeights   map[string]bool
+	// entries holds the keys of the words learnt with keys of their own (see Learn).
+	entries map[string][]string
+	// ngrams is the n-gram model taught by AcceptSequence.
+	ngrams nGrams
+	// decay holds the decayed accept counts of the keys, if there is a half-life (see WithHalfLife).
+	decay decay
+	// entryAccepts holds the accept counts of the entries, apart from the ones of their keys.
+	entryAccepts entryAccepts
+	// changes holds when words were last learnt or unlearnt (see Merge).
+	changes        changes
+	ranker         Ranker
 	prefixMap      map[string]string
 	prefixMapDepth int
+	alphabet       []rune
+	normalizer     Normalizer
+	tokenizer      Tokenizer
+	// owned holds the nodes a fork may modify in place, nil if autoComplete is not a fork (see fork).
+	owned map[*liNo]bool
+	// shared holds the nodes and prefixes of a fork below the ones in wordMap and prefixMap, which are the ones it has
+	// modified itself. It is nil if autoComplete is not a fork.
+	shared *liNoLayer
 }
 
 // NewAutoCompleteLinoE returns a new, empty autocompleter.
@@ -41,8 +66,8 @@ type AutoCompleteLiNo struct {
 //
 // The returned completer does not contain any words to be completed. New words can be added to it by using the Learn()
 // function
-func NewAutoCompleteLinoE(prefixMapDepth, resultSize, radius uint) (AutoCompleteLiNo, error) {
-	return NewAutoCompleteLinoS([]string{}, prefixMapDepth, resultSize, radius)
+func NewAutoCompleteLinoE(prefixMapDepth, resultSize, radius uint, options ...Option) (AutoCompleteLiNo, error) {
+	return NewAutoCompleteLinoS([]string{}, prefixMapDepth, resultSize, radius, options...)
 }
 
 // NewAutoCompleteLinoF returns a new autocompleter.
@@ -53,30 +78,29 @@ func NewAutoCompleteLinoE(prefixMapDepth, resultSize, radius uint) (AutoComplete
 //
 // radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
 //
-// New words can be added to it by using the Learn() function
-func NewAutoCompleteLinoF(dictionaryFileName string, prefixMapDepth, resultSize, radius uint) (AutoCompleteLiNo, error) {
+// New words can be added to it by using the Learn() function. With WithPayloadSeparator, every line holds a word and its
+// payload; with WithWeightSeparator, a word and its weight. WithCSV reads a CSV file instead. The file may be compressed
+// with gzip or bzip2.
+func NewAutoCompleteLinoF(dictionaryFileName string, prefixMapDepth, resultSize, radius uint, options ...Option) (AutoCompleteLiNo, error) {
 
 	var nAc AutoCompleteLiNo
 
 	f, err := os.Open(dictionaryFileName)
-	defer f.Close()
-
 	if err != nil {
 		return nAc, err
 	}
+	defer f.Close()
 
-	lineScanner := bufio.NewScanner(f)
-	var dictionary []string
-
-	for lineScanner.Scan() {
-		word := lineScanner.Text()
-		if len(word) == 0 {
-			return nAc, errors.New("Empty word in dictionary")
-		}
-		dictionary = append(dictionary, word)
-	}
+	return NewAutoCompleteLinoR(f, prefixMapDepth, resultSize, radius, options...)
+}
 
-	return NewAutoCompleteLinoS(dictionary, prefixMapDepth, resultSize, radius)
+// NewAutoCompleteLinoR returns a new autocompleter, as NewAutoCompleteLinoF does, from a dictionary read from reader,
+// which may be compressed with gzip or bzip2. Words are streamed from reader into the autocompleter.
+//
+// Lines starting with the prefix of WithCommentPrefix are skipped, and so are blank lines with WithSkipBlankLines.
+// Errors on bad input report the number of the line they were found at.
+func NewAutoCompleteLinoR(reader io.Reader, prefixMapDepth, resultSize, radius uint, options ...Option) (AutoCompleteLiNo, error) {
+	return buildAutoCompleteLino(readerRecords(reader, newConfig(options)), prefixMapDepth, resultSize, radius, options)
 }
 
 func makePrefixMap(sortedDictionary []string, maxDepth int) map[string]string {
@@ -84,9 +108,7 @@ func makePrefixMap(sortedDictionary []string, maxDepth int) map[string]string {
 	prefixes := make(map[string]string)
 
 	for _, word := range sortedDictionary {
-		runes := []rune(word)
-		for depth := 1; depth <= maxDepth && depth <= len(runes); depth++ {
-			prefix := string(runes[:depth])
+		for _, prefix := range runePrefixes(word, maxDepth) {
 			if _, exists := prefixes[prefix]; !exists {
 				prefixes[prefix] = word
 			}
@@ -95,6 +117,32 @@ func makePrefixMap(sortedDictionary []string, maxDepth int) map[string]string {
 	return prefixes
 }
 
+// runePrefixes returns the prefixes of word, shortest first, up to maxDepth runes long. Prefix depths are always
+// measured in runes, never in bytes, so that no prefix ends in the middle of a multi-byte UTF-8 sequence.
+func runePrefixes(word string, maxDepth int) []string {
+
+	var prefixes []string
+	for i := range word {
+		if i == 0 {
+			continue
+		}
+		if len(prefixes) == maxDepth {
+			return prefixes
+		}
+		prefixes = append(prefixes, word[:i])
+	}
+	if len(word) > 0 && len(prefixes) < maxDepth {
+		prefixes = append(prefixes, word)
+	}
+	return prefixes
+}
+
+// trimLastRune removes the last rune of word.
+func trimLastRune(word string) string {
+	_, size := utf8.DecodeLastRuneInString(word)
+	return word[:len(word)-size]
+}
+
 // NewAutoCompleteLinoS returns a new autocompleter.
 //
 // dictionary is a slice of words to be used for completion.
@@ -105,8 +153,21 @@ func makePrefixMap(sortedDictionary []string, maxDepth int) map[string]string {
 //
 // radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
 //
+// Words sharing the same key under the normalizer of the autocompleter (see WithNormalizer) are completed together.
+//
 // New words can be added to it by using the Learn() function
-func NewAutoCompleteLinoS(dictionary []string, prefixMapDepth, resultSize, radius uint) (AutoCompleteLiNo, error) {
+func NewAutoCompleteLinoS(dictionary []string, prefixMapDepth, resultSize, radius uint, options ...Option) (AutoCompleteLiNo, error) {
+	return NewAutoCompleteLinoP(wordRecords(dictionary), prefixMapDepth, resultSize, radius, options...)
+}
+
+// NewAutoCompleteLinoP returns a new autocompleter, as NewAutoCompleteLinoS does, from a dictionary of records. The
+// payload of each word is returned along with it by CompleteScored, and words with keys are entries matched by their
+// keys (see Learn).
+func NewAutoCompleteLinoP(dictionary []Record, prefixMapDepth, resultSize, radius uint, options ...Option) (AutoCompleteLiNo, error) {
+	return buildAutoCompleteLino(recordSlice(dictionary), prefixMapDepth, resultSize, radius, options)
+}
+
+func buildAutoCompleteLino(dictionary records, prefixMapDepth, resultSize, radius uint, options []Option) (AutoCompleteLiNo, error) {
 
 	var nAc AutoCompleteLiNo
 
@@ -121,32 +182,81 @@ func NewAutoCompleteLinoS(dictionary []string, prefixMapDepth, resultSize, radiu
 		radius = DefaultRadius
 	}
 
+	cfg := newConfig(options)
 	autoComplete := AutoCompleteLiNo{
 		wordMap:      make(map[string]*liNo),
 		resultSize:   int(resultSize),
 		radius:       int(radius),
 		newWords:     make(map[string]bool),
+		newPayloads:  make(map[string]bool),
+		newWeights:   make(map[string]bool),
 		removedWords: make(map[string]bool),
+		entries:      make(map[string][]string),
+		normalizer:   cfg.normalizer,
+		tokenizer:    cfg.tokenizer,
+		ranker:       cfg.ranker,
+		decay:        newDecay(cfg),
+		entryAccepts: newEntryAccepts(cfg),
+		changes:      newChanges(cfg),
+	}
+
+	var keys []string
+	err := dictionary(func(record Record) error {
+		word := record.Word
+		recordKeys := []string{autoComplete.normalizer.Normalize(word)}
+		entry := record.Keys
+		if len(entry) == 0 {
+			entry = tokenKeys(autoComplete.tokenizer, record.Word)
+		}
+		if len(entry) > 0 {
+			var err error
+			if recordKeys, err = entryKeys(autoComplete.normalizer, word, entry); err != nil {
+				return err
+			}
+			autoComplete.entries[word] = recordKeys
+		}
+		for _, key := range recordKeys {
+			lino, exists := autoComplete.wordMap[key]
+			if exists {
+				lino.forms.add(key, word)
+			} else {
+				lino = &liNo{}
+				if key != word {
+					lino.forms = surfaceForms{word}
+				}
+				autoComplete.wordMap[key] = lino
+				keys = append(keys, key)
+			}
+			if record.Payload != nil {
+				lino.payload = record.Payload
+			}
+			if record.Weight > lino.weight {
+				lino.weight = record.Weight
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nAc, err
 	}
 
-	sort.Strings(dictionary)
+	sort.Strings(keys)
 	var linop *liNo
 
-	for _, word := range dictionary {
-		newLinop := &liNo{}
-		autoComplete.wordMap[word] = newLinop
+	for _, key := range keys {
+		newLinop := autoComplete.wordMap[key]
 		if linop != nil {
-			linop.next = word
+			linop.next = key
 		}
 		linop = newLinop
-
+		autoComplete.learnAlphabet(key)
 	}
-	if len(dictionary) > 0 {
-		autoComplete.head = dictionary[0]
-		autoComplete.tail = dictionary[len(dictionary)-1]
+	if len(keys) > 0 {
+		autoComplete.head = keys[0]
+		autoComplete.tail = keys[len(keys)-1]
 	}
 
-	autoComplete.prefixMap = makePrefixMap(dictionary, int(prefixMapDepth))
+	autoComplete.prefixMap = makePrefixMap(keys, int(prefixMapDepth))
 	autoComplete.prefixMapDepth = int(prefixMapDepth)
 
 	return autoComplete, nil
@@ -154,84 +264,294 @@ func NewAutoCompleteLinoS(dictionary []string, prefixMapDepth, resultSize, radiu
 
 // Complete : see description in AutoComplete interface
 func (autoComplete *AutoCompleteLiNo) Complete(stem string) ([]string, error) {
+	return autoComplete.CompleteWith(stem, CompleteOptions{})
+}
 
-	result := sOLILI{}
-	hits := 0
-	//radius := 0
-	lino, hit := autoComplete.wordMap[stem]
+// CompleteWith : see description in AutoComplete interface. Radius is the number of words scanned, which is extended
+// by Offset when paging.
+func (autoComplete *AutoCompleteLiNo) CompleteWith(stem string, opts CompleteOptions) ([]string, error) {
 
-	if hit {
-		hits++
-		result.insert(stem, lino.accepts)
-	} else {
-		subStem := stem
-		prefixRoot, prefixExists := autoComplete.prefixMap[subStem]
-
-		for !prefixExists && len(subStem) > 0 {
-			subStem = subStem[:len(subStem)-1]
-			prefixRoot, prefixExists = autoComplete.prefixMap[subStem]
-		}
-		if prefixExists {
-			searchPtr := prefixRoot
-			for !strings.HasPrefix(searchPtr, stem) {
-				searchPtr = autoComplete.wordMap[searchPtr].next
-				if searchPtr == "" || !strings.HasPrefix(searchPtr, subStem) {
-					return []string{}, nil
+	result, opts, err := autoComplete.completeList(stem, opts)
+	if err != nil {
+		return nil, err
+	}
+	return opts.page(result), nil
+}
+
+// CompleteScored : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) CompleteScored(stem string, opts CompleteOptions) ([]Result, error) {
+
+	result, opts, err := autoComplete.completeList(stem, opts)
+	if err != nil {
+		return nil, err
+	}
+	return opts.pageResults(result), nil
+}
+
+// CompleteQuery : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) CompleteQuery(query string) ([]string, error) {
+	return completeQuery(autoComplete, autoComplete.tokenizer, autoComplete.normalizer, autoComplete.resultSize, query)
+}
+
+// AcceptSequence : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) AcceptSequence(words []string) error {
+	return autoComplete.ngrams.accept(autoComplete.normalizer, words)
+}
+
+// Predict : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) Predict(previousWords []string, stem string) ([]string, error) {
+	return autoComplete.ngrams.predict(autoComplete, autoComplete.normalizer, autoComplete.resultSize, previousWords, stem)
+}
+
+func (autoComplete *AutoCompleteLiNo) completeList(stem string, opts CompleteOptions) (sOLILI, CompleteOptions, error) {
+
+	opts, err := opts.resolve(autoComplete.resultSize, autoComplete.radius)
+	if err != nil {
+		return sOLILI{}, opts, err
+	}
+	walk := func(automaton *levenshteinAutomaton, matches *[]fuzzyMatch) {
+		autoComplete.fuzzyWalk(automaton, automaton.start(), nil, opts.MaxEdits+1, matches)
+	}
+	complete := func(prefix string) sOLILI {
+		return autoComplete.complete(prefix, opts)
+	}
+	return completeFuzzy(autoComplete.normalizer.Normalize(stem), opts, walk, complete), opts, nil
+}
+
+// complete scans the words whose key starts with stem, which must be normalized.
+func (autoComplete *AutoCompleteLiNo) complete(stem string, opts CompleteOptions) sOLILI {
+
+	result := newEntryHits(opts.Offset + opts.Limit)
+	key, hit := autoComplete.firstWithPrefix(stem)
+	matchLength := utf8.RuneCountInString(stem)
+
+	// the radius bounds both the words scanned, whether their length is admitted or not, and the hits.
+	for hits, scanned := 0, 0; hit && hits < opts.Radius+opts.Offset && scanned < opts.Radius+opts.Offset; scanned++ {
+		lino := autoComplete.node(key)
+		if opts.admits(utf8.RuneCountInString(key)) {
+			candidate := autoComplete.decay.candidate(key, lino.accepts)
+			candidate.Weight = lino.weight
+			for _, form := range lino.forms.list(key) {
+				if result.insert(opts.hit(autoComplete.ranker, form, autoComplete.entryAccepts.candidate(form, candidate, autoComplete.entries), lino.payload, autoComplete.newWords[form], matchLength), key, stem, autoComplete.normalizer, autoComplete.entries) {
+					hits++
 				}
 			}
-			hit = true
-			lino = autoComplete.wordMap[searchPtr]
-			if _, prefixRootIsWord := autoComplete.wordMap[searchPtr]; prefixRootIsWord {
-				hits++
-				result.insert(searchPtr, lino.accepts)
+		}
+		key = lino.next
+		hit = strings.HasPrefix(key, stem)
+	}
+	return result.list()
+}
+
+// firstWithPrefix returns the first word in the dictionary starting with stem.
+func (autoComplete *AutoCompleteLiNo) firstWithPrefix(stem string) (string, bool) {
+
+	if autoComplete.node(stem) != nil {
+		return stem, true
+	}
+	subStem := stem
+	prefixRoot, prefixExists := autoComplete.prefix(subStem)
+
+	for !prefixExists && len(subStem) > 0 {
+		subStem = trimLastRune(subStem)
+		prefixRoot, prefixExists = autoComplete.prefix(subStem)
+	}
+	if !prefixExists {
+		return "", false
+	}
+	searchPtr := prefixRoot
+	for !strings.HasPrefix(searchPtr, stem) {
+		searchPtr = autoComplete.node(searchPtr).next
+		if searchPtr == "" || !strings.HasPrefix(searchPtr, subStem) {
+			return "", false
+		}
+	}
+	return searchPtr, true
+}
+
+// CompleteFuzzy : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	return autoComplete.CompleteWith(stem, CompleteOptions{MaxEdits: maxEdits})
+}
+
+// fuzzyWalk runs automaton over the prefixes of the dictionary. There is no tree to walk, so the prefixes following a
+// given one are generated from the alphabet and checked against prefixMap as long as it is deep enough, and are read off
+// the word list past that.
+func (autoComplete *AutoCompleteLiNo) fuzzyWalk(automaton *levenshteinAutomaton, state levenshteinState, prefix []rune, best int, matches *[]fuzzyMatch) {
+
+	if len(prefix) >= autoComplete.radius {
+		return
+	}
+	for _, r := range autoComplete.nextRunes(string(prefix), len(prefix)) {
+		childPrefix := append(prefix[:len(prefix):len(prefix)], r)
+		childState, childBest, descend := automaton.visit(state, r, childPrefix, best, matches)
+		if descend {
+			autoComplete.fuzzyWalk(automaton, childState, childPrefix, childBest, matches)
+		}
+	}
+}
+
+// nextRunes returns, in order, the runes following prefix (depth runes long) in the words of the dictionary.
+func (autoComplete *AutoCompleteLiNo) nextRunes(prefix string, depth int) []rune {
+
+	var next []rune
+
+	if depth < autoComplete.prefixMapDepth {
+		for _, r := range autoComplete.alphabet {
+			if _, exists := autoComplete.prefix(prefix + string(r)); exists {
+				next = append(next, r)
 			}
 		}
+		return next
 	}
-	for hit && hits < autoComplete.radius {
-		word := lino.next
-		hit = strings.HasPrefix(word, stem)
-		if hit {
-			lino = autoComplete.wordMap[word]
-			hits++
-			result.insert(word, lino.accepts)
+
+	word, hit := autoComplete.firstWithPrefix(prefix)
+	if prefix == "" {
+		word, hit = autoComplete.head, autoComplete.head != ""
+	}
+	for hit {
+		if len(word) > len(prefix) {
+			r, _ := utf8.DecodeRuneInString(word[len(prefix):])
+			if len(next) == 0 || next[len(next)-1] != r {
+				next = append(next, r)
+			}
+		}
+		word = autoComplete.node(word).next
+		hit = word != "" && strings.HasPrefix(word, prefix)
+	}
+	return next
+}
+
+// learnAlphabet adds to the alphabet of the autocompleter the runes of word it does not contain yet.
+func (autoComplete *AutoCompleteLiNo) learnAlphabet(word string) {
+
+	for _, r := range word {
+		i := sort.Search(len(autoComplete.alphabet), func(i int) bool {
+			return autoComplete.alphabet[i] >= r
+		})
+		if i < len(autoComplete.alphabet) && autoComplete.alphabet[i] == r {
+			continue
 		}
+		autoComplete.alphabet = append(autoComplete.alphabet, 0)
+		copy(autoComplete.alphabet[i+1:], autoComplete.alphabet[i:])
+		autoComplete.alphabet[i] = r
 	}
-	return result.flushL(autoComplete.resultSize), nil
 }
 
 // Accept : see description in AutoComplete interface
 func (autoComplete *AutoCompleteLiNo) Accept(acceptedWord string) error {
 
-	var lino *liNo
-	var exist bool
-	if lino, exist = autoComplete.wordMap[acceptedWord]; !exist {
+	if _, isEntry := autoComplete.entries[acceptedWord]; isEntry {
+		autoComplete.entryAccepts.accept(acceptedWord)
+		return nil
+	}
+	key := autoComplete.normalizer.Normalize(acceptedWord)
+	if autoComplete.node(key) == nil {
 		return errors.New("Word to be accepted not found")
 	}
-	lino.accepts++
+	autoComplete.mutable(key).accepts++
+	autoComplete.decay.accept(key)
 	return nil
 }
 
 // Learn : see description in AutoComplete interface
-func (autoComplete *AutoCompleteLiNo) Learn(word string) error {
+func (autoComplete *AutoCompleteLiNo) Learn(word string, keys ...string) error {
 
-	if _, exists := autoComplete.wordMap[word]; exists {
+	if len(keys) == 0 {
+		keys = tokenKeys(autoComplete.tokenizer, word)
+	}
+	if _, isWord := autoComplete.keysOf(word); isWord {
 		return errors.New("Word already in dictionary")
 	}
+	wordKeys, err := entryKeys(autoComplete.normalizer, word, keys)
+	if err != nil {
+		return err
+	}
+	for _, key := range wordKeys {
+		if lino := autoComplete.node(key); lino != nil && lino.forms.contains(key, word) {
+			return errors.New("Word already in dictionary")
+		}
+	}
+
+	for _, key := range wordKeys {
+		if autoComplete.node(key) != nil {
+			autoComplete.mutable(key).forms.add(key, word)
+			continue
+		}
+		autoComplete.insert(key)
+		if key != word {
+			autoComplete.wordMap[key].forms = surfaceForms{word}
+		}
+	}
+	if len(keys) > 0 {
+		autoComplete.entries[word] = wordKeys
+	}
+	autoComplete.learnt(word)
+	return nil
+}
+
+// LearnWithPayload : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) LearnWithPayload(word string, payload interface{}, keys ...string) error {
+
+	wordKeys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		if err := autoComplete.Learn(word, keys...); err != nil {
+			return err
+		}
+		wordKeys, _ = autoComplete.keysOf(word)
+	}
+	for _, key := range wordKeys {
+		autoComplete.mutable(key).payload = payload
+		autoComplete.newPayloads[key] = true
+	}
+	return nil
+}
+
+// SetWeight : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) SetWeight(word string, weight float64) error {
+
+	keys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		return errors.New("Word not in dictionary")
+	}
+	for _, key := range keys {
+		autoComplete.mutable(key).weight = weight
+		autoComplete.newWeights[key] = true
+	}
+	return nil
+}
+
+// keysOf returns the keys word is matched by, and whether word is in the dictionary.
+func (autoComplete *AutoCompleteLiNo) keysOf(word string) ([]string, bool) {
+
+	if keys, isEntry := autoComplete.entries[word]; isEntry {
+		return keys, true
+	}
+	key := autoComplete.normalizer.Normalize(word)
+	lino := autoComplete.node(key)
+	return []string{key}, lino != nil && lino.forms.contains(key, word)
+}
+
+func (autoComplete *AutoCompleteLiNo) insert(word string) {
 
 	prevWord := autoComplete.findPreviousWord(word)
-	prevLino := autoComplete.wordMap[prevWord]
 
 	newLino := &liNo{}
 	autoComplete.wordMap[word] = newLino
+	if autoComplete.owned != nil {
+		autoComplete.owned[newLino] = true
+	}
 
 	if prevWord != autoComplete.head {
+		prevLino := autoComplete.mutable(prevWord)
 		newLino.next = prevLino.next
 		prevLino.next = word
 	} else { // we're at head
 		if autoComplete.head != "" {
 			if word > autoComplete.head {
-				newLino.next = autoComplete.wordMap[autoComplete.head].next
-				autoComplete.wordMap[autoComplete.head].next = word
+				headLino := autoComplete.mutable(autoComplete.head)
+				newLino.next = headLino.next
+				headLino.next = word
 			} else {
 				newLino.next = autoComplete.head
 			}
@@ -245,48 +565,95 @@ func (autoComplete *AutoCompleteLiNo) Learn(word string) error {
 		autoComplete.tail = word
 	}
 
-	for i := 1; i <= autoComplete.prefixMapDepth && i <= len(word); i++ {
-		prefix := word[:i]
-		if _, exists := autoComplete.prefixMap[prefix]; !exists {
+	for _, prefix := range runePrefixes(word, autoComplete.prefixMapDepth) {
+		if first, exists := autoComplete.prefix(prefix); !exists {
 			autoComplete.prefixMap[prefix] = word
 		} else {
-			if word < autoComplete.prefixMap[prefix] {
+			if word < first {
 				autoComplete.prefixMap[prefix] = word
 			}
 		}
 	}
-	autoComplete.newWords[word] = true
-	return nil
+	autoComplete.learnAlphabet(word)
 }
 
-// UnLearn : see description in AutoComplete interface
+// UnLearn : see description in AutoComplete interface. If word is one of several forms sharing a key, only that form is
+// removed; otherwise the key is removed along with all of its forms, but for the entries it is a key of.
 func (autoComplete *AutoCompleteLiNo) UnLearn(word string) error {
 
-	if _, exists := autoComplete.wordMap[word]; !exists {
+	if keys, isEntry := autoComplete.entries[word]; isEntry {
+		for _, key := range keys {
+			autoComplete.removeForms(key, []string{word})
+		}
+		delete(autoComplete.entries, word)
+		autoComplete.entryAccepts.forget(word)
+		autoComplete.unlearnt(word)
+		return nil
+	}
+
+	key := autoComplete.normalizer.Normalize(word)
+	lino := autoComplete.node(key)
+	if lino == nil {
 		return errors.New("Word not in dictionary")
 	}
+	forms := []string{word}
+	if !lino.forms.contains(key, word) {
+		forms = nil
+		for _, form := range lino.forms.list(key) {
+			if _, isEntry := autoComplete.entries[form]; !isEntry {
+				forms = append(forms, form)
+			}
+		}
+		if len(forms) == 0 {
+			return errors.New("Word not in dictionary")
+		}
+	}
+	autoComplete.removeForms(key, forms)
+	for _, form := range forms {
+		autoComplete.unlearnt(form)
+	}
+	return nil
+}
+
+// removeForms removes forms from the forms of key, and key itself if no form is left.
+func (autoComplete *AutoCompleteLiNo) removeForms(key string, forms []string) {
+
+	remaining := autoComplete.node(key).forms
+	for _, form := range forms {
+		remaining.remove(key, form)
+	}
+	if len(remaining.list(key)) == 0 {
+		autoComplete.remove(key)
+		delete(autoComplete.newPayloads, key)
+		delete(autoComplete.newWeights, key)
+		autoComplete.decay.forget(key)
+		return
+	}
+	autoComplete.mutable(key).forms = remaining
+}
+
+func (autoComplete *AutoCompleteLiNo) remove(word string) {
 
 	prevWord := autoComplete.findPreviousWord(word)
 	var nextWord string
-	prevLino := autoComplete.wordMap[prevWord]
-	if autoComplete.wordMap[word].next == "" {
+	prevLino := autoComplete.mutable(prevWord)
+	if autoComplete.node(word).next == "" {
 		prevLino.next = ""
 	} else {
-		nextWord = autoComplete.wordMap[word].next
+		nextWord = autoComplete.node(word).next
 		prevLino.next = nextWord
 	}
 
-	delete(autoComplete.wordMap, word)
+	autoComplete.removeNode(word)
 
-	for i := 1; i <= autoComplete.prefixMapDepth && i < len(word); i++ {
-		prefix := word[:i]
-		if _, exists := autoComplete.prefixMap[prefix]; exists {
-			if autoComplete.prefixMap[prefix] == word {
+	for _, prefix := range runePrefixes(word, autoComplete.prefixMapDepth) {
+		if first, exists := autoComplete.prefix(prefix); exists {
+			if first == word {
 				// does next word start with prefix? if yes, assign, otherwise prefix is gone
 				if strings.HasPrefix(nextWord, prefix) {
 					autoComplete.prefixMap[prefix] = nextWord
 				} else {
-					delete(autoComplete.prefixMap, prefix)
+					autoComplete.removePrefix(prefix)
 				}
 			}
 		}
@@ -297,24 +664,82 @@ func (autoComplete *AutoCompleteLiNo) UnLearn(word string) error {
 	if autoComplete.tail == word {
 		autoComplete.tail = prevWord
 	}
+}
+
+// mutable returns the node of word, copying it first if it is shared with the autocompleter autoComplete was forked
+// from. It returns nil if word is not in the dictionary.
+func (autoComplete *AutoCompleteLiNo) mutable(word string) *liNo {
+
+	lino := autoComplete.node(word)
+	if lino == nil || autoComplete.owned == nil || autoComplete.owned[lino] {
+		return lino
+	}
+	clone := *lino
+	autoComplete.owned[&clone] = true
+	autoComplete.wordMap[word] = &clone
+	return &clone
+}
+
+// fork returns a copy of autoComplete to apply changes to, leaving autoComplete untouched. The nodes and prefixes of
+// autoComplete are shared as layers below the ones the fork modifies (see liNoLayer), and nodes are copied as they are
+// modified.
+func (autoComplete *AutoCompleteLiNo) fork() forker {
+
+	forked := *autoComplete
+	forked.shared = autoComplete.pushLayer()
+	forked.wordMap = make(map[string]*liNo)
+	forked.prefixMap = make(map[string]string)
+	forked.newWords = copyWordSet(autoComplete.newWords)
+	forked.newPayloads = copyWordSet(autoComplete.newPayloads)
+	forked.newWeights = copyWordSet(autoComplete.newWeights)
+	forked.entries = copyEntries(autoComplete.entries)
+	forked.removedWords = copyWordSet(autoComplete.removedWords)
+	forked.alphabet = append([]rune(nil), autoComplete.alphabet...)
+	forked.owned = make(map[*liNo]bool)
+	forked.ngrams = autoComplete.ngrams.copy()
+	forked.decay = autoComplete.decay.copy()
+	forked.entryAccepts = autoComplete.entryAccepts.copy()
+	forked.changes = autoComplete.changes.copy()
+	return &forked
+}
+
+// now : see description in clocked interface
+func (autoComplete *AutoCompleteLiNo) now() time.Time {
+	return autoComplete.changes.clock()
+}
+
+// at : see description in clocked interface
+func (autoComplete *AutoCompleteLiNo) at(at time.Time, change func() error) error {
+	return atTime(at, change, &autoComplete.decay.clock, &autoComplete.entryAccepts.decay.clock, &autoComplete.changes.clock)
+}
+
+// learnt and unlearnt keep track of the difference between the bootstrap dictionary and the learnt one.
+func (autoComplete *AutoCompleteLiNo) learnt(word string) {
+	if _, contains := autoComplete.removedWords[word]; contains {
+		delete(autoComplete.removedWords, word)
+	} else {
+		autoComplete.newWords[word] = true
+	}
+	autoComplete.changes.record(word)
+}
 
+func (autoComplete *AutoCompleteLiNo) unlearnt(word string) {
 	if _, contains := autoComplete.newWords[word]; !contains {
 		autoComplete.removedWords[word] = true
 	} else {
 		delete(autoComplete.newWords, word)
 	}
-
-	return nil
+	autoComplete.changes.record(word)
 }
 
 func (autoComplete *AutoCompleteLiNo) findPreviousWord(word string) string {
 
-	prefix := word[:len(word)-1]
-	searchPtr, prefixExists := autoComplete.prefixMap[prefix]
+	prefix := trimLastRune(word)
+	searchPtr, prefixExists := autoComplete.prefix(prefix)
 
 	for len(prefix) > 0 && (!prefixExists || word <= searchPtr) {
-		prefix = prefix[:len(prefix)-1]
-		searchPtr, prefixExists = autoComplete.prefixMap[prefix]
+		prefix = trimLastRune(prefix)
+		searchPtr, prefixExists = autoComplete.prefix(prefix)
 	}
 	// find the longest prefix present in prefixMap
 	if searchPtr == "" { // prefix not found
@@ -324,7 +749,7 @@ func (autoComplete *AutoCompleteLiNo) findPreviousWord(word string) string {
 	// now scan from longest prefix ptr until next word in dictionary is found
 	for searchPtr != "" && searchPtr < word {
 		prevWord = searchPtr
-		searchPtr = autoComplete.wordMap[searchPtr].next
+		searchPtr = autoComplete.node(searchPtr).next
 	}
 
 	return prevWord
@@ -332,46 +757,80 @@ func (autoComplete *AutoCompleteLiNo) findPreviousWord(word string) string {
 
 // Save : see description in AutoComplete interface
 func (autoComplete *AutoCompleteLiNo) Save(fileName string) error {
+	return SaveFile(fileName, autoComplete.SaveTo)
+}
 
-	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
-	if err != nil {
-		return err
-	}
+// SaveTo : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) SaveTo(writer io.Writer) error {
+	return writeSave(writer, autoComplete.saveHeader(), autoComplete.saveBody)
+}
+
+// saveHeader describes autoComplete in the header of its save files.
+func (autoComplete *AutoCompleteLiNo) saveHeader() saveHeader {
+	return saveHeader{Engine: "LiNo", Normalizer: normalizerName(autoComplete.normalizer), PrefixMapDepth: autoComplete.prefixMapDepth, ResultSize: autoComplete.resultSize, Radius: autoComplete.radius}
+}
+
+// saveBody writes the body of a save file of autoComplete.
+func (autoComplete *AutoCompleteLiNo) saveBody(writer io.Writer) error {
 
-	enc := gob.NewEncoder(f)
+	enc := &wordEncoder{enc: gob.NewEncoder(writer)}
+	var written entrySet
 
-	for w, liNo := range autoComplete.wordMap {
-		if liNo.accepts > 0 {
-			enc.Encode(wordAccepts{
-				w,
-				liNo.accepts,
-			})
-		} else if _, exists := autoComplete.newWords[w]; exists {
-			enc.Encode(wordAccepts{
-				w,
-				liNo.accepts,
-			})
+	for key := autoComplete.head; key != ""; key = autoComplete.node(key).next {
+		liNo := autoComplete.node(key)
+		var payload interface{}
+		if autoComplete.newPayloads[key] {
+			payload = liNo.payload
+		}
+		var weight *float64
+		if autoComplete.newWeights[key] {
+			weight = &liNo.weight
+		}
+		for _, w := range liNo.forms.list(key) {
+			accepts, decayed := autoComplete.entryAccepts.saved(w, liNo.accepts, autoComplete.decay.get(key), autoComplete.entries)
+			if accepts > 0 || autoComplete.newWords[w] || autoComplete.newPayloads[key] || weight != nil {
+				if written.first(w, autoComplete.entries) {
+					enc.encode(wordAccepts{
+						Word:    w,
+						Accepts: accepts,
+						New:     autoComplete.newWords[w],
+						Decay:   decayed,
+						Payload: payload,
+						Weight:  weight,
+						Keys:    autoComplete.entries[w],
+						Changed: autoComplete.changes.get(w),
+					})
+				}
+			}
 		}
 	}
 
 	for w := range autoComplete.removedWords {
-		enc.Encode(wordAccepts{
-			w,
-			-1,
-		})
+		enc.encode(wordAccepts{Word: w, Accepts: -1, Changed: autoComplete.changes.get(w)})
 	}
-	return f.Close()
+	autoComplete.changes.save(enc, func(word string) bool {
+		_, isWord := autoComplete.keysOf(word)
+		return isWord
+	})
+	autoComplete.ngrams.save(enc)
+	return enc.err
 }
 
 // Retrieve : see description in AutoComplete interface
 func (autoComplete *AutoCompleteLiNo) Retrieve(fileName string) error {
-	f, err := os.Open(fileName)
-	defer f.Close()
-	if err != nil {
-		return err
-	}
+	return retrieveFile(fileName, autoComplete.RetrieveFrom)
+}
+
+// RetrieveFrom : see description in AutoComplete interface
+func (autoComplete *AutoCompleteLiNo) RetrieveFrom(reader io.Reader) error {
+	return readSave(reader, autoComplete.saveHeader(), autoComplete.retrieveBody)
+}
 
-	dec := gob.NewDecoder(f)
+// retrieveBody reads the body of a save file.
+func (autoComplete *AutoCompleteLiNo) retrieveBody(reader io.Reader) error {
+
+	var err error
+	dec := gob.NewDecoder(reader)
 	for {
 		var wA wordAccepts
 		if err = dec.Decode(&wA); err == io.EOF {
@@ -379,18 +838,181 @@ func (autoComplete *AutoCompleteLiNo) Retrieve(fileName string) error {
 		} else if err != nil {
 			return err
 		}
-		if _, exists := autoComplete.wordMap[wA.Word]; !exists {
-			err = autoComplete.Learn(wA.Word)
+		if len(wA.Context) > 0 {
+			autoComplete.ngrams.retrieve(wA)
+			continue
+		}
+		keys, isWord := autoComplete.keysOf(wA.Word)
+		if !isWord {
+			err = autoComplete.Learn(wA.Word, wA.Keys...)
 			if err != nil {
 				return err
 			}
+			keys, _ = autoComplete.keysOf(wA.Word)
+		}
+		_, isEntry := autoComplete.entries[wA.Word]
+		if isEntry {
+			autoComplete.entryAccepts.retrieve(wA.Word, wA)
 		}
-		if wA.Accepts > 0 {
-			l := autoComplete.wordMap[wA.Word]
-			l.accepts = wA.Accepts
-		} else if wA.Accepts < 0 {
+		for _, key := range keys {
+			if wA.Payload != nil {
+				autoComplete.mutable(key).payload = wA.Payload
+				autoComplete.newPayloads[key] = true
+			}
+			if wA.Weight != nil {
+				autoComplete.mutable(key).weight = *wA.Weight
+				autoComplete.newWeights[key] = true
+			}
+			if isEntry {
+				continue
+			}
+			if wA.Accepts > 0 || wA.SetAccepts {
+				autoComplete.mutable(key).accepts = wA.Accepts
+			}
+			if wA.Decay != nil {
+				autoComplete.decay.set(key, *wA.Decay)
+			}
+		}
+		if wA.Accepts < 0 {
 			autoComplete.UnLearn(wA.Word)
 		}
+		autoComplete.changes.set(wA.Word, wA.Changed)
+	}
+	return nil
+}
+
+// WriteIndex writes the whole of autoComplete, the words of its dictionary along with what it has learnt, to the index
+// fileName, which LoadIndex loads without sorting words or building the prefix map again. The file is replaced
+// atomically.
+func (autoComplete *AutoCompleteLiNo) WriteIndex(fileName string) error {
+	return SaveFile(fileName, autoComplete.writeIndex)
+}
+
+// The words of a LiNo index are its prefix map depth, its alphabet, its keys in order with the flags and fields of each
+// (see indexWriter.word) and its prefix map, each prefix followed by the position of its key.
+func (autoComplete *AutoCompleteLiNo) writeIndex(writer io.Writer) error {
+
+	iw := newIndexWriter(writer, "LiNo", autoComplete.normalizer)
+	iw.uvarint(uint64(autoComplete.prefixMapDepth))
+	iw.string(string(autoComplete.alphabet))
+
+	var keys []string
+	for key := autoComplete.head; key != ""; key = autoComplete.node(key).next {
+		keys = append(keys, key)
+	}
+	iw.uvarint(uint64(len(keys)))
+	positions := make(map[string]int, len(keys))
+	payloads := make(map[string]interface{})
+	for _, key := range keys {
+		lino := autoComplete.node(key)
+		positions[key] = len(positions)
+		iw.string(key)
+		iw.word(0, lino.accepts, lino.weight, lino.forms)
+		if lino.payload != nil {
+			payloads[key] = lino.payload
+		}
+	}
+
+	prefixes := autoComplete.prefixes()
+	iw.uvarint(uint64(len(prefixes)))
+	for prefix, key := range prefixes {
+		iw.string(prefix)
+		iw.uvarint(uint64(positions[key]))
+	}
+
+	return iw.close(indexState{
+		Payloads:     payloads,
+		NewWords:     setList(autoComplete.newWords),
+		NewPayloads:  setList(autoComplete.newPayloads),
+		NewWeights:   setList(autoComplete.newWeights),
+		RemovedWords: setList(autoComplete.removedWords),
+		Entries:      autoComplete.entries,
+		NGramCounts:  autoComplete.ngrams.counts,
+		NGramTotals:  autoComplete.ngrams.totals,
+		Decay:        autoComplete.decay.accepts,
+		EntryAccepts: autoComplete.entryAccepts.accepts,
+		EntryDecay:   autoComplete.entryAccepts.decay.accepts,
+		Changes:      autoComplete.changes.at,
+	})
+}
+
+// LoadIndex replaces the words of autoComplete, and what it has learnt, with the ones of the index fileName, written by
+// WriteIndex. autoComplete must be created by NewAutoCompleteLinoE with the normalizer the index was written with, or
+// LoadIndex fails; the prefix map depth of the index replaces its own.
+func (autoComplete *AutoCompleteLiNo) LoadIndex(fileName string) error {
+
+	ir, err := openIndex(fileName, "LiNo", autoComplete.normalizer)
+	if err != nil {
+		return err
+	}
+	prefixMapDepth := int(ir.uvarint())
+	alphabet := []rune(ir.string())
+
+	count := ir.uvarint()
+	if count > uint64(ir.reader.Len()) {
+		return errors.New("Corrupted index file: word count out of range")
+	}
+	keys := make([]string, count)
+	wordMap := make(map[string]*liNo, count)
+	var previous *liNo
+	for i := range keys {
+		keys[i] = ir.string()
+		lino := &liNo{}
+		_, lino.accepts, lino.weight, lino.forms = ir.word()
+		wordMap[keys[i]] = lino
+		if previous != nil {
+			previous.next = keys[i]
+		}
+		previous = lino
+	}
+
+	prefixCount := ir.uvarint()
+	if prefixCount > uint64(ir.reader.Len()) {
+		return errors.New("Corrupted index file: prefix count out of range")
+	}
+	prefixMap := make(map[string]string, prefixCount)
+	for i := uint64(0); i < prefixCount && ir.err == nil; i++ {
+		prefix := ir.string()
+		position := ir.uvarint()
+		if position >= count {
+			return errors.New("Corrupted index file: word position out of range")
+		}
+		prefixMap[prefix] = keys[position]
+	}
+
+	state, err := ir.close()
+	if err != nil {
+		return err
+	}
+	for key, payload := range state.Payloads {
+		if lino, exists := wordMap[key]; exists {
+			lino.payload = payload
+		}
+	}
+
+	autoComplete.wordMap = wordMap
+	autoComplete.head, autoComplete.tail = "", ""
+	if count > 0 {
+		autoComplete.head = keys[0]
+		autoComplete.tail = keys[count-1]
+	}
+	autoComplete.prefixMap = prefixMap
+	autoComplete.prefixMapDepth = prefixMapDepth
+	autoComplete.alphabet = alphabet
+	autoComplete.newWords = listSet(state.NewWords)
+	autoComplete.newPayloads = listSet(state.NewPayloads)
+	autoComplete.newWeights = listSet(state.NewWeights)
+	autoComplete.removedWords = listSet(state.RemovedWords)
+	autoComplete.entries = state.Entries
+	if autoComplete.entries == nil {
+		autoComplete.entries = make(map[string][]string)
 	}
+	autoComplete.ngrams = nGrams{counts: state.NGramCounts, totals: state.NGramTotals}
+	autoComplete.decay.accepts = state.Decay
+	autoComplete.entryAccepts.accepts = state.EntryAccepts
+	autoComplete.entryAccepts.decay.accepts = state.EntryDecay
+	autoComplete.changes.at = state.Changes
+	autoComplete.owned = nil
+	autoComplete.shared = nil
 	return nil
 }
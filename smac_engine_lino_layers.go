@@ -0,0 +1,130 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+// liNoLayer holds the nodes and prefixes a LiNo autocompleter set or removed before being forked, over the layers of
+// the autocompleters it was itself forked from. Layers are shared by forks and never modified: a removed node is nil in
+// its layer, and a removed prefix "".
+type liNoLayer struct {
+	nodes    map[string]*liNo
+	prefixes map[string]string
+	below    *liNoLayer
+}
+
+func (layer *liNoLayer) size() int {
+	return len(layer.nodes) + len(layer.prefixes)
+}
+
+// pushLayer returns the layers under a fork of autoComplete: its own maps over the layers it shares. A layer as large
+// as half the one below is merged with it, so that the layers get larger towards the bottom and there are only a few
+// of them to look through.
+func (autoComplete *AutoCompleteLiNo) pushLayer() *liNoLayer {
+
+	if len(autoComplete.wordMap) == 0 && len(autoComplete.prefixMap) == 0 {
+		return autoComplete.shared
+	}
+	layer := &liNoLayer{nodes: autoComplete.wordMap, prefixes: autoComplete.prefixMap, below: autoComplete.shared}
+	for layer.below != nil && 2*layer.size() >= layer.below.size() {
+		layer = mergeLayers(layer.below, layer)
+	}
+	return layer
+}
+
+// mergeLayers returns a new layer with the nodes and prefixes of upper over the ones of lower. The removed ones are
+// dropped if there is nothing below lower.
+func mergeLayers(lower, upper *liNoLayer) *liNoLayer {
+
+	merged := &liNoLayer{
+		nodes:    make(map[string]*liNo, len(lower.nodes)+len(upper.nodes)),
+		prefixes: make(map[string]string, len(lower.prefixes)+len(upper.prefixes)),
+		below:    lower.below,
+	}
+	for _, layer := range []*liNoLayer{lower, upper} {
+		for key, lino := range layer.nodes {
+			if lino == nil && merged.below == nil {
+				delete(merged.nodes, key)
+				continue
+			}
+			merged.nodes[key] = lino
+		}
+		for prefix, key := range layer.prefixes {
+			if key == "" && merged.below == nil {
+				delete(merged.prefixes, prefix)
+				continue
+			}
+			merged.prefixes[prefix] = key
+		}
+	}
+	return merged
+}
+
+// node returns the node of key, nil if key is not in the dictionary.
+func (autoComplete *AutoCompleteLiNo) node(key string) *liNo {
+
+	if lino, exists := autoComplete.wordMap[key]; exists || autoComplete.shared == nil {
+		return lino
+	}
+	for layer := autoComplete.shared; layer != nil; layer = layer.below {
+		if lino, exists := layer.nodes[key]; exists {
+			return lino
+		}
+	}
+	return nil
+}
+
+// removeNode removes the node of key.
+func (autoComplete *AutoCompleteLiNo) removeNode(key string) {
+
+	if autoComplete.shared == nil {
+		delete(autoComplete.wordMap, key)
+		return
+	}
+	autoComplete.wordMap[key] = nil
+}
+
+// prefix returns the first key starting with prefix, and whether there is one.
+func (autoComplete *AutoCompleteLiNo) prefix(prefix string) (string, bool) {
+
+	if key, exists := autoComplete.prefixMap[prefix]; exists || autoComplete.shared == nil {
+		return key, key != ""
+	}
+	for layer := autoComplete.shared; layer != nil; layer = layer.below {
+		if key, exists := layer.prefixes[prefix]; exists {
+			return key, key != ""
+		}
+	}
+	return "", false
+}
+
+// removePrefix removes prefix from the prefix map.
+func (autoComplete *AutoCompleteLiNo) removePrefix(prefix string) {
+
+	if autoComplete.shared == nil {
+		delete(autoComplete.prefixMap, prefix)
+		return
+	}
+	autoComplete.prefixMap[prefix] = ""
+}
+
+// prefixes returns the whole prefix map, merging the layers of a fork.
+func (autoComplete *AutoCompleteLiNo) prefixes() map[string]string {
+
+	if autoComplete.shared == nil {
+		return autoComplete.prefixMap
+	}
+	prefixes := make(map[string]string)
+	seen := make(map[string]bool)
+	layer := &liNoLayer{prefixes: autoComplete.prefixMap, below: autoComplete.shared}
+	for ; layer != nil; layer = layer.below {
+		for prefix, key := range layer.prefixes {
+			if !seen[prefix] {
+				seen[prefix] = true
+				if key != "" {
+					prefixes[prefix] = key
+				}
+			}
+		}
+	}
+	return prefixes
+}
@@ -0,0 +1,915 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// radixNode is a node of a compressed trie: label holds all the runes on the edge from its parent, which saves the
+// nodes a plain trie would spend on chains of single children. links are sorted by the first rune of their label.
+type radixNode struct {
+	label string
+	word  *radixWord
+	links []*radixNode
+}
+
+// radixWord is only allocated for nodes that end a word with accepts, a weight, forms other than its key or a payload.
+// All the other words, most of a dictionary, share plainWord, which is never modified: mutableWord allocates a word of
+// its own to the node of a word about to change.
+type radixWord struct {
+	accepts int
+	weight  float64
+	forms   surfaceForms
+	payload interface{}
+}
+
+var plainWord = &radixWord{}
+
+// AutoCompleteRadix is a compressed trie (radix tree) implementation of AutoComplete. It completes as fast as
+// AutoCompleteTrie while using less memory than AutoCompleteLiNo: on the 355k words of demo/allwords.txt, it holds
+// 26MB against the 39MB of AutoCompleteLiNo with prefixMapDepth 4 (see BenchmarkRadixMemoryAllWords).
+type AutoCompleteRadix struct {
+	root         *radixNode
+	resultSize   int
+	radius       int
+	newWords     map[string]bool
+	newPayloads  map[string]bool
+	newWeights   map[string]bool
+	removedWords map[string]bool
+	// entries holds the keys of the words learnt with keys of their own (see Learn).
+	entries map[string][]string
+	// ngrams is the n-gram model taught by AcceptSequence.
+	ngrams nGrams
+	// decay holds the decayed accept counts of the keys, if there is a half-life (see WithHalfLife).
+	decay decay
+	// entryAccepts holds the accept counts of the entries, apart from the ones of their keys.
+	entryAccepts entryAccepts
+	// changes holds when words were last learnt or unlearnt (see Merge).
+	changes    changes
+	ranker     Ranker
+	normalizer Normalizer
+	tokenizer  Tokenizer
+	// owned holds the nodes a fork may modify in place, nil if autoComplete is not a fork (see fork).
+	owned map[*radixNode]bool
+}
+
+// NewAutoCompleteRadixE returns a new, empty autocompleter.
+//
+// resultSize is the number of hits returned. If 0 is used, it defaults to DEF_RESULTS_SIZE
+//
+// radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
+//
+// The returned completer does not contain any words to be completed. New words can be added to it by using the Learn()
+// function
+func NewAutoCompleteRadixE(resultSize, radius uint, options ...Option) (AutoCompleteRadix, error) {
+	return NewAutoCompleteRadixS([]string{}, resultSize, radius, options...)
+}
+
+// NewAutoCompleteRadixF returns a new autocompleter.
+//
+// dictionaryFileName is the name of a dictionary file (a file containing words) to be used for completion.
+//
+// resultSize is the number of hits returned. If 0 is used, it defaults to DEF_RESULTS_SIZE
+//
+// radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
+//
+// New words can be added to it by using the Learn() function. With WithPayloadSeparator, every line holds a word and its
+// payload; with WithWeightSeparator, a word and its weight. WithCSV reads a CSV file instead. The file may be compressed
+// with gzip or bzip2.
+func NewAutoCompleteRadixF(dictionaryFileName string, resultSize, radius uint, options ...Option) (AutoCompleteRadix, error) {
+
+	var nAc AutoCompleteRadix
+
+	f, err := os.Open(dictionaryFileName)
+	if err != nil {
+		return nAc, err
+	}
+	defer f.Close()
+
+	return NewAutoCompleteRadixR(f, resultSize, radius, options...)
+}
+
+// NewAutoCompleteRadixR returns a new autocompleter, as NewAutoCompleteRadixF does, from a dictionary read from reader,
+// which may be compressed with gzip or bzip2.
+//
+// Lines starting with the prefix of WithCommentPrefix are skipped, and so are blank lines with WithSkipBlankLines.
+// Errors on bad input report the number of the line they were found at.
+func NewAutoCompleteRadixR(reader io.Reader, resultSize, radius uint, options ...Option) (AutoCompleteRadix, error) {
+	return buildAutoCompleteRadix(readerRecords(reader, newConfig(options)), resultSize, radius, options)
+}
+
+// NewAutoCompleteRadixS returns a new autocompleter.
+//
+// dictionary is a slice of words to be used for completion.
+//
+// resultSize is the number of hits returned. If 0 is used, it defaults to DEF_RESULTS_SIZE
+//
+// radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
+//
+// New words can be added to it by using the Learn() function
+func NewAutoCompleteRadixS(dictionary []string, resultSize, radius uint, options ...Option) (AutoCompleteRadix, error) {
+	return NewAutoCompleteRadixP(wordRecords(dictionary), resultSize, radius, options...)
+}
+
+// NewAutoCompleteRadixP returns a new autocompleter, as NewAutoCompleteRadixS does, from a dictionary of records. The
+// payload of each word is returned along with it by CompleteScored, and words with keys are entries matched by their
+// keys (see Learn).
+func NewAutoCompleteRadixP(dictionary []Record, resultSize, radius uint, options ...Option) (AutoCompleteRadix, error) {
+	return buildAutoCompleteRadix(recordSlice(dictionary), resultSize, radius, options)
+}
+
+func buildAutoCompleteRadix(dictionary records, resultSize, radius uint, options []Option) (AutoCompleteRadix, error) {
+
+	var nAc AutoCompleteRadix
+
+	if resultSize == 0 {
+		resultSize = DefaultResultSize
+	}
+	if radius == 0 {
+		radius = DefaultRadius
+	}
+
+	cfg := newConfig(options)
+	autoComplete := AutoCompleteRadix{
+		root:         &radixNode{},
+		resultSize:   int(resultSize),
+		radius:       int(radius),
+		newWords:     make(map[string]bool),
+		newPayloads:  make(map[string]bool),
+		newWeights:   make(map[string]bool),
+		removedWords: make(map[string]bool),
+		entries:      make(map[string][]string),
+		normalizer:   cfg.normalizer,
+		tokenizer:    cfg.tokenizer,
+		ranker:       cfg.ranker,
+		decay:        newDecay(cfg),
+		entryAccepts: newEntryAccepts(cfg),
+		changes:      newChanges(cfg),
+	}
+
+	err := dictionary(func(record Record) error {
+		keys := []string{autoComplete.normalizer.Normalize(record.Word)}
+		entry := record.Keys
+		if len(entry) == 0 {
+			entry = tokenKeys(autoComplete.tokenizer, record.Word)
+		}
+		if len(entry) > 0 {
+			var err error
+			if keys, err = entryKeys(autoComplete.normalizer, record.Word, entry); err != nil {
+				return err
+			}
+			autoComplete.entries[record.Word] = keys
+		}
+		for _, key := range keys {
+			if key == "" {
+				return errors.New("Empty word in dictionary")
+			}
+			autoComplete.putForm(key, record.Word)
+			if record.Payload != nil {
+				autoComplete.mutableWord(key).payload = record.Payload
+			}
+			if record.Weight > autoComplete.find(key).word.weight {
+				autoComplete.mutableWord(key).weight = record.Weight
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nAc, err
+	}
+
+	return autoComplete, nil
+}
+
+// child returns the link of node whose label starts with r, or the position it would be inserted at.
+func (node *radixNode) child(r rune) (int, *radixNode) {
+
+	for i, link := range node.links {
+		first, _ := utf8.DecodeRuneInString(link.label)
+		if first == r {
+			return i, link
+		}
+		if first > r {
+			return i, nil
+		}
+	}
+	return len(node.links), nil
+}
+
+func (node *radixNode) removeLink(link *radixNode) {
+
+	for i, l := range node.links {
+		if l == link {
+			links := make([]*radixNode, len(node.links)-1)
+			copy(links, node.links[:i])
+			copy(links[i:], node.links[i+1:])
+			node.links = links
+			return
+		}
+	}
+}
+
+// commonPrefixLength returns the length in bytes of the longest common prefix of a and b made of whole runes.
+func commonPrefixLength(a, b string) int {
+
+	i := 0
+	for i < len(a) && i < len(b) {
+		ra, size := utf8.DecodeRuneInString(a[i:])
+		rb, _ := utf8.DecodeRuneInString(b[i:])
+		if ra != rb {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// insert returns the node for key, adding it to the tree and splitting the edge it falls on if needed.
+func (autoComplete *AutoCompleteRadix) insert(key string) *radixNode {
+
+	node := autoComplete.mutable(nil, autoComplete.root)
+	rest := key
+
+	for rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		i, link := node.child(r)
+		if link == nil {
+			leaf := autoComplete.own(&radixNode{
+				label: rest,
+			})
+			links := make([]*radixNode, len(node.links)+1)
+			copy(links, node.links[:i])
+			links[i] = leaf
+			copy(links[i+1:], node.links[i:])
+			node.links = links
+			return leaf
+		}
+		link = autoComplete.mutable(node, link)
+		common := commonPrefixLength(link.label, rest)
+		if common < len(link.label) {
+			split := autoComplete.own(&radixNode{
+				label: link.label[:common],
+				links: []*radixNode{link},
+			})
+			link.label = link.label[common:]
+			node.links[i] = split
+			link = split
+		}
+		node = link
+		rest = rest[common:]
+	}
+	return node
+}
+
+// find returns the node for key, or nil if key is not in the tree.
+func (autoComplete *AutoCompleteRadix) find(key string) *radixNode {
+
+	node := autoComplete.root
+	rest := key
+
+	for rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		_, link := node.child(r)
+		if link == nil || !strings.HasPrefix(rest, link.label) {
+			return nil
+		}
+		node = link
+		rest = rest[len(link.label):]
+	}
+	return node
+}
+
+// locate returns the topmost node whose path starts with stem, along with its path.
+func (autoComplete *AutoCompleteRadix) locate(stem string) (*radixNode, string) {
+
+	node := autoComplete.root
+	rest := stem
+
+	for rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		_, link := node.child(r)
+		if link == nil {
+			return nil, ""
+		}
+		if strings.HasPrefix(link.label, rest) {
+			return link, stem + link.label[len(rest):]
+		}
+		if !strings.HasPrefix(rest, link.label) {
+			return nil, ""
+		}
+		node = link
+		rest = rest[len(link.label):]
+	}
+	return node, stem
+}
+
+// own marks node, just created, as modifiable in place by a fork.
+func (autoComplete *AutoCompleteRadix) own(node *radixNode) *radixNode {
+	if autoComplete.owned != nil {
+		autoComplete.owned[node] = true
+	}
+	return node
+}
+
+// mutable returns node, copying it first if it is shared with the autocompleter autoComplete was forked from. The
+// copy replaces node among the links of parent, which must be mutable already; a nil parent stands for the root.
+func (autoComplete *AutoCompleteRadix) mutable(parent, node *radixNode) *radixNode {
+
+	if autoComplete.owned == nil || autoComplete.owned[node] {
+		return node
+	}
+	clone := *node
+	clone.links = append([]*radixNode(nil), node.links...)
+	if node.word != nil && node.word != plainWord {
+		word := *node.word
+		clone.word = &word
+	}
+	autoComplete.owned[&clone] = true
+	if parent == nil {
+		autoComplete.root = &clone
+		return &clone
+	}
+	for i, link := range parent.links {
+		if link == node {
+			parent.links[i] = &clone
+		}
+	}
+	return &clone
+}
+
+// mutablePath returns the nodes on the path to key, root first, making each of them mutable. key must be in the tree.
+func (autoComplete *AutoCompleteRadix) mutablePath(key string) []*radixNode {
+
+	node := autoComplete.mutable(nil, autoComplete.root)
+	path := []*radixNode{node}
+	rest := key
+
+	for rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		_, link := node.child(r)
+		node = autoComplete.mutable(node, link)
+		path = append(path, node)
+		rest = rest[len(link.label):]
+	}
+	return path
+}
+
+// mutableNode returns the mutable node for key, which must be in the tree.
+func (autoComplete *AutoCompleteRadix) mutableNode(key string) *radixNode {
+	path := autoComplete.mutablePath(key)
+	return path[len(path)-1]
+}
+
+// mutableWord returns the mutable word of key, which must be a word of the tree, giving its node a word of its own if it
+// shares plainWord.
+func (autoComplete *AutoCompleteRadix) mutableWord(key string) *radixWord {
+
+	node := autoComplete.mutableNode(key)
+	if node.word == plainWord {
+		node.word = &radixWord{}
+	}
+	return node.word
+}
+
+// fork returns a copy of autoComplete to apply changes to, leaving autoComplete untouched. Nodes are shared until they
+// are modified, when they are copied along with their path from the root.
+func (autoComplete *AutoCompleteRadix) fork() forker {
+
+	forked := *autoComplete
+	forked.newWords = copyWordSet(autoComplete.newWords)
+	forked.newPayloads = copyWordSet(autoComplete.newPayloads)
+	forked.newWeights = copyWordSet(autoComplete.newWeights)
+	forked.removedWords = copyWordSet(autoComplete.removedWords)
+	forked.entries = copyEntries(autoComplete.entries)
+	forked.owned = make(map[*radixNode]bool)
+	forked.ngrams = autoComplete.ngrams.copy()
+	forked.decay = autoComplete.decay.copy()
+	forked.entryAccepts = autoComplete.entryAccepts.copy()
+	forked.changes = autoComplete.changes.copy()
+	return &forked
+}
+
+// now : see description in clocked interface
+func (autoComplete *AutoCompleteRadix) now() time.Time {
+	return autoComplete.changes.clock()
+}
+
+// at : see description in clocked interface
+func (autoComplete *AutoCompleteRadix) at(at time.Time, change func() error) error {
+	return atTime(at, change, &autoComplete.decay.clock, &autoComplete.entryAccepts.decay.clock, &autoComplete.changes.clock)
+}
+
+// remove removes key from the tree, merging the edges that are left with a single child.
+func (autoComplete *AutoCompleteRadix) remove(key string) {
+
+	if autoComplete.find(key) == nil {
+		return
+	}
+	parents := autoComplete.mutablePath(key)
+	node := parents[len(parents)-1]
+	parents = parents[:len(parents)-1]
+	node.word = nil
+
+	if len(node.links) == 0 && len(parents) > 0 {
+		parent := parents[len(parents)-1]
+		parent.removeLink(node)
+		node = parent
+	}
+	if node != autoComplete.root && node.word == nil && len(node.links) == 1 {
+		child := autoComplete.mutable(node, node.links[0])
+		node.label += child.label
+		node.word = child.word
+		node.links = child.links
+	}
+}
+
+func (autoComplete *AutoCompleteRadix) putForm(key, form string) {
+
+	node := autoComplete.insert(key)
+	switch {
+	case node.word != nil:
+		if !node.word.forms.contains(key, form) {
+			autoComplete.mutableWord(key).forms.add(key, form)
+		}
+	case key == form:
+		node.word = plainWord
+	default:
+		node.word = &radixWord{forms: surfaceForms{form}}
+	}
+}
+
+// Accept : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) Accept(acceptedWord string) error {
+
+	if _, isEntry := autoComplete.entries[acceptedWord]; isEntry {
+		autoComplete.entryAccepts.accept(acceptedWord)
+		return nil
+	}
+	key := autoComplete.normalizer.Normalize(acceptedWord)
+	if node := autoComplete.find(key); node == nil || node.word == nil {
+		return errors.New("Word to be accepted not found")
+	}
+	autoComplete.mutableWord(key).accepts++
+	autoComplete.decay.accept(key)
+	return nil
+}
+
+// Learn : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) Learn(word string, keys ...string) error {
+
+	if len(keys) == 0 {
+		keys = tokenKeys(autoComplete.tokenizer, word)
+	}
+	if _, isWord := autoComplete.keysOf(word); isWord {
+		return errors.New("Word already in dictionary")
+	}
+	wordKeys, err := entryKeys(autoComplete.normalizer, word, keys)
+	if err != nil {
+		return err
+	}
+	for _, key := range wordKeys {
+		if node := autoComplete.find(key); node != nil && node.word != nil && node.word.forms.contains(key, word) {
+			return errors.New("Word already in dictionary")
+		}
+	}
+
+	for _, key := range wordKeys {
+		autoComplete.putForm(key, word)
+	}
+	if len(keys) > 0 {
+		autoComplete.entries[word] = wordKeys
+	}
+	if _, contains := autoComplete.removedWords[word]; contains {
+		delete(autoComplete.removedWords, word)
+	} else {
+		autoComplete.newWords[word] = true
+	}
+	autoComplete.changes.record(word)
+	return nil
+}
+
+// LearnWithPayload : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) LearnWithPayload(word string, payload interface{}, keys ...string) error {
+
+	wordKeys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		if err := autoComplete.Learn(word, keys...); err != nil {
+			return err
+		}
+		wordKeys, _ = autoComplete.keysOf(word)
+	}
+	for _, key := range wordKeys {
+		autoComplete.mutableWord(key).payload = payload
+		autoComplete.newPayloads[key] = true
+	}
+	return nil
+}
+
+// SetWeight : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) SetWeight(word string, weight float64) error {
+
+	keys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		return errors.New("Word not in dictionary")
+	}
+	for _, key := range keys {
+		autoComplete.mutableWord(key).weight = weight
+		autoComplete.newWeights[key] = true
+	}
+	return nil
+}
+
+// keysOf returns the keys word is matched by, and whether word is in the dictionary.
+func (autoComplete *AutoCompleteRadix) keysOf(word string) ([]string, bool) {
+
+	if keys, isEntry := autoComplete.entries[word]; isEntry {
+		return keys, true
+	}
+	key := autoComplete.normalizer.Normalize(word)
+	node := autoComplete.find(key)
+	return []string{key}, node != nil && node.word != nil && node.word.forms.contains(key, word)
+}
+
+// UnLearn : see description in AutoComplete interface. If word is one of several forms sharing a key, only that form is
+// removed; otherwise the key is removed along with all of its forms, but for the entries it is a key of.
+func (autoComplete *AutoCompleteRadix) UnLearn(word string) error {
+
+	if keys, isEntry := autoComplete.entries[word]; isEntry {
+		for _, key := range keys {
+			autoComplete.removeForms(key, []string{word})
+		}
+		delete(autoComplete.entries, word)
+		autoComplete.entryAccepts.forget(word)
+		autoComplete.unlearnt(word)
+		return nil
+	}
+
+	key := autoComplete.normalizer.Normalize(word)
+	node := autoComplete.find(key)
+	if node == nil || node.word == nil {
+		return errors.New("Word not in dictionary")
+	}
+	forms := []string{word}
+	if !node.word.forms.contains(key, word) {
+		forms = nil
+		for _, form := range node.word.forms.list(key) {
+			if _, isEntry := autoComplete.entries[form]; !isEntry {
+				forms = append(forms, form)
+			}
+		}
+		if len(forms) == 0 {
+			return errors.New("Word not in dictionary")
+		}
+	}
+	autoComplete.removeForms(key, forms)
+	for _, form := range forms {
+		autoComplete.unlearnt(form)
+	}
+	return nil
+}
+
+// removeForms removes forms from the forms of key, and key itself if no form is left.
+func (autoComplete *AutoCompleteRadix) removeForms(key string, forms []string) {
+
+	remaining := autoComplete.find(key).word.forms
+	for _, form := range forms {
+		remaining.remove(key, form)
+	}
+	if len(remaining.list(key)) == 0 {
+		autoComplete.remove(key)
+		delete(autoComplete.newPayloads, key)
+		delete(autoComplete.newWeights, key)
+		autoComplete.decay.forget(key)
+		return
+	}
+	autoComplete.mutableWord(key).forms = remaining
+}
+
+func (autoComplete *AutoCompleteRadix) unlearnt(word string) {
+	if _, contains := autoComplete.newWords[word]; !contains {
+		autoComplete.removedWords[word] = true
+	} else {
+		delete(autoComplete.newWords, word)
+	}
+	autoComplete.changes.record(word)
+}
+
+// Complete : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) Complete(stem string) ([]string, error) {
+	return autoComplete.CompleteWith(stem, CompleteOptions{})
+}
+
+// CompleteWith : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) CompleteWith(stem string, opts CompleteOptions) ([]string, error) {
+
+	result, opts, err := autoComplete.completeList(stem, opts)
+	if err != nil {
+		return nil, err
+	}
+	return opts.page(result), nil
+}
+
+// CompleteScored : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) CompleteScored(stem string, opts CompleteOptions) ([]Result, error) {
+
+	result, opts, err := autoComplete.completeList(stem, opts)
+	if err != nil {
+		return nil, err
+	}
+	return opts.pageResults(result), nil
+}
+
+// CompleteQuery : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) CompleteQuery(query string) ([]string, error) {
+	return completeQuery(autoComplete, autoComplete.tokenizer, autoComplete.normalizer, autoComplete.resultSize, query)
+}
+
+// AcceptSequence : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) AcceptSequence(words []string) error {
+	return autoComplete.ngrams.accept(autoComplete.normalizer, words)
+}
+
+// Predict : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) Predict(previousWords []string, stem string) ([]string, error) {
+	return autoComplete.ngrams.predict(autoComplete, autoComplete.normalizer, autoComplete.resultSize, previousWords, stem)
+}
+
+func (autoComplete *AutoCompleteRadix) completeList(stem string, opts CompleteOptions) (sOLILI, CompleteOptions, error) {
+
+	opts, err := opts.resolve(autoComplete.resultSize, autoComplete.radius)
+	if err != nil {
+		return sOLILI{}, opts, err
+	}
+	walk := func(automaton *levenshteinAutomaton, matches *[]fuzzyMatch) {
+		autoComplete.fuzzyWalk(autoComplete.root, automaton, automaton.start(), nil, opts.MaxEdits+1, matches)
+	}
+	complete := func(prefix string) sOLILI {
+		return autoComplete.complete(prefix, opts)
+	}
+	return completeFuzzy(autoComplete.normalizer.Normalize(stem), opts, walk, complete), opts, nil
+}
+
+// radixBranch is a node waiting to be visited during completion. Branches are visited in order of length first and
+// alphabetical second, as edges of different lengths rule out a plain breadth-first visit.
+type radixBranch struct {
+	node   *radixNode
+	path   string
+	length int
+}
+
+type radixQueue []radixBranch
+
+func (queue radixQueue) Len() int { return len(queue) }
+func (queue radixQueue) Less(i, j int) bool {
+	if queue[i].length != queue[j].length {
+		return queue[i].length < queue[j].length
+	}
+	return queue[i].path < queue[j].path
+}
+func (queue radixQueue) Swap(i, j int) { queue[i], queue[j] = queue[j], queue[i] }
+
+func (queue *radixQueue) Push(branch interface{}) {
+	*queue = append(*queue, branch.(radixBranch))
+}
+
+func (queue *radixQueue) Pop() interface{} {
+	old := *queue
+	branch := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return branch
+}
+
+// complete collects the words whose key starts with stem, which must be normalized.
+func (autoComplete *AutoCompleteRadix) complete(stem string, opts CompleteOptions) sOLILI {
+
+	words := newEntryHits(opts.Offset + opts.Limit)
+	matchLength := utf8.RuneCountInString(stem)
+	for _, entry := range autoComplete.completeEntries(stem, opts) {
+		candidate := autoComplete.decay.candidate(entry.key, entry.word.accepts)
+		candidate.Weight = entry.word.weight
+		for _, form := range entry.word.forms.list(entry.key) {
+			words.insert(opts.hit(autoComplete.ranker, form, autoComplete.entryAccepts.candidate(form, candidate, autoComplete.entries), entry.word.payload, autoComplete.newWords[form], matchLength), entry.key, stem, autoComplete.normalizer, autoComplete.entries)
+		}
+	}
+	return words.list()
+}
+
+type radixEntry struct {
+	key    string
+	length int
+	word   *radixWord
+}
+
+// completeEntries returns, in order of length first and alphabetical second, the words whose key starts with stem and
+// whose length is within the bounds of opts, up to the scan limit of opts.
+func (autoComplete *AutoCompleteRadix) completeEntries(stem string, opts CompleteOptions) []radixEntry {
+
+	var entries []radixEntry
+	node, path := autoComplete.locate(stem)
+	if node == nil {
+		return entries
+	}
+
+	queue := &radixQueue{radixBranch{
+		node:   node,
+		path:   path,
+		length: utf8.RuneCountInString(path),
+	}}
+
+	maxLength := opts.maxLength()
+	for queue.Len() > 0 && len(entries) < opts.scanLimit() {
+		branch := heap.Pop(queue).(radixBranch)
+		if branch.node.word != nil && opts.admits(branch.length) {
+			entries = append(entries, radixEntry{
+				key:    branch.path,
+				length: branch.length,
+				word:   branch.node.word,
+			})
+		}
+		for _, link := range branch.node.links {
+			length := branch.length + utf8.RuneCountInString(link.label)
+			if length <= maxLength {
+				heap.Push(queue, radixBranch{
+					node:   link,
+					path:   branch.path + link.label,
+					length: length,
+				})
+			}
+		}
+	}
+	return entries
+}
+
+// CompleteFuzzy : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	return autoComplete.CompleteWith(stem, CompleteOptions{MaxEdits: maxEdits})
+}
+
+// fuzzyWalk runs automaton over the tree, feeding it the runes of each edge one at a time, since a matching prefix can
+// end in the middle of an edge.
+func (autoComplete *AutoCompleteRadix) fuzzyWalk(node *radixNode, automaton *levenshteinAutomaton, state levenshteinState, prefix []rune, best int, matches *[]fuzzyMatch) {
+
+	for _, link := range node.links {
+		childState, childBest, childPrefix := state, best, prefix
+		descend := true
+		for _, r := range link.label {
+			if len(childPrefix) >= autoComplete.radius {
+				descend = false
+				break
+			}
+			childPrefix = append(childPrefix[:len(childPrefix):len(childPrefix)], r)
+			childState, childBest, descend = automaton.visit(childState, r, childPrefix, childBest, matches)
+			if !descend {
+				break
+			}
+		}
+		if descend {
+			autoComplete.fuzzyWalk(link, automaton, childState, childPrefix, childBest, matches)
+		}
+	}
+}
+
+// walk visits, depth first and in alphabetical order, all the word nodes under node.
+func (autoComplete *AutoCompleteRadix) walk(node *radixNode, path string, visit func(key string, word *radixWord)) {
+
+	if node.word != nil {
+		visit(path, node.word)
+	}
+	for _, link := range node.links {
+		autoComplete.walk(link, path+link.label, visit)
+	}
+}
+
+// Save : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) Save(fileName string) error {
+	return SaveFile(fileName, autoComplete.SaveTo)
+}
+
+// SaveTo : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) SaveTo(writer io.Writer) error {
+	return writeSave(writer, autoComplete.saveHeader(), autoComplete.saveBody)
+}
+
+// saveHeader describes autoComplete in the header of its save files.
+func (autoComplete *AutoCompleteRadix) saveHeader() saveHeader {
+	return saveHeader{Engine: "radix", Normalizer: normalizerName(autoComplete.normalizer), ResultSize: autoComplete.resultSize, Radius: autoComplete.radius}
+}
+
+// saveBody writes the body of a save file of autoComplete.
+func (autoComplete *AutoCompleteRadix) saveBody(writer io.Writer) error {
+
+	enc := &wordEncoder{enc: gob.NewEncoder(writer)}
+	var written entrySet
+
+	autoComplete.walk(autoComplete.root, "", func(key string, word *radixWord) {
+		var payload interface{}
+		if autoComplete.newPayloads[key] {
+			payload = word.payload
+		}
+		var weight *float64
+		if autoComplete.newWeights[key] {
+			weight = &word.weight
+		}
+		for _, w := range word.forms.list(key) {
+			accepts, decayed := autoComplete.entryAccepts.saved(w, word.accepts, autoComplete.decay.get(key), autoComplete.entries)
+			if accepts > 0 || autoComplete.newWords[w] || autoComplete.newPayloads[key] || weight != nil {
+				if written.first(w, autoComplete.entries) {
+					enc.encode(wordAccepts{
+						Word:    w,
+						Accepts: accepts,
+						New:     autoComplete.newWords[w],
+						Decay:   decayed,
+						Payload: payload,
+						Weight:  weight,
+						Keys:    autoComplete.entries[w],
+						Changed: autoComplete.changes.get(w),
+					})
+				}
+			}
+		}
+	})
+
+	for w := range autoComplete.removedWords {
+		enc.encode(wordAccepts{Word: w, Accepts: -1, Changed: autoComplete.changes.get(w)})
+	}
+	autoComplete.changes.save(enc, func(word string) bool {
+		_, isWord := autoComplete.keysOf(word)
+		return isWord
+	})
+	autoComplete.ngrams.save(enc)
+	return enc.err
+}
+
+// Retrieve : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) Retrieve(fileName string) error {
+	return retrieveFile(fileName, autoComplete.RetrieveFrom)
+}
+
+// RetrieveFrom : see description in AutoComplete interface
+func (autoComplete *AutoCompleteRadix) RetrieveFrom(reader io.Reader) error {
+	return readSave(reader, autoComplete.saveHeader(), autoComplete.retrieveBody)
+}
+
+// retrieveBody reads the body of a save file.
+func (autoComplete *AutoCompleteRadix) retrieveBody(reader io.Reader) error {
+
+	var err error
+	dec := gob.NewDecoder(reader)
+	for {
+		var wA wordAccepts
+		if err = dec.Decode(&wA); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if len(wA.Context) > 0 {
+			autoComplete.ngrams.retrieve(wA)
+			continue
+		}
+		keys, isWord := autoComplete.keysOf(wA.Word)
+		if !isWord {
+			err = autoComplete.Learn(wA.Word, wA.Keys...)
+			if err != nil {
+				return err
+			}
+			keys, _ = autoComplete.keysOf(wA.Word)
+		}
+		_, isEntry := autoComplete.entries[wA.Word]
+		if isEntry {
+			autoComplete.entryAccepts.retrieve(wA.Word, wA)
+		}
+		for _, key := range keys {
+			if wA.Payload != nil {
+				autoComplete.mutableWord(key).payload = wA.Payload
+				autoComplete.newPayloads[key] = true
+			}
+			if wA.Weight != nil {
+				autoComplete.mutableWord(key).weight = *wA.Weight
+				autoComplete.newWeights[key] = true
+			}
+			if isEntry {
+				continue
+			}
+			if wA.Accepts > 0 || wA.SetAccepts {
+				autoComplete.mutableWord(key).accepts = wA.Accepts
+			}
+			if wA.Decay != nil {
+				autoComplete.decay.set(key, *wA.Decay)
+			}
+		}
+		if wA.Accepts < 0 {
+			autoComplete.UnLearn(wA.Word)
+		}
+		autoComplete.changes.set(wA.Word, wA.Changed)
+	}
+	return nil
+}
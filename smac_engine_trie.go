@@ -4,30 +4,49 @@
 package smac
 
 import (
-	"bufio"
 	"encoding/gob"
 	"errors"
 	"io"
 	"os"
+	"time"
 )
 
 type trieNode struct {
 	isWord  bool
+	dense   bool
 	intRune int
 	accepts int
+	weight  float64
 	links   []*trieNode
+	forms   surfaceForms
+	payload interface{}
 }
 
 // AutoCompleteTrie represents the autocomplete engine.
 type AutoCompleteTrie struct {
 	root         *trieNode
-	alphabetMin  int
-	alphabetMax  int
-	alphabetSize int
+	alphabet     trieAlphabet
 	resultSize   int
 	radius       int
 	newWords     map[string]byte
+	newPayloads  map[string]byte
+	newWeights   map[string]byte
 	removedWords map[string]byte
+	// entries holds the keys of the words learnt with keys of their own (see Learn).
+	entries map[string][]string
+	// ngrams is the n-gram model taught by AcceptSequence.
+	ngrams nGrams
+	// decay holds the decayed accept counts of the keys, if there is a half-life (see WithHalfLife).
+	decay decay
+	// entryAccepts holds the accept counts of the entries, apart from the ones of their keys.
+	entryAccepts entryAccepts
+	// changes holds when words were last learnt or unlearnt (see Merge).
+	changes    changes
+	ranker     Ranker
+	normalizer Normalizer
+	tokenizer  Tokenizer
+	// owned holds the nodes a fork may modify in place, nil if autoComplete is not a fork (see fork).
+	owned map[*trieNode]bool
 }
 
 // NewAutoCompleteTrieE returns a new, empty autocompleter for a given alphabet (set of runes).
@@ -38,13 +57,12 @@ type AutoCompleteTrie struct {
 //
 // The returned completer does not contain any words to be completed. New words can be added to it by using the Learn()
 // function
-func NewAutoCompleteTrieE(alphabet string, resultSize, radius uint) (AutoCompleteTrie, error) {
+func NewAutoCompleteTrieE(alphabet string, resultSize, radius uint, options ...Option) (AutoCompleteTrie, error) {
 
 	var nAc AutoCompleteTrie
 	if len(alphabet) == 0 {
 		return nAc, errors.New("Empty alphabet")
 	}
-	min, max := minMax([]rune(alphabet))
 
 	if resultSize == 0 {
 		resultSize = DefaultResultSize
@@ -53,19 +71,25 @@ func NewAutoCompleteTrieE(alphabet string, resultSize, radius uint) (AutoComplet
 		radius = DefaultRadius
 	}
 
+	cfg := newConfig(options)
 	autoComplete := AutoCompleteTrie{
-		alphabetMin:  int(min),
-		alphabetMax:  int(max),
-		alphabetSize: int(max) - int(min) + 1,
+		alphabet:     newTrieAlphabet(alphabet),
 		resultSize:   int(resultSize),
 		radius:       int(radius),
 		newWords:     make(map[string]byte),
+		newPayloads:  make(map[string]byte),
+		newWeights:   make(map[string]byte),
 		removedWords: make(map[string]byte),
+		entries:      make(map[string][]string),
+		normalizer:   cfg.normalizer,
+		tokenizer:    cfg.tokenizer,
+		ranker:       cfg.ranker,
+		decay:        newDecay(cfg),
+		entryAccepts: newEntryAccepts(cfg),
+		changes:      newChanges(cfg),
 	}
 
-	autoComplete.root = &trieNode{
-		links: make([]*trieNode, autoComplete.alphabetSize),
-	}
+	autoComplete.root = &trieNode{}
 	return autoComplete, nil
 }
 
@@ -77,15 +101,23 @@ func NewAutoCompleteTrieE(alphabet string, resultSize, radius uint) (AutoComplet
 //
 // radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
 //
+// alphabet must contain the runes of the words as normalized by the normalizer of the autocompleter (see WithNormalizer).
+//
 // New words can be added to it by using the Learn() function
-func NewAutoCompleteTrieS(alphabet string, dictionary []string, resultSize, radius uint) (AutoCompleteTrie, error) {
+func NewAutoCompleteTrieS(alphabet string, dictionary []string, resultSize, radius uint, options ...Option) (AutoCompleteTrie, error) {
+	return NewAutoCompleteTrieP(alphabet, wordRecords(dictionary), resultSize, radius, options...)
+}
+
+// NewAutoCompleteTrieP returns a new autocompleter, as NewAutoCompleteTrieS does, from a dictionary of records. The
+// payload of each word is returned along with it by CompleteScored, and words with keys are entries matched by their
+// keys (see Learn).
+func NewAutoCompleteTrieP(alphabet string, dictionary []Record, resultSize, radius uint, options ...Option) (AutoCompleteTrie, error) {
 
 	var nAc AutoCompleteTrie
 
 	if len(alphabet) == 0 {
 		return nAc, errors.New("Empty alphabet")
 	}
-	min, max := minMax([]rune(alphabet))
 
 	if resultSize == 0 {
 		resultSize = DefaultResultSize
@@ -93,22 +125,28 @@ func NewAutoCompleteTrieS(alphabet string, dictionary []string, resultSize, radi
 	if radius == 0 {
 		radius = DefaultRadius
 	}
+	cfg := newConfig(options)
 	autoComplete := AutoCompleteTrie{
-		alphabetMin:  int(min),
-		alphabetMax:  int(max),
-		alphabetSize: int(max) - int(min) + 1,
+		alphabet:     newTrieAlphabet(alphabet),
 		resultSize:   int(resultSize),
 		radius:       int(radius),
 		newWords:     make(map[string]byte),
+		newPayloads:  make(map[string]byte),
+		newWeights:   make(map[string]byte),
 		removedWords: make(map[string]byte),
+		entries:      make(map[string][]string),
+		normalizer:   cfg.normalizer,
+		tokenizer:    cfg.tokenizer,
+		ranker:       cfg.ranker,
+		decay:        newDecay(cfg),
+		entryAccepts: newEntryAccepts(cfg),
+		changes:      newChanges(cfg),
 	}
 
-	autoComplete.root = &trieNode{
-		links: make([]*trieNode, autoComplete.alphabetSize),
-	}
+	autoComplete.root = &trieNode{}
 
-	for _, w := range dictionary {
-		err := autoComplete.put(w)
+	for _, record := range dictionary {
+		err := autoComplete.putRecord(record)
 		if err != nil {
 			return nAc, err
 		}
@@ -125,51 +163,37 @@ func NewAutoCompleteTrieS(alphabet string, dictionary []string, resultSize, radi
 //
 // radius is the max length of words the engine will search while autocompleting. If 0 is used, it defaults to DEF_RADIUS
 //
-// New words can be added to it by using the Learn() function
-func NewAutoCompleteTrieF(alphabet, dictionaryFileName string, resultSize, radius uint) (AutoCompleteTrie, error) {
+// New words can be added to it by using the Learn() function. With WithPayloadSeparator, every line holds a word and its
+// payload; with WithWeightSeparator, a word and its weight. WithCSV reads a CSV file instead. The file may be compressed
+// with gzip or bzip2.
+func NewAutoCompleteTrieF(alphabet, dictionaryFileName string, resultSize, radius uint, options ...Option) (AutoCompleteTrie, error) {
 
 	var nAc AutoCompleteTrie
-	if len(alphabet) == 0 {
-		return nAc, errors.New("Empty alphabet")
-	}
-	min, max := minMax([]rune(alphabet))
-
-	if resultSize == 0 {
-		resultSize = DefaultResultSize
-	}
-	if radius == 0 {
-		radius = DefaultRadius
-	}
-
-	autoComplete := AutoCompleteTrie{
-		alphabetMin:  int(min),
-		alphabetMax:  int(max),
-		alphabetSize: int(max) - int(min) + 1,
-		resultSize:   int(resultSize),
-		radius:       int(radius),
-		newWords:     make(map[string]byte),
-		removedWords: make(map[string]byte),
-	}
 
 	f, err := os.Open(dictionaryFileName)
-	defer f.Close()
-
 	if err != nil {
 		return nAc, err
 	}
+	defer f.Close()
 
-	lineScanner := bufio.NewScanner(f)
+	return NewAutoCompleteTrieR(alphabet, f, resultSize, radius, options...)
+}
 
-	autoComplete.root = &trieNode{
-		links: make([]*trieNode, autoComplete.alphabetSize),
-	}
+// NewAutoCompleteTrieR returns a new autocompleter, as NewAutoCompleteTrieF does, from a dictionary read from reader,
+// which may be compressed with gzip or bzip2. Words are streamed from reader into the trie.
+//
+// Lines starting with the prefix of WithCommentPrefix are skipped, and so are blank lines with WithSkipBlankLines.
+// Errors on bad input report the number of the line they were found at.
+func NewAutoCompleteTrieR(alphabet string, reader io.Reader, resultSize, radius uint, options ...Option) (AutoCompleteTrie, error) {
 
-	for lineScanner.Scan() {
-		line := lineScanner.Text()
-		err := autoComplete.put(line)
-		if err != nil {
-			return nAc, err
-		}
+	var nAc AutoCompleteTrie
+
+	autoComplete, err := NewAutoCompleteTrieE(alphabet, resultSize, radius, options...)
+	if err != nil {
+		return nAc, err
+	}
+	if err = readRecords(reader, newConfig(options), autoComplete.putRecord); err != nil {
+		return nAc, err
 	}
 
 	return autoComplete, nil
@@ -177,18 +201,21 @@ func NewAutoCompleteTrieF(alphabet, dictionaryFileName string, resultSize, radiu
 
 // Accept : See description in AutoComplete interface
 func (autoComplete *AutoCompleteTrie) Accept(acceptedWord string) error {
-	acceptedWordInts, err := autoComplete.runesToInts(acceptedWord)
+
+	if _, isEntry := autoComplete.entries[acceptedWord]; isEntry {
+		autoComplete.entryAccepts.accept(acceptedWord)
+		return nil
+	}
+	key := autoComplete.normalizer.Normalize(acceptedWord)
+	acceptedWordInts, err := autoComplete.runesToInts(key)
 	if err != nil {
 		return err
 	}
-	node := autoComplete.root
-	for _, c := range acceptedWordInts {
-		if node.links[c-autoComplete.alphabetMin] == nil {
-			return errors.New("Word " + acceptedWord + " not in dictionary")
-		}
-		node = node.links[c-autoComplete.alphabetMin]
+	if autoComplete.find(acceptedWordInts) == nil {
+		return errors.New("Word " + acceptedWord + " not in dictionary")
 	}
-	node.accepts++
+	autoComplete.mutablePath(acceptedWordInts)[len(acceptedWordInts)].accepts++
+	autoComplete.decay.accept(key)
 	return nil
 }
 
@@ -197,92 +224,271 @@ func (autoComplete *AutoCompleteTrie) runesToInts(word string) ([]int, error) {
 	var conv []int
 
 	for _, r := range runes {
-		i := int(r)
-		if i < autoComplete.alphabetMin || i > autoComplete.alphabetMax {
+		if _, exists := autoComplete.alphabet[r]; !exists {
 			return nil, errors.New("illegal char in word - " + string(r))
 		}
-		conv = append(conv, i)
+		conv = append(conv, int(r))
 	}
 
 	return conv, nil
 }
 
 // Learn : see interface
-func (autoComplete *AutoCompleteTrie) Learn(word string) error {
-	conv, err := autoComplete.runesToInts(word)
+func (autoComplete *AutoCompleteTrie) Learn(word string, keys ...string) error {
+
+	if len(keys) == 0 {
+		keys = tokenKeys(autoComplete.tokenizer, word)
+	}
+	if _, isWord := autoComplete.keysOf(word); isWord {
+		return errors.New("Word already in dictionary")
+	}
+	wordKeys, err := entryKeys(autoComplete.normalizer, word, keys)
 	if err != nil {
 		return err
 	}
-	autoComplete.putIter(conv)
-	autoComplete.newWords[word] = 0
+	convs := make([][]int, len(wordKeys))
+	for i, key := range wordKeys {
+		if convs[i], err = autoComplete.runesToInts(key); err != nil {
+			return err
+		}
+		if node := autoComplete.find(convs[i]); node != nil && node.isWord && node.forms.contains(key, word) {
+			return errors.New("Word already in dictionary")
+		}
+	}
+
+	for i, key := range wordKeys {
+		autoComplete.putForm(convs[i], key, word)
+	}
+	if len(keys) > 0 {
+		autoComplete.entries[word] = wordKeys
+	}
+	if _, contains := autoComplete.removedWords[word]; contains {
+		delete(autoComplete.removedWords, word)
+	} else {
+		autoComplete.newWords[word] = 0
+	}
+	autoComplete.changes.record(word)
 	return nil
 }
 
-func (autoComplete *AutoCompleteTrie) put(word string) error {
+// LearnWithPayload : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) LearnWithPayload(word string, payload interface{}, keys ...string) error {
+
+	wordKeys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		if err := autoComplete.Learn(word, keys...); err != nil {
+			return err
+		}
+		wordKeys, _ = autoComplete.keysOf(word)
+	}
+	for _, key := range wordKeys {
+		conv, _ := autoComplete.runesToInts(key)
+		autoComplete.mutablePath(conv)[len(conv)].payload = payload
+		autoComplete.newPayloads[key] = 0
+	}
+	return nil
+}
 
-	conv, err := autoComplete.runesToInts(word)
+// SetWeight : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) SetWeight(word string, weight float64) error {
+
+	keys, isWord := autoComplete.keysOf(word)
+	if !isWord {
+		return errors.New("Word not in dictionary")
+	}
+	for _, key := range keys {
+		conv, _ := autoComplete.runesToInts(key)
+		autoComplete.mutablePath(conv)[len(conv)].weight = weight
+		autoComplete.newWeights[key] = 0
+	}
+	return nil
+}
+
+// keysOf returns the keys word is matched by, and whether word is in the dictionary.
+func (autoComplete *AutoCompleteTrie) keysOf(word string) ([]string, bool) {
+
+	if keys, isEntry := autoComplete.entries[word]; isEntry {
+		return keys, true
+	}
+	key := autoComplete.normalizer.Normalize(word)
+	conv, err := autoComplete.runesToInts(key)
 	if err != nil {
-		return err
+		return []string{key}, false
+	}
+	node := autoComplete.find(conv)
+	return []string{key}, node != nil && node.isWord && node.forms.contains(key, word)
+}
+
+// putRecord adds record, a word of the bootstrap dictionary, to the trie.
+func (autoComplete *AutoCompleteTrie) putRecord(record Record) error {
+
+	keys := []string{autoComplete.normalizer.Normalize(record.Word)}
+	entry := record.Keys
+	if len(entry) == 0 {
+		entry = tokenKeys(autoComplete.tokenizer, record.Word)
+	}
+	if len(entry) > 0 {
+		var err error
+		if keys, err = entryKeys(autoComplete.normalizer, record.Word, entry); err != nil {
+			return err
+		}
+		autoComplete.entries[record.Word] = keys
+	}
+	for _, key := range keys {
+		conv, err := autoComplete.runesToInts(key)
+		if err != nil {
+			return err
+		}
+		autoComplete.putForm(conv, key, record.Word)
+		node := autoComplete.find(conv)
+		if record.Payload != nil {
+			node.payload = record.Payload
+		}
+		if record.Weight > node.weight {
+			node.weight = record.Weight
+		}
 	}
-	autoComplete.putIter(conv)
 	return nil
 }
 
-func (autoComplete *AutoCompleteTrie) putIter(intVals []int) {
+// putForm adds form to the forms of key, adding key to the trie if needed.
+func (autoComplete *AutoCompleteTrie) putForm(intVals []int, key, form string) {
 
-	node := autoComplete.root
+	if node := autoComplete.find(intVals); node != nil && node.isWord {
+		autoComplete.mutablePath(intVals)[len(intVals)].forms.add(key, form)
+		return
+	}
+	node := autoComplete.putIter(intVals)
+	if key != form {
+		node.forms = surfaceForms{form}
+	}
+}
+
+func (autoComplete *AutoCompleteTrie) putIter(intVals []int) *trieNode {
+
+	node := autoComplete.mutable(nil, autoComplete.root)
 
 	for i, c := range intVals {
-		if node.links[c-autoComplete.alphabetMin] == nil {
+		link := autoComplete.child(node, c)
+		if link == nil {
 			newNode := trieNode{
 				intRune: c,
-				links:   make([]*trieNode, autoComplete.alphabetSize),
 			}
 			if i == len(intVals)-1 {
 				newNode.isWord = true
 			}
-			node.links[c-autoComplete.alphabetMin] = &newNode
+			if autoComplete.owned != nil {
+				autoComplete.owned[&newNode] = true
+			}
+			autoComplete.addChild(node, &newNode)
 			node = &newNode
 			continue
 		}
-		node = node.links[c-autoComplete.alphabetMin]
+		node = autoComplete.mutable(node, link)
 		if i == len(intVals)-1 {
 			node.isWord = true
 		}
 	}
+	return node
+}
 
+// find returns the node at the end of the path spelled by intVals, or nil if there is no such path.
+func (autoComplete *AutoCompleteTrie) find(intVals []int) *trieNode {
+
+	node := autoComplete.root
+	for _, c := range intVals {
+		node = autoComplete.child(node, c)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
 }
 
-// UnLearn :  : See description in AutoComplete interface
+// UnLearn :  : See description in AutoComplete interface. If word is one of several forms sharing a key, only that
+// form is removed; otherwise the key is removed along with all of its forms, but for the entries it is a key of.
 func (autoComplete *AutoCompleteTrie) UnLearn(word string) error {
-	conv, err := autoComplete.runesToInts(word)
+
+	if keys, isEntry := autoComplete.entries[word]; isEntry {
+		for _, key := range keys {
+			conv, _ := autoComplete.runesToInts(key)
+			autoComplete.removeForms(conv, key, []string{word})
+		}
+		delete(autoComplete.entries, word)
+		autoComplete.entryAccepts.forget(word)
+		autoComplete.unlearnt(word)
+		return nil
+	}
+
+	key := autoComplete.normalizer.Normalize(word)
+	conv, err := autoComplete.runesToInts(key)
 	if err != nil {
 		return err
 	}
-	autoComplete.remove(conv)
+	node := autoComplete.find(conv)
+	if node == nil || !node.isWord {
+		return errors.New("Word not in dictionary")
+	}
+	forms := []string{word}
+	if !node.forms.contains(key, word) {
+		forms = nil
+		for _, form := range node.forms.list(key) {
+			if _, isEntry := autoComplete.entries[form]; !isEntry {
+				forms = append(forms, form)
+			}
+		}
+		if len(forms) == 0 {
+			return errors.New("Word not in dictionary")
+		}
+	}
+	autoComplete.removeForms(conv, key, forms)
+	for _, form := range forms {
+		autoComplete.unlearnt(form)
+	}
+
+	return nil
+}
+
+// removeForms removes forms from the forms of key, and key itself if no form is left.
+func (autoComplete *AutoCompleteTrie) removeForms(intVals []int, key string, forms []string) {
+
+	node := autoComplete.mutablePath(intVals)[len(intVals)]
+	remaining := node.forms
+	for _, form := range forms {
+		remaining.remove(key, form)
+	}
+	if len(remaining.list(key)) > 0 {
+		node.forms = remaining
+		return
+	}
+	node.forms = nil
+	node.payload = nil
+	node.weight = 0
+	autoComplete.remove(intVals)
+	delete(autoComplete.newPayloads, key)
+	delete(autoComplete.newWeights, key)
+	autoComplete.decay.forget(key)
+}
+
+func (autoComplete *AutoCompleteTrie) unlearnt(word string) {
 	if _, contains := autoComplete.newWords[word]; !contains {
 		autoComplete.removedWords[word] = 0
 	} else {
 		delete(autoComplete.newWords, word)
 	}
-
-	return nil
+	autoComplete.changes.record(word)
 }
 
 func (autoComplete *AutoCompleteTrie) remove(intVals []int) {
-	node := autoComplete.root
-	lifo := lIFO{}
-
-	for _, c := range intVals {
-		if node.links[c-autoComplete.alphabetMin] == nil {
-			return
-		}
-		lifo.push(node)
-		node = node.links[c-autoComplete.alphabetMin]
-	}
-	if !node.isWord {
+	if node := autoComplete.find(intVals); node == nil || !node.isWord {
 		return
 	}
+	path := autoComplete.mutablePath(intVals)
+	node := path[len(intVals)]
+	lifo := lIFO{}
+	for _, parent := range path[:len(intVals)] {
+		lifo.push(parent)
+	}
 	isLeaf := true
 	for _, link := range node.links {
 		if link != nil {
@@ -299,7 +505,7 @@ func (autoComplete *AutoCompleteTrie) remove(intVals []int) {
 
 	for lifo.size() > 0 {
 		parentNode := lifo.pop()
-		parentNode.links[node.intRune-autoComplete.alphabetMin] = nil
+		autoComplete.removeChild(parentNode, node.intRune)
 
 		if parentNode.isWord {
 			return
@@ -315,46 +521,120 @@ func (autoComplete *AutoCompleteTrie) remove(intVals []int) {
 
 // Complete : see description in Autocomplete interface
 func (autoComplete *AutoCompleteTrie) Complete(word string) ([]string, error) {
+	return autoComplete.CompleteWith(word, CompleteOptions{})
+}
 
-	ints, err := autoComplete.runesToInts(word)
+// CompleteWith : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) CompleteWith(word string, opts CompleteOptions) ([]string, error) {
+
+	result, opts, err := autoComplete.completeList(word, opts)
 	if err != nil {
 		return nil, err
 	}
-	return autoComplete.complete(word, ints), nil
+	return opts.page(result), nil
 }
 
-func (autoComplete *AutoCompleteTrie) complete(word string, intRunes []int) []string {
+// CompleteScored : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) CompleteScored(word string, opts CompleteOptions) ([]Result, error) {
+
+	result, opts, err := autoComplete.completeList(word, opts)
+	if err != nil {
+		return nil, err
+	}
+	return opts.pageResults(result), nil
+}
+
+// CompleteQuery : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) CompleteQuery(query string) ([]string, error) {
+	return completeQuery(autoComplete, autoComplete.tokenizer, autoComplete.normalizer, autoComplete.resultSize, query)
+}
+
+// AcceptSequence : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) AcceptSequence(words []string) error {
+	return autoComplete.ngrams.accept(autoComplete.normalizer, words)
+}
+
+// Predict : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) Predict(previousWords []string, stem string) ([]string, error) {
+	return autoComplete.ngrams.predict(autoComplete, autoComplete.normalizer, autoComplete.resultSize, previousWords, stem)
+}
+
+func (autoComplete *AutoCompleteTrie) completeList(word string, opts CompleteOptions) (sOLILI, CompleteOptions, error) {
+
+	opts, err := opts.resolve(autoComplete.resultSize, autoComplete.radius)
+	if err != nil {
+		return sOLILI{}, opts, err
+	}
+	word = autoComplete.normalizer.Normalize(word)
+	if opts.MaxEdits == 0 {
+		ints, err := autoComplete.runesToInts(word)
+		if err != nil {
+			return sOLILI{}, opts, err
+		}
+		return autoComplete.complete(word, ints, opts), opts, nil
+	}
+	walk := func(automaton *levenshteinAutomaton, matches *[]fuzzyMatch) {
+		autoComplete.fuzzyWalk(autoComplete.root, automaton, automaton.start(), nil, opts.MaxEdits+1, matches)
+	}
+	complete := func(prefix string) sOLILI {
+		ints, _ := autoComplete.runesToInts(prefix)
+		return autoComplete.complete(prefix, ints, opts)
+	}
+	return completeFuzzy(word, opts, walk, complete), opts, nil
+}
+
+func (autoComplete *AutoCompleteTrie) complete(word string, intRunes []int, opts CompleteOptions) sOLILI {
 
 	wordEnd := autoComplete.root
 	for _, c := range intRunes {
-		wordEnd = wordEnd.links[c-autoComplete.alphabetMin]
+		wordEnd = autoComplete.child(wordEnd, c)
 		if wordEnd == nil {
-			return []string{}
+			return sOLILI{}
 		}
 	}
 
-	words := sOLILI{}
+	words := newEntryHits(opts.Offset + opts.Limit)
 	fifo := fIFO{}
 	stem := []rune(word)
-	stem = stem[:len(stem)-1]
-	fifo.add(branch{
-		node:   wordEnd,
-		parent: &stem,
-	})
+	matchLength := len(stem)
+	if matchLength == 0 {
+		// the root holds no rune, so the visit starts from its children
+		for _, link := range wordEnd.links {
+			if link != nil {
+				fifo.add(branch{
+					node:   link,
+					parent: &stem,
+				})
+			}
+		}
+	} else {
+		stem = stem[:len(stem)-1]
+		fifo.add(branch{
+			node:   wordEnd,
+			parent: &stem,
+		})
+	}
 	results := 0
+	maxLength := opts.maxLength()
 	for fifo.size() > 0 {
-		if results == autoComplete.resultSize {
+		if results == opts.scanLimit() {
 			break
 		}
 
 		nodeBranch := fifo.remove()
-		if nodeBranch.node.isWord {
-			words.insert(string(append(*nodeBranch.parent, rune(nodeBranch.node.intRune))), nodeBranch.node.accepts)
+		if nodeBranch.node.isWord && opts.admits(len(*nodeBranch.parent)+1) {
+			key := string(append(*nodeBranch.parent, rune(nodeBranch.node.intRune)))
+			candidate := autoComplete.decay.candidate(key, nodeBranch.node.accepts)
+			candidate.Weight = nodeBranch.node.weight
+			for _, form := range nodeBranch.node.forms.list(key) {
+				_, learnt := autoComplete.newWords[form]
+				words.insert(opts.hit(autoComplete.ranker, form, autoComplete.entryAccepts.candidate(form, candidate, autoComplete.entries), nodeBranch.node.payload, learnt, matchLength), key, word, autoComplete.normalizer, autoComplete.entries)
+			}
 			results++
 		}
 		links := nodeBranch.node.links
 
-		if len(*nodeBranch.parent) < autoComplete.radius-1 {
+		if len(*nodeBranch.parent) < maxLength-1 {
 			parentString := make([]rune, len(*nodeBranch.parent)+1)
 			copy(parentString, *nodeBranch.parent)
 			parentString[len(parentString)-1] = rune(nodeBranch.node.intRune)
@@ -370,7 +650,30 @@ func (autoComplete *AutoCompleteTrie) complete(word string, intRunes []int) []st
 			}
 		}
 	}
-	return words.flush()
+	return words.list()
+}
+
+// CompleteFuzzy : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	return autoComplete.CompleteWith(stem, CompleteOptions{MaxEdits: maxEdits})
+}
+
+func (autoComplete *AutoCompleteTrie) fuzzyWalk(node *trieNode, automaton *levenshteinAutomaton, state levenshteinState, prefix []rune, best int, matches *[]fuzzyMatch) {
+
+	if len(prefix) >= autoComplete.radius {
+		return
+	}
+	for _, link := range node.links {
+		if link == nil {
+			continue
+		}
+		r := rune(link.intRune)
+		childPrefix := append(prefix[:len(prefix):len(prefix)], r)
+		childState, childBest, descend := automaton.visit(state, r, childPrefix, best, matches)
+		if descend {
+			autoComplete.fuzzyWalk(link, automaton, childState, childPrefix, childBest, matches)
+		}
+	}
 }
 
 type branch struct {
@@ -412,13 +715,24 @@ func (lifo *lIFO) size() int {
 
 // Save : see description in AutoComplete interface
 func (autoComplete *AutoCompleteTrie) Save(fileName string) error {
+	return SaveFile(fileName, autoComplete.SaveTo)
+}
 
-	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
-	if err != nil {
-		return err
-	}
+// SaveTo : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) SaveTo(writer io.Writer) error {
+	return writeSave(writer, autoComplete.saveHeader(), autoComplete.saveBody)
+}
+
+// saveHeader describes autoComplete in the header of its save files.
+func (autoComplete *AutoCompleteTrie) saveHeader() saveHeader {
+	return saveHeader{Engine: "trie", Alphabet: autoComplete.alphabet.String(), Normalizer: normalizerName(autoComplete.normalizer), ResultSize: autoComplete.resultSize, Radius: autoComplete.radius}
+}
+
+// saveBody writes the body of a save file of autoComplete.
+func (autoComplete *AutoCompleteTrie) saveBody(writer io.Writer) error {
 
-	enc := gob.NewEncoder(f)
+	enc := &wordEncoder{enc: gob.NewEncoder(writer)}
+	var written entrySet
 
 	fifo := fIFO{}
 	var nSlice []rune
@@ -432,18 +746,32 @@ func (autoComplete *AutoCompleteTrie) Save(fileName string) error {
 
 		nodeBranch := fifo.remove()
 		if nodeBranch.node.isWord {
-			currWord := string(append(*nodeBranch.parent, rune(nodeBranch.node.intRune)))
-			if nodeBranch.node.accepts > 0 {
-				enc.Encode(wordAccepts{
-					currWord,
-					nodeBranch.node.accepts,
-				})
-
-			} else if _, exists := autoComplete.newWords[currWord]; exists {
-				enc.Encode(wordAccepts{
-					currWord,
-					nodeBranch.node.accepts,
-				})
+			currKey := string(append(*nodeBranch.parent, rune(nodeBranch.node.intRune)))
+			var payload interface{}
+			_, newPayload := autoComplete.newPayloads[currKey]
+			if newPayload {
+				payload = nodeBranch.node.payload
+			}
+			var weight *float64
+			if _, newWeight := autoComplete.newWeights[currKey]; newWeight {
+				weight = &nodeBranch.node.weight
+			}
+			for _, currWord := range nodeBranch.node.forms.list(currKey) {
+				accepts, decayed := autoComplete.entryAccepts.saved(currWord, nodeBranch.node.accepts, autoComplete.decay.get(currKey), autoComplete.entries)
+				if _, isNew := autoComplete.newWords[currWord]; isNew || accepts > 0 || newPayload || weight != nil {
+					if written.first(currWord, autoComplete.entries) {
+						enc.encode(wordAccepts{
+							Word:    currWord,
+							Accepts: accepts,
+							New:     isNew,
+							Decay:   decayed,
+							Payload: payload,
+							Weight:  weight,
+							Keys:    autoComplete.entries[currWord],
+							Changed: autoComplete.changes.get(currWord),
+						})
+					}
+				}
 			}
 		}
 		links := nodeBranch.node.links
@@ -470,24 +798,31 @@ func (autoComplete *AutoCompleteTrie) Save(fileName string) error {
 		}
 	}
 	for w := range autoComplete.removedWords {
-		enc.Encode(wordAccepts{
-			w,
-			-1,
-		})
+		enc.encode(wordAccepts{Word: w, Accepts: -1, Changed: autoComplete.changes.get(w)})
 	}
-	return f.Close()
+	autoComplete.changes.save(enc, func(word string) bool {
+		_, isWord := autoComplete.keysOf(word)
+		return isWord
+	})
+	autoComplete.ngrams.save(enc)
+	return enc.err
 }
 
 // Retrieve : see description in AutoComplete interface
 func (autoComplete *AutoCompleteTrie) Retrieve(fileName string) error {
+	return retrieveFile(fileName, autoComplete.RetrieveFrom)
+}
 
-	f, err := os.Open(fileName)
-	defer f.Close()
-	if err != nil {
-		return err
-	}
+// RetrieveFrom : see description in AutoComplete interface
+func (autoComplete *AutoCompleteTrie) RetrieveFrom(reader io.Reader) error {
+	return readSave(reader, autoComplete.saveHeader(), autoComplete.retrieveBody)
+}
 
-	dec := gob.NewDecoder(f)
+// retrieveBody reads the body of a save file.
+func (autoComplete *AutoCompleteTrie) retrieveBody(reader io.Reader) error {
+
+	var err error
+	dec := gob.NewDecoder(reader)
 	for {
 		var wA wordAccepts
 		if err = dec.Decode(&wA); err == io.EOF {
@@ -495,27 +830,51 @@ func (autoComplete *AutoCompleteTrie) Retrieve(fileName string) error {
 		} else if err != nil {
 			return err
 		}
-		runesAsInts, err := autoComplete.runesToInts(wA.Word)
-		if err != nil {
-			return err
+		if len(wA.Context) > 0 {
+			autoComplete.ngrams.retrieve(wA)
+			continue
 		}
-		node := autoComplete.root
-		for _, c := range runesAsInts {
-			if node.links[c-autoComplete.alphabetMin] == nil {
-				err = autoComplete.Learn(wA.Word)
-				if err != nil {
-					return err
-				}
+		keys, isWord := autoComplete.keysOf(wA.Word)
+		if !isWord {
+			err = autoComplete.Learn(wA.Word, wA.Keys...)
+			if err != nil {
+				return err
 			}
-			node = node.links[c-autoComplete.alphabetMin]
+			keys, _ = autoComplete.keysOf(wA.Word)
 		}
-		if wA.Accepts > 0 {
-			if err = autoComplete.updateAccepts(runesAsInts, wA.Accepts); err != nil {
+		_, isEntry := autoComplete.entries[wA.Word]
+		if isEntry {
+			autoComplete.entryAccepts.retrieve(wA.Word, wA)
+		}
+		for _, key := range keys {
+			runesAsInts, err := autoComplete.runesToInts(key)
+			if err != nil {
 				return err
 			}
-		} else if wA.Accepts < 0 {
+			if wA.Payload != nil {
+				autoComplete.mutablePath(runesAsInts)[len(runesAsInts)].payload = wA.Payload
+				autoComplete.newPayloads[key] = 0
+			}
+			if wA.Weight != nil {
+				autoComplete.mutablePath(runesAsInts)[len(runesAsInts)].weight = *wA.Weight
+				autoComplete.newWeights[key] = 0
+			}
+			if isEntry {
+				continue
+			}
+			if wA.Accepts > 0 || wA.SetAccepts {
+				if err = autoComplete.updateAccepts(runesAsInts, wA.Accepts); err != nil {
+					return err
+				}
+			}
+			if wA.Decay != nil {
+				autoComplete.decay.set(key, *wA.Decay)
+			}
+		}
+		if wA.Accepts < 0 {
 			autoComplete.UnLearn(wA.Word)
 		}
+		autoComplete.changes.set(wA.Word, wA.Changed)
 	}
 
 	return nil
@@ -523,41 +882,210 @@ func (autoComplete *AutoCompleteTrie) Retrieve(fileName string) error {
 
 func (autoComplete *AutoCompleteTrie) updateAccepts(word []int, accepts int) error {
 
-	node := autoComplete.root
-
-	for _, c := range word {
-		if node.links[c-autoComplete.alphabetMin] == nil {
-			return errors.New("Word not found")
-		}
-		node = node.links[c-autoComplete.alphabetMin]
+	if autoComplete.find(word) == nil {
+		return errors.New("Word not found")
 	}
-	node.accepts = accepts
+	autoComplete.mutablePath(word)[len(word)].accepts = accepts
 	return nil
 }
 
-func minMax(runes []rune) (rune, rune) {
+// mutable returns node, copying it first if it is shared with the autocompleter autoComplete was forked from. The copy
+// replaces node among the links of parent, which must be mutable already; a nil parent stands for the root.
+func (autoComplete *AutoCompleteTrie) mutable(parent, node *trieNode) *trieNode {
+
+	if autoComplete.owned == nil || autoComplete.owned[node] {
+		return node
+	}
+	clone := *node
+	clone.links = append([]*trieNode(nil), node.links...)
+	autoComplete.owned[&clone] = true
+	if parent == nil {
+		autoComplete.root = &clone
+	} else {
+		autoComplete.replaceChild(parent, &clone)
+	}
+	return &clone
+}
+
+// mutablePath returns the nodes on the path spelled by intVals, root first, making each of them mutable. The path must
+// exist.
+func (autoComplete *AutoCompleteTrie) mutablePath(intVals []int) []*trieNode {
 
-	if len(runes) == 1 {
-		return runes[0], runes[0]
+	path := make([]*trieNode, 0, len(intVals)+1)
+	node := autoComplete.mutable(nil, autoComplete.root)
+	path = append(path, node)
+	for _, c := range intVals {
+		node = autoComplete.mutable(node, autoComplete.child(node, c))
+		path = append(path, node)
 	}
+	return path
+}
 
-	var min, max rune
+// fork returns a copy of autoComplete to apply changes to, leaving autoComplete untouched. Nodes are shared until they
+// are modified, when they are copied along with their path from the root.
+func (autoComplete *AutoCompleteTrie) fork() forker {
 
-	if runes[0] > runes[1] {
-		max = runes[0]
-		min = runes[1]
-	} else {
-		max = runes[1]
-		min = runes[0]
+	forked := *autoComplete
+	forked.newWords = make(map[string]byte, len(autoComplete.newWords))
+	for word := range autoComplete.newWords {
+		forked.newWords[word] = 0
+	}
+	forked.newPayloads = make(map[string]byte, len(autoComplete.newPayloads))
+	for key := range autoComplete.newPayloads {
+		forked.newPayloads[key] = 0
+	}
+	forked.newWeights = make(map[string]byte, len(autoComplete.newWeights))
+	for key := range autoComplete.newWeights {
+		forked.newWeights[key] = 0
+	}
+	forked.entries = copyEntries(autoComplete.entries)
+	forked.removedWords = make(map[string]byte, len(autoComplete.removedWords))
+	for word := range autoComplete.removedWords {
+		forked.removedWords[word] = 0
+	}
+	forked.owned = make(map[*trieNode]bool)
+	forked.ngrams = autoComplete.ngrams.copy()
+	forked.decay = autoComplete.decay.copy()
+	forked.entryAccepts = autoComplete.entryAccepts.copy()
+	forked.changes = autoComplete.changes.copy()
+	return &forked
+}
+
+// now : see description in clocked interface
+func (autoComplete *AutoCompleteTrie) now() time.Time {
+	return autoComplete.changes.clock()
+}
+
+// at : see description in clocked interface
+func (autoComplete *AutoCompleteTrie) at(at time.Time, change func() error) error {
+	return atTime(at, change, &autoComplete.decay.clock, &autoComplete.entryAccepts.decay.clock, &autoComplete.changes.clock)
+}
+
+// WriteIndex writes the whole of autoComplete, the words of its dictionary along with what it has learnt, to the index
+// fileName, which LoadIndex loads without inserting words one by one again. The file is replaced atomically.
+func (autoComplete *AutoCompleteTrie) WriteIndex(fileName string) error {
+	return SaveFile(fileName, autoComplete.writeIndex)
+}
+
+// The words of a trie index are its alphabet and its nodes, depth first: the rune of each node but the root, its flags
+// and fields (see indexWriter.word), and the number of its children, which follow in rune order.
+func (autoComplete *AutoCompleteTrie) writeIndex(writer io.Writer) error {
+
+	iw := newIndexWriter(writer, "trie", autoComplete.normalizer)
+	iw.string(autoComplete.alphabet.String())
+
+	payloads := make(map[string]interface{})
+	var write func(node *trieNode, key []rune)
+	write = func(node *trieNode, key []rune) {
+		var flags byte
+		if node.isWord {
+			flags = indexWord
+		}
+		iw.word(flags, node.accepts, node.weight, node.forms)
+		if node.payload != nil {
+			payloads[string(key)] = node.payload
+		}
+		var children []*trieNode
+		for _, link := range node.links {
+			if link != nil {
+				children = append(children, link)
+			}
+		}
+		iw.uvarint(uint64(len(children)))
+		for _, child := range children {
+			iw.uvarint(uint64(child.intRune))
+			write(child, append(key, rune(child.intRune)))
+		}
+	}
+	write(autoComplete.root, nil)
+
+	return iw.close(indexState{
+		Payloads:     payloads,
+		NewWords:     byteSetList(autoComplete.newWords),
+		NewPayloads:  byteSetList(autoComplete.newPayloads),
+		NewWeights:   byteSetList(autoComplete.newWeights),
+		RemovedWords: byteSetList(autoComplete.removedWords),
+		Entries:      autoComplete.entries,
+		NGramCounts:  autoComplete.ngrams.counts,
+		NGramTotals:  autoComplete.ngrams.totals,
+		Decay:        autoComplete.decay.accepts,
+		EntryAccepts: autoComplete.entryAccepts.accepts,
+		EntryDecay:   autoComplete.entryAccepts.decay.accepts,
+		Changes:      autoComplete.changes.at,
+	})
+}
+
+// LoadIndex replaces the words of autoComplete, and what it has learnt, with the ones of the index fileName, written by
+// WriteIndex. autoComplete must be created by NewAutoCompleteTrieE with the normalizer the index was written with, or
+// LoadIndex fails; the alphabet of the index replaces its own.
+func (autoComplete *AutoCompleteTrie) LoadIndex(fileName string) error {
+
+	ir, err := openIndex(fileName, "trie", autoComplete.normalizer)
+	if err != nil {
+		return err
 	}
+	alphabet := ir.string()
+	if ir.err == nil && len(alphabet) == 0 {
+		return errors.New("Corrupted index file: empty alphabet")
+	}
+	loaded := AutoCompleteTrie{alphabet: newTrieAlphabet(alphabet)}
+
+	var read func(node *trieNode)
+	read = func(node *trieNode) {
+		var flags byte
+		flags, node.accepts, node.weight, node.forms = ir.word()
+		node.isWord = flags&indexWord != 0
+		count := ir.uvarint()
+		if count == 0 || ir.err != nil {
+			return
+		}
+		if count > uint64(len(loaded.alphabet)) {
+			ir.err = errors.New("child count out of range")
+			return
+		}
+		children := make([]*trieNode, count)
+		for i := range children {
+			children[i] = &trieNode{intRune: int(ir.uvarint())}
+			if _, exists := loaded.alphabet[rune(children[i].intRune)]; !exists {
+				ir.err = errors.New("rune not in alphabet")
+				return
+			}
+			read(children[i])
+		}
+		loaded.setLinks(node, children)
+	}
+	loaded.root = &trieNode{}
+	read(loaded.root)
 
-	for i := 2; i < len(runes); i++ {
-		if runes[i] > max {
-			max = runes[i]
-		} else if runes[i] < min {
-			min = runes[i]
+	state, err := ir.close()
+	if err != nil {
+		return err
+	}
+	for key, payload := range state.Payloads {
+		conv, err := loaded.runesToInts(key)
+		if err != nil {
+			return err
+		}
+		if node := loaded.find(conv); node != nil {
+			node.payload = payload
 		}
 	}
 
-	return min, max
+	autoComplete.root = loaded.root
+	autoComplete.alphabet = loaded.alphabet
+	autoComplete.newWords = listByteSet(state.NewWords)
+	autoComplete.newPayloads = listByteSet(state.NewPayloads)
+	autoComplete.newWeights = listByteSet(state.NewWeights)
+	autoComplete.removedWords = listByteSet(state.RemovedWords)
+	autoComplete.entries = state.Entries
+	if autoComplete.entries == nil {
+		autoComplete.entries = make(map[string][]string)
+	}
+	autoComplete.ngrams = nGrams{counts: state.NGramCounts, totals: state.NGramTotals}
+	autoComplete.decay.accepts = state.Decay
+	autoComplete.entryAccepts.accepts = state.EntryAccepts
+	autoComplete.entryAccepts.decay.accepts = state.EntryDecay
+	autoComplete.changes.at = state.Changes
+	autoComplete.owned = nil
+	return nil
 }
@@ -0,0 +1,134 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"sort"
+)
+
+// sparseLinksMax is the number of children past which a trie node switches from a sorted slice of links to a table
+// indexed by alphabet position. Most nodes have very few children, and a table sized on the alphabet for each of them
+// would make large or mixed-script alphabets unusable.
+const sparseLinksMax = 16
+
+// trieAlphabet maps the runes of an alphabet to their position in it, in rune order.
+type trieAlphabet map[rune]int
+
+type runeSlice []rune
+
+func (runes runeSlice) Len() int           { return len(runes) }
+func (runes runeSlice) Less(i, j int) bool { return runes[i] < runes[j] }
+func (runes runeSlice) Swap(i, j int)      { runes[i], runes[j] = runes[j], runes[i] }
+
+func newTrieAlphabet(alphabet string) trieAlphabet {
+
+	runes := []rune(alphabet)
+	sort.Sort(runeSlice(runes))
+
+	positions := make(trieAlphabet)
+	for _, r := range runes {
+		if _, exists := positions[r]; !exists {
+			positions[r] = len(positions)
+		}
+	}
+	return positions
+}
+
+// String returns the runes of alphabet in rune order.
+func (alphabet trieAlphabet) String() string {
+
+	runes := make([]rune, len(alphabet))
+	for r, position := range alphabet {
+		runes[position] = r
+	}
+	return string(runes)
+}
+
+// child returns the child of node for rune c, or nil. Children are only ever linked for runes of the alphabet, which
+// runesToInts and LoadIndex check, so that this is the only lookup that needs to.
+func (autoComplete *AutoCompleteTrie) child(node *trieNode, c int) *trieNode {
+
+	if node.dense {
+		position, exists := autoComplete.alphabet[rune(c)]
+		if !exists {
+			return nil
+		}
+		return node.links[position]
+	}
+	for _, link := range node.links {
+		if link.intRune == c {
+			return link
+		}
+		if link.intRune > c {
+			return nil
+		}
+	}
+	return nil
+}
+
+// addChild links child to node, keeping links in rune order.
+func (autoComplete *AutoCompleteTrie) addChild(node, child *trieNode) {
+
+	if node.dense {
+		node.links[autoComplete.alphabet[rune(child.intRune)]] = child
+		return
+	}
+	i := 0
+	for i < len(node.links) && node.links[i].intRune < child.intRune {
+		i++
+	}
+	links := make([]*trieNode, len(node.links)+1)
+	copy(links, node.links[:i])
+	links[i] = child
+	copy(links[i+1:], node.links[i:])
+	autoComplete.setLinks(node, links)
+}
+
+// setLinks links children, in rune order, to node, which has no children yet or is sparse.
+func (autoComplete *AutoCompleteTrie) setLinks(node *trieNode, children []*trieNode) {
+
+	if len(children) > sparseLinksMax && len(autoComplete.alphabet) > sparseLinksMax {
+		dense := make([]*trieNode, len(autoComplete.alphabet))
+		for _, link := range children {
+			dense[autoComplete.alphabet[rune(link.intRune)]] = link
+		}
+		node.links = dense
+		node.dense = true
+		return
+	}
+	node.links = children
+}
+
+// replaceChild replaces the child of node for the rune of child with child.
+func (autoComplete *AutoCompleteTrie) replaceChild(node, child *trieNode) {
+
+	if node.dense {
+		node.links[autoComplete.alphabet[rune(child.intRune)]] = child
+		return
+	}
+	for i, link := range node.links {
+		if link.intRune == child.intRune {
+			node.links[i] = child
+			return
+		}
+	}
+}
+
+// removeChild unlinks the child of node for rune c.
+func (autoComplete *AutoCompleteTrie) removeChild(node *trieNode, c int) {
+
+	if node.dense {
+		node.links[autoComplete.alphabet[rune(c)]] = nil
+		return
+	}
+	for i, link := range node.links {
+		if link.intRune == c {
+			links := make([]*trieNode, len(node.links)-1)
+			copy(links, node.links[:i])
+			copy(links[i:], node.links[i+1:])
+			node.links = links
+			return
+		}
+	}
+}
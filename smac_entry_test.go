@@ -0,0 +1,190 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+const strangelove = "Dr. Strangelove (1964)"
+
+func entryEngines(dictionary []Record) map[string]AutoComplete {
+
+	lino, _ := NewAutoCompleteLinoP(dictionary, 2, 0, 0)
+	trie, _ := NewAutoCompleteTrieP("abcdefghijklmnopqrstuvwxyz ", dictionary, 0, 0)
+	radix, _ := NewAutoCompleteRadixP(dictionary, 0, 0)
+	automaton, _ := NewAutoCompleteFSTP(dictionary, 0, 0)
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}
+}
+
+// count returns how many times word is among the completions of stem, and its accepts.
+func count(autoComplete AutoComplete, stem, word string) (int, int) {
+
+	n, accepts := 0, 0
+	results, _ := autoComplete.CompleteScored(stem, CompleteOptions{})
+	for _, result := range results {
+		if result.Word == word {
+			n++
+			accepts = result.Accepts
+		}
+	}
+	return n, accepts
+}
+
+func TestEntries(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dictionary := []Record{{Word: "stranger"}, {Word: "drama"}}
+
+	t.Log("Given the need to match entries by several keys")
+	{
+		retrieved := entryEngines(dictionary)
+		for name, autoComplete := range entryEngines(dictionary) {
+			if err = autoComplete.Learn(strangelove, "strangelove", "dr strangelove"); err != nil {
+				t.Fatal(err)
+			}
+			autoComplete.Learn("Strange Days (1995)", "strange days", "strange")
+			if n, _ := count(autoComplete, "str", strangelove); n != 1 {
+				t.Fatal("Should be able to complete an entry by its first key on a "+name+" autocompleter", ballotX)
+			}
+			if n, _ := count(autoComplete, "dr", strangelove); n != 1 {
+				t.Fatal("Should be able to complete an entry by its second key on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete an entry by each of its keys on a "+name+" autocompleter", checkMark)
+
+			if n, _ := count(autoComplete, "strange", "Strange Days (1995)"); n != 1 {
+				t.Fatal("Should be able to complete an entry once when several of its keys match on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete an entry once when several of its keys match on a "+name+" autocompleter", checkMark)
+
+			if autoComplete.Learn(strangelove, "strangelove") == nil {
+				t.Fatal("Should not be able to learn an entry twice on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should not be able to learn an entry twice on a "+name+" autocompleter", checkMark)
+
+			autoComplete.Accept(strangelove)
+			_, strAccepts := count(autoComplete, "str", strangelove)
+			_, drAccepts := count(autoComplete, "dr", strangelove)
+			if strAccepts != 1 || drAccepts != 1 {
+				t.Fatal("Should be able to accept an entry by all of its keys on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to accept an entry by all of its keys on a "+name+" autocompleter", checkMark)
+
+			saveFile := dir + "/" + name
+			if err = autoComplete.Save(saveFile); err != nil {
+				t.Fatal(err)
+			}
+			if err = retrieved[name].Retrieve(saveFile); err != nil {
+				t.Fatal(err)
+			}
+			n, accepts := count(retrieved[name], "dr", strangelove)
+			if m, _ := count(retrieved[name], "str", strangelove); n != 1 || m != 1 || accepts != 1 {
+				t.Fatal("Should be able to save and retrieve entries on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to save and retrieve entries on a "+name+" autocompleter", checkMark)
+
+			if err = autoComplete.UnLearn(strangelove); err != nil {
+				t.Fatal(err)
+			}
+			n, _ = count(autoComplete, "str", strangelove)
+			if m, _ := count(autoComplete, "dr", strangelove); n != 0 || m != 0 {
+				t.Fatal("Should be able to unlearn an entry from all of its keys on a "+name+" autocompleter", ballotX)
+			}
+			if n, _ = count(autoComplete, "str", "stranger"); n != 1 {
+				t.Fatal("Should be able to unlearn an entry leaving other words alone on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to unlearn an entry from all of its keys on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to load entries with a dictionary")
+	{
+		records := append([]Record{{Word: strangelove, Payload: "1964", Keys: []string{"strangelove", "dr strangelove"}}}, dictionary...)
+		for name, autoComplete := range entryEngines(records) {
+			n, _ := count(autoComplete, "dr", strangelove)
+			if p := payloads(autoComplete, "dr", "str"); n != 1 || len(p) != 3 || p[strangelove] != "1964" {
+				t.Log(p)
+				t.Fatal("Should be able to load entries with a dictionary on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to load entries with a dictionary on a "+name+" autocompleter", checkMark)
+
+			autoComplete.UnLearn(strangelove)
+			if n, _ := count(autoComplete, "str", strangelove); n != 0 {
+				t.Fatal("Should be able to unlearn a loaded entry on a "+name+" autocompleter", ballotX)
+			}
+			if err = autoComplete.Learn(strangelove, "strangelove", "dr strangelove"); err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := count(autoComplete, "dr", strangelove); n != 1 {
+				t.Fatal("Should be able to learn back a loaded entry on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to unlearn and learn back a loaded entry on a "+name+" autocompleter", checkMark)
+		}
+
+		automaton, _ := NewAutoCompleteFSTP(records, 0, 0)
+		fstFile := dir + "/entries.fst"
+		if err = automaton.WriteFST(fstFile); err != nil {
+			t.Fatal(err)
+		}
+		mapped, err := NewAutoCompleteFSTM(fstFile, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer mapped.Close()
+		if n, _ := count(&mapped, "str", strangelove); n != 1 || mapped.UnLearn(strangelove) != nil || len(mapped.fstForms("strangelove")) != 0 {
+			t.Fatal("Should be able to write entries with the automaton of a FST autocompleter", ballotX)
+		}
+		t.Log("Should be able to write entries with the automaton of a FST autocompleter", checkMark)
+	}
+
+	t.Log("Given the need to keep the accepts of an entry apart from the words sharing its keys")
+	{
+		words := []Record{{Word: "strange"}, {Word: "strangelove"}}
+		loaded := append([]Record{{Word: strangelove, Keys: []string{"strangelove", "dr strangelove"}}}, words...)
+		for how, records := range map[string][]Record{"learnt": words, "loaded": loaded} {
+			for name, autoComplete := range entryEngines(records) {
+				autoComplete.Learn(strangelove, "strangelove", "dr strangelove")
+				autoComplete.Accept(strangelove)
+				_, entryAccepts := count(autoComplete, "strange", strangelove)
+				if _, wordAccepts := count(autoComplete, "strange", "strangelove"); entryAccepts != 1 || wordAccepts != 0 {
+					t.Fatal("Should be able to accept a "+how+" entry without accepting the word of its key on a "+name+" autocompleter", ballotX)
+				}
+				t.Log("Should be able to accept a "+how+" entry without accepting the word of its key on a "+name+" autocompleter", checkMark)
+
+				var save bytes.Buffer
+				autoComplete.SaveTo(&save)
+				retrieved := entryEngines(records)[name]
+				if err = retrieved.RetrieveFrom(&save); err != nil {
+					t.Fatal(err)
+				}
+				_, entryAccepts = count(retrieved, "strange", strangelove)
+				if _, wordAccepts := count(retrieved, "strange", "strangelove"); entryAccepts != 1 || wordAccepts != 0 {
+					t.Fatal("Should be able to save and retrieve the accepts of a "+how+" entry on a "+name+" autocompleter", ballotX)
+				}
+				t.Log("Should be able to save and retrieve the accepts of a "+how+" entry on a "+name+" autocompleter", checkMark)
+
+				autoComplete.UnLearn(strangelove)
+				ac, _ := autoComplete.Complete("strange")
+				if !reflect.DeepEqual(ac, []string{"strange", "strangelove"}) {
+					t.Log(ac)
+					t.Fatal("Should be able to drop the accepts of an unlearnt "+how+" entry on a "+name+" autocompleter", ballotX)
+				}
+				autoComplete.Learn(strangelove, "strangelove", "dr strangelove")
+				if _, entryAccepts = count(autoComplete, "strange", strangelove); entryAccepts != 0 {
+					t.Fatal("Should be able to drop the accepts of an unlearnt "+how+" entry on a "+name+" autocompleter", ballotX)
+				}
+				t.Log("Should be able to drop the accepts of an unlearnt "+how+" entry on a "+name+" autocompleter", checkMark)
+			}
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportFormat is the format Export writes the learnt state of an autocompleter in, and Import reads it in.
+type ExportFormat int
+
+const (
+	// ExportJSONLines writes a JSON object per word, one per line: {"word":"chairman","accepts":3,"status":"new"}.
+	ExportJSONLines ExportFormat = iota
+	// ExportCSV writes a CSV record per word, after a header record: word,accepts,status.
+	ExportCSV
+)
+
+// ImportMode tells Import what to do with the accept counts an autocompleter already has.
+type ImportMode int
+
+const (
+	// ImportMerge adds the accept counts imported to the ones of the autocompleter.
+	ImportMerge ImportMode = iota
+	// ImportReplace resets the learnt state of the autocompleter to the one imported: the accept counts are replaced
+	// with the ones imported, and the words learnt, unlearnt or accepted that are not imported are unlearnt, learnt again
+	// or have their accept count reset.
+	ImportReplace
+)
+
+// The status of a LearntWord.
+const (
+	StatusNew     = "new"
+	StatusRemoved = "removed"
+)
+
+// LearntWord is a word of the learnt state of an autocompleter, as written by Export.
+type LearntWord struct {
+	Word    string `json:"word"`
+	Accepts int    `json:"accepts"`
+	// Status is StatusNew for a word learnt, StatusRemoved for a word unlearnt, and empty for a word of the dictionary
+	// that was accepted.
+	Status string `json:"status,omitempty"`
+}
+
+var exportHeader = []string{"word", "accepts", "status"}
+
+// learntWords sorts LearntWords by word.
+type learntWords []LearntWord
+
+func (words learntWords) Len() int           { return len(words) }
+func (words learntWords) Less(i, j int) bool { return words[i].Word < words[j].Word }
+func (words learntWords) Swap(i, j int)      { words[i], words[j] = words[j], words[i] }
+
+// Export writes the learnt state of autoComplete to writer in format, in order of word: the words it has learnt and
+// unlearnt, and the words of its dictionary it has accepted, with their accept counts. Payloads, weights, keys and the
+// counts of the n-gram model are not exported; Save keeps them.
+func Export(autoComplete AutoComplete, writer io.Writer, format ExportFormat) error {
+
+	var save bytes.Buffer
+	if err := autoComplete.SaveTo(&save); err != nil {
+		return err
+	}
+	return ExportSave(&save, writer, format)
+}
+
+// ExportSave is like Export, for the autocompleter saved in the save file read from reader.
+func ExportSave(reader io.Reader, writer io.Writer, format ExportFormat) error {
+
+	words, err := readLearnt(reader)
+	if err != nil {
+		return err
+	}
+	return writeLearnt(writer, words, format)
+}
+
+// Import reads from reader the learnt state written by Export in format, and teaches it to autoComplete: words new to
+// it are learnt, words removed are unlearnt, and accept counts are merged or replaced according to mode.
+func Import(autoComplete AutoComplete, reader io.Reader, format ExportFormat, mode ImportMode) error {
+
+	words, err := parseLearnt(reader, format)
+	if err != nil {
+		return err
+	}
+	var save bytes.Buffer
+	if err = autoComplete.SaveTo(&save); err != nil {
+		return err
+	}
+	current, err := readLearnt(&save)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case ImportMerge:
+		accepts := make(map[string]int, len(current))
+		for _, word := range current {
+			accepts[word.Word] = word.Accepts
+		}
+		for i := range words {
+			if words[i].Status != StatusRemoved {
+				words[i].Accepts += accepts[words[i].Word]
+			}
+		}
+	case ImportReplace:
+		imported := make(map[string]bool, len(words))
+		for _, word := range words {
+			imported[word.Word] = true
+		}
+		for _, word := range current {
+			if imported[word.Word] {
+				continue
+			}
+			switch word.Status {
+			case StatusNew:
+				words = append(words, LearntWord{Word: word.Word, Status: StatusRemoved})
+			case StatusRemoved:
+				words = append(words, LearntWord{Word: word.Word, Status: StatusNew})
+			default:
+				words = append(words, LearntWord{Word: word.Word})
+			}
+		}
+	}
+
+	var body bytes.Buffer
+	if err = writeLearntBody(&body, words, mode == ImportReplace); err != nil {
+		return err
+	}
+	return autoComplete.RetrieveFrom(&body)
+}
+
+// ImportSave reads from reader the learnt state written by Export in format, and writes it to writer as a save file of
+// an engine ("LiNo", "trie", "radix" or "FST"), for Retrieve to read.
+func ImportSave(reader io.Reader, writer io.Writer, format ExportFormat, engine string) error {
+
+	switch engine {
+	case "LiNo", "trie", "radix", "FST":
+	default:
+		return errors.New("Unknown engine: " + engine)
+	}
+	words, err := parseLearnt(reader, format)
+	if err != nil {
+		return err
+	}
+	return writeSave(writer, saveHeader{Engine: engine}, func(writer io.Writer) error {
+		return writeLearntBody(writer, words, false)
+	})
+}
+
+// readLearnt reads the learnt state in the save file read from reader, in order of word.
+func readLearnt(reader io.Reader) ([]LearntWord, error) {
+
+	_, body, err := readSaveHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	var words []LearntWord
+	dec := gob.NewDecoder(body)
+	for {
+		var wA wordAccepts
+		if err = dec.Decode(&wA); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(wA.Context) > 0:
+		case wA.Accepts < 0:
+			words = append(words, LearntWord{Word: wA.Word, Status: StatusRemoved})
+		case wA.New:
+			words = append(words, LearntWord{Word: wA.Word, Accepts: wA.Accepts, Status: StatusNew})
+		case wA.Accepts > 0:
+			words = append(words, LearntWord{Word: wA.Word, Accepts: wA.Accepts})
+		}
+	}
+	sort.Sort(learntWords(words))
+	return words, nil
+}
+
+// writeLearntBody writes words as the body of a save file. With setAccepts, their accept counts replace the ones of the
+// autocompleter retrieving it even if they are 0.
+func writeLearntBody(writer io.Writer, words []LearntWord, setAccepts bool) error {
+
+	enc := &wordEncoder{enc: gob.NewEncoder(writer)}
+	for _, word := range words {
+		wA := wordAccepts{Word: word.Word, Accepts: word.Accepts, New: word.Status == StatusNew, SetAccepts: setAccepts}
+		if word.Status == StatusRemoved {
+			wA.Accepts, wA.SetAccepts = -1, false
+		}
+		enc.encode(wA)
+	}
+	return enc.err
+}
+
+// writeLearnt writes words to writer in format.
+func writeLearnt(writer io.Writer, words []LearntWord, format ExportFormat) error {
+
+	buffered := bufio.NewWriter(writer)
+	switch format {
+	case ExportJSONLines:
+		enc := json.NewEncoder(buffered)
+		for _, word := range words {
+			if err := enc.Encode(word); err != nil {
+				return err
+			}
+		}
+	case ExportCSV:
+		csvWriter := csv.NewWriter(buffered)
+		csvWriter.Write(exportHeader)
+		for _, word := range words {
+			csvWriter.Write([]string{word.Word, strconv.Itoa(word.Accepts), word.Status})
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+	default:
+		return errors.New("Unknown export format")
+	}
+	return buffered.Flush()
+}
+
+// parseLearnt reads from reader the words written by writeLearnt in format. Blank lines are skipped. Errors are
+// prefixed by the number of the line they were found at.
+func parseLearnt(reader io.Reader, format ExportFormat) ([]LearntWord, error) {
+
+	var words []LearntWord
+	switch format {
+	case ExportJSONLines:
+		lineScanner := bufio.NewScanner(reader)
+		for line := 1; lineScanner.Scan(); line++ {
+			if strings.TrimSpace(lineScanner.Text()) == "" {
+				continue
+			}
+			var word LearntWord
+			if err := json.Unmarshal(lineScanner.Bytes(), &word); err != nil {
+				return nil, lineError(line, err)
+			}
+			if err := word.check(); err != nil {
+				return nil, lineError(line, err)
+			}
+			words = append(words, word)
+		}
+		return words, lineScanner.Err()
+	case ExportCSV:
+		csvReader := csv.NewReader(reader)
+		csvReader.FieldsPerRecord = -1
+		for {
+			fields, err := csvReader.Read()
+			if err == io.EOF {
+				return words, nil
+			} else if err != nil {
+				return nil, err
+			}
+			line, _ := csvReader.FieldPos(0)
+			if line == 1 && fields[0] == exportHeader[0] {
+				continue
+			}
+			if len(fields) < 2 || len(fields) > 3 {
+				return nil, lineError(line, errors.New("Expected word,accepts,status in learnt state"))
+			}
+			word := LearntWord{Word: fields[0]}
+			if word.Accepts, err = strconv.Atoi(fields[1]); err != nil {
+				return nil, lineError(line, errors.New("Invalid accepts in learnt state: "+fields[1]))
+			}
+			if len(fields) == 3 {
+				word.Status = fields[2]
+			}
+			if err = word.check(); err != nil {
+				return nil, lineError(line, err)
+			}
+			words = append(words, word)
+		}
+	}
+	return nil, errors.New("Unknown export format")
+}
+
+// check returns an error if word cannot be imported.
+func (word LearntWord) check() error {
+
+	if len(word.Word) == 0 {
+		return errors.New("Empty word in learnt state")
+	}
+	if word.Accepts < 0 {
+		return errors.New("Invalid accepts in learnt state: " + strconv.Itoa(word.Accepts))
+	}
+	switch word.Status {
+	case "", StatusNew, StatusRemoved:
+		return nil
+	}
+	return errors.New("Invalid status in learnt state: " + word.Status)
+}
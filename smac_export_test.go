@@ -0,0 +1,132 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+
+	jsonLines := `{"word":"yam","accepts":2}
+{"word":"yarn","accepts":0,"status":"new"}
+{"word":"yes","accepts":0,"status":"removed"}
+`
+	csvRecords := "word,accepts,status\nyam,2,\nyarn,0,new\nyes,0,removed\n"
+
+	t.Log("Given the need to read and edit what an autocompleter has learnt")
+	{
+		for name, autoComplete := range saveEngines() {
+			autoComplete.Accept("yam")
+			autoComplete.Accept("yam")
+			autoComplete.Learn("yarn")
+			autoComplete.UnLearn("yes")
+
+			var exported bytes.Buffer
+			if err := Export(autoComplete, &exported, ExportJSONLines); err != nil {
+				t.Fatal(err)
+			}
+			if exported.String() != jsonLines {
+				t.Log(exported.String())
+				t.Fatal("Should be able to export to JSON Lines from a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to export to JSON Lines from a "+name+" autocompleter", checkMark)
+
+			exported.Reset()
+			if err := Export(autoComplete, &exported, ExportCSV); err != nil {
+				t.Fatal(err)
+			}
+			if exported.String() != csvRecords {
+				t.Log(exported.String())
+				t.Fatal("Should be able to export to CSV from a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to export to CSV from a "+name+" autocompleter", checkMark)
+
+			for format, learnt := range map[ExportFormat]string{ExportJSONLines: jsonLines, ExportCSV: csvRecords} {
+				imported := saveEngines()[name]
+				imported.Accept("yam")
+				if err := Import(imported, strings.NewReader(learnt), format, ImportReplace); err != nil {
+					t.Fatal(err)
+				}
+				ac, _ := imported.Complete("y")
+				results, _ := imported.CompleteScored("yam", CompleteOptions{})
+				if !reflect.DeepEqual(ac, []string{"yam", "yak", "yarn"}) || results[0].Accepts != 2 {
+					t.Log(ac, results)
+					t.Fatal("Should be able to import replacing accepts into a "+name+" autocompleter", ballotX)
+				}
+				t.Log("Should be able to import replacing accepts into a "+name+" autocompleter", checkMark)
+
+				if err := Import(imported, strings.NewReader(learnt), format, ImportMerge); err != nil {
+					t.Fatal(err)
+				}
+				results, _ = imported.CompleteScored("yam", CompleteOptions{})
+				if results[0].Accepts != 4 {
+					t.Log(results)
+					t.Fatal("Should be able to import adding accepts into a "+name+" autocompleter", ballotX)
+				}
+				t.Log("Should be able to import adding accepts into a "+name+" autocompleter", checkMark)
+
+				dropped := map[ExportFormat]string{ExportJSONLines: `{"word":"yak","accepts":1}` + "\n", ExportCSV: "word,accepts,status\nyak,1,\n"}[format]
+				if err := Import(imported, strings.NewReader(dropped), format, ImportReplace); err != nil {
+					t.Fatal(err)
+				}
+				var exported bytes.Buffer
+				Export(imported, &exported, format)
+				ac, _ = imported.Complete("y")
+				if exported.String() != dropped || !reflect.DeepEqual(ac, []string{"yak", "yam", "yes"}) {
+					t.Log(exported.String(), ac)
+					t.Fatal("Should be able to reset what a "+name+" autocompleter has learnt to exactly what is imported", ballotX)
+				}
+				t.Log("Should be able to reset what a "+name+" autocompleter has learnt to exactly what is imported", checkMark)
+			}
+
+			var save bytes.Buffer
+			if err := ImportSave(strings.NewReader(jsonLines), &save, ExportJSONLines, name); err != nil {
+				t.Fatal(err)
+			}
+			retrieved := saveEngines()[name]
+			if err := retrieved.RetrieveFrom(bytes.NewReader(save.Bytes())); err != nil {
+				t.Fatal(err)
+			}
+			exported.Reset()
+			ExportSave(&save, &exported, ExportCSV)
+			ac, _ := retrieved.Complete("y")
+			if !reflect.DeepEqual(ac, []string{"yam", "yak", "yarn"}) || exported.String() != csvRecords {
+				t.Log(ac, exported.String())
+				t.Fatal("Should be able to convert between save files and CSV or JSON Lines for a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to convert between save files and CSV or JSON Lines for a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to reject learnt state that cannot be imported")
+	{
+		bad := []struct {
+			name   string
+			format ExportFormat
+			learnt string
+			err    string
+		}{
+			{"a word without a word", ExportJSONLines, `{"word":"yam"}` + "\n\n" + `{"accepts":1}`, "Line 3: Empty word in learnt state"},
+			{"a word with negative accepts", ExportCSV, "word,accepts,status\nyam,-1,\n", "Line 2: Invalid accepts in learnt state: -1"},
+			{"a word with accepts that are not a number", ExportCSV, "yam,many\n", "Line 1: Invalid accepts in learnt state: many"},
+			{"a word with an unknown status", ExportJSONLines, `{"word":"yam","status":"forgotten"}`, "Line 1: Invalid status in learnt state: forgotten"},
+		}
+		for _, test := range bad {
+			if err := Import(saveEngines()["LiNo"], strings.NewReader(test.learnt), test.format, ImportMerge); err == nil || err.Error() != test.err {
+				t.Log(err)
+				t.Fatal("Should not be able to import "+test.name, ballotX)
+			}
+			t.Log("Should not be able to import "+test.name, checkMark)
+		}
+
+		if ImportSave(strings.NewReader(""), &bytes.Buffer{}, ExportCSV, "B-tree") == nil {
+			t.Fatal("Should not be able to write a save file of an unknown engine", ballotX)
+		}
+		t.Log("Should not be able to write a save file of an unknown engine", checkMark)
+	}
+}
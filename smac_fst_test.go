@@ -0,0 +1,318 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFSTConstruction(t *testing.T) {
+
+	t.Log("Given the need to test the automaton construction")
+	{
+		autoComplete, _ := NewAutoCompleteFSTS([]string{"tap", "taps", "top", "tops"}, 0, 0)
+		if len(autoComplete.fst.states)/fstStateSize != 5 {
+			t.Fatal("Should be able to share suffixes between words", ballotX)
+		}
+		t.Log("Should be able to share suffixes between words", checkMark)
+
+		words := []string{"a", "ab", "abc", "b", "bc", "c"}
+		autoComplete, _ = NewAutoCompleteFSTS([]string{"bc", "c", "a", "abc", "b", "ab"}, 0, 0)
+		for i, word := range words {
+			if ordinal, ok := autoComplete.fst.lookup(word); !ok || ordinal != uint32(i) {
+				t.Fatal("Should be able to map words to their ordinal", ballotX)
+			}
+		}
+		if _, ok := autoComplete.fst.lookup("ac"); ok {
+			t.Fatal("Should be able to map words to their ordinal", ballotX)
+		}
+		t.Log("Should be able to map words to their ordinal", checkMark)
+
+		builder := newFSTBuilder(IdentityNormalizer)
+		builder.add("b")
+		if builder.add("a") == nil {
+			t.Fatal("Should be able to reject an unsorted dictionary", ballotX)
+		}
+		t.Log("Should be able to reject an unsorted dictionary", checkMark)
+
+		if _, err := NewAutoCompleteFSTS([]string{"a", ""}, 0, 0); err == nil {
+			t.Fatal("Should be able to reject an empty word", ballotX)
+		}
+		t.Log("Should be able to reject an empty word", checkMark)
+
+		if _, err := readFST([]byte("SMACFST")); err == nil {
+			t.Fatal("Should be able to reject a file that is not an automaton", ballotX)
+		}
+		t.Log("Should be able to reject a file that is not an automaton", checkMark)
+
+		autoComplete, _ = NewAutoCompleteFSTS([]string{"Bach", "bach", "Bac", "bac", "bad"}, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		data := autoComplete.fst.data
+		if ordinal, _ := autoComplete.fst.lookup("bach"); !reflect.DeepEqual(autoComplete.fst.forms(ordinal), surfaceForms{"Bach", "bach"}) {
+			t.Fatal("Should be able to read the forms of a word from the forms table", ballotX)
+		}
+		if ordinal, _ := autoComplete.fst.lookup("bad"); autoComplete.fst.forms(ordinal) != nil {
+			t.Fatal("Should be able to read the forms of a word from the forms table", ballotX)
+		}
+		t.Log("Should be able to read the forms of a word from the forms table", checkMark)
+
+		corrupted := append([]byte{}, data...)
+		transitions := fstHeaderSize + len(autoComplete.fst.states)
+		copy(corrupted[transitions+4:], []byte{0xff, 0xff, 0, 0})
+		if _, err := readFST(corrupted); err == nil {
+			t.Fatal("Should be able to reject a transition to a state out of bounds", ballotX)
+		}
+		corrupted = append([]byte{}, data...)
+		copy(corrupted[fstHeaderSize+4:], []byte{0xff, 0xff, 0, 0})
+		if _, err := readFST(corrupted); err == nil {
+			t.Fatal("Should be able to reject a state with transitions out of bounds", ballotX)
+		}
+		corrupted = append([]byte{}, data...)
+		copy(corrupted[len(data)-len(autoComplete.fst.formData)-len(autoComplete.fst.formTable)+4:], []byte{0xff, 0xff, 0, 0})
+		if _, err := readFST(corrupted); err == nil {
+			t.Fatal("Should be able to reject forms out of bounds", ballotX)
+		}
+		if _, err := readFST(data[:len(data)-1]); err == nil {
+			t.Fatal("Should be able to reject a truncated file", ballotX)
+		}
+		t.Log("Should be able to reject a corrupted file", checkMark)
+	}
+}
+
+func TestFSTCompletion(t *testing.T) {
+
+	words := []string{"aaa", "aaab", "aaac", "aaabbb", "aaad", "abbbbb"}
+	autoComplete, _ := NewAutoCompleteFSTS(words, 0, 0)
+
+	t.Log("Given the need to test the completion feature")
+	{
+		ac, _ := autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaab", "aaac", "aaad", "aaabbb"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to autocomplete on a stem word by length and then by alphabetical order", ballotX)
+		}
+		t.Log("Should be able to autocomplete on a stem word by length and then by alphabetical order", checkMark)
+
+		autoComplete.Accept("aaad")
+		autoComplete.Accept("aaad")
+		autoComplete.Accept("aaabbb")
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaad", "aaabbb", "aaa", "aaab", "aaac"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to prioritize accepted words", ballotX)
+		}
+		t.Log("Should be able to prioritize accepted words", checkMark)
+
+		autoComplete.Learn("aaa0")
+		autoComplete.Learn("aaabb")
+		autoComplete.Accept("aaabb")
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaad", "aaabb", "aaabbb", "aaa", "aaa0", "aaab", "aaac"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to merge learnt words with the automaton", ballotX)
+		}
+		t.Log("Should be able to merge learnt words with the automaton", checkMark)
+
+		autoComplete, _ = NewAutoCompleteFSTS(words, 3, 4)
+		autoComplete.Learn("aaa0")
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaa0", "aaab"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to limit result set size", ballotX)
+		}
+		t.Log("Should be able to limit result set size", checkMark)
+
+		autoComplete, _ = NewAutoCompleteFSTS(words, 10, 4)
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaab", "aaac", "aaad"}) {
+			t.Fatal("Should be able to limit radius", ballotX)
+		}
+		t.Log("Should be able to limit radius", checkMark)
+
+		autoComplete, _ = NewAutoCompleteFSTS([]string{"München", "Munich", "MÜNCHEN"}, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		ac, _ = autoComplete.Complete("mun")
+		if !reflect.DeepEqual(ac, []string{"Munich", "München", "MÜNCHEN"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete normalized words in their surface forms", ballotX)
+		}
+		t.Log("Should be able to complete normalized words in their surface forms", checkMark)
+	}
+}
+
+func TestFSTLearn(t *testing.T) {
+
+	words := []string{"aaa", "aaab", "aaabbb", "ddd"}
+	autoComplete, _ := NewAutoCompleteFSTS(words, 0, 0)
+
+	t.Log("Given the need to test the Learn and UnLearn features")
+	{
+		if autoComplete.Learn("aaa") == nil {
+			t.Fatal("Should be able to reject a word already in the automaton", ballotX)
+		}
+		t.Log("Should be able to reject a word already in the automaton", checkMark)
+
+		autoComplete.UnLearn("aaab")
+		autoComplete.Accept("aaa")
+		autoComplete.UnLearn("aaa")
+		ac, _ := autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaabbb"}) {
+			t.Fatal("Should be able to unlearn words of the automaton", ballotX)
+		}
+		t.Log("Should be able to unlearn words of the automaton", checkMark)
+
+		if autoComplete.UnLearn("aaab") == nil || autoComplete.Accept("aaab") == nil {
+			t.Fatal("Should be able to reject using an unlearnt word", ballotX)
+		}
+		t.Log("Should be able to reject using an unlearnt word", checkMark)
+
+		autoComplete.Learn("aaa")
+		autoComplete.Learn("aa")
+		ac, _ = autoComplete.Complete("aa")
+		if !reflect.DeepEqual(ac, []string{"aa", "aaa", "aaabbb"}) || len(autoComplete.accepts) != 0 {
+			t.Log(ac)
+			t.Fatal("Should be able to learn words back and new words", ballotX)
+		}
+		t.Log("Should be able to learn words back and new words", checkMark)
+
+		autoComplete, _ = NewAutoCompleteFSTS([]string{"München", "MÜNCHEN"}, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		autoComplete.Learn("Munchen")
+		autoComplete.UnLearn("MÜNCHEN")
+		autoComplete.UnLearn("Munchen")
+		ac, _ = autoComplete.Complete("mu")
+		if !reflect.DeepEqual(ac, []string{"München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to unlearn a single form", ballotX)
+		}
+		t.Log("Should be able to unlearn a single form", checkMark)
+
+		autoComplete.Learn("Munchen")
+		autoComplete.UnLearn("munchen")
+		ac, _ = autoComplete.Complete("mu")
+		if !reflect.DeepEqual(ac, []string{}) {
+			t.Log(ac)
+			t.Fatal("Should be able to unlearn all the forms of a word", ballotX)
+		}
+		t.Log("Should be able to unlearn all the forms of a word", checkMark)
+	}
+}
+
+func TestFSTCompleteFuzzy(t *testing.T) {
+
+	words := []string{"chai", "chain", "chair", "chairman", "chairperson", "chalk", "cheer", "table"}
+	autoComplete, _ := NewAutoCompleteFSTS(words, 0, 0)
+
+	t.Log("Given the need to test the fuzzy completion feature")
+	{
+		ac, _ := autoComplete.CompleteFuzzy("chiar", 1)
+		if !reflect.DeepEqual(ac, []string{"chair", "chairman", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a stem with transposed runes", ballotX)
+		}
+		t.Log("Should be able to complete a stem with transposed runes", checkMark)
+
+		autoComplete.Learn("chairs")
+		autoComplete.UnLearn("chairperson")
+		ac, _ = autoComplete.CompleteFuzzy("chiar", 1)
+		if !reflect.DeepEqual(ac, []string{"chair", "chairs", "chairman"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete fuzzily on the overlay", ballotX)
+		}
+		t.Log("Should be able to complete fuzzily on the overlay", checkMark)
+	}
+}
+
+func TestFSTFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	words := []string{"aaa", "aaabbb", "bbb", "München", "Munich"}
+	dictionary := dir + "/words.txt"
+	ioutil.WriteFile(dictionary, []byte(strings.Join([]string{"aaa", "aaabbb", "bbb", "München", "munich"}, "\n")), 0644)
+
+	t.Log("Given the need to test writing and mapping the automaton")
+	{
+		fstFile := dir + "/words.fst"
+		if err = BuildFST(dictionary, fstFile, WithNormalizer(FoldingNormalizer{})); err != nil {
+			t.Fatal(err)
+		}
+		autoComplete, err := NewAutoCompleteFSTM(fstFile, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ac, _ := autoComplete.Complete("mu")
+		if !reflect.DeepEqual(ac, []string{"munich", "München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to map an automaton built from a sorted dictionary", ballotX)
+		}
+		autoComplete.Close()
+		t.Log("Should be able to map an automaton built from a sorted dictionary", checkMark)
+
+		ioutil.WriteFile(dictionary, []byte("bbb\naaa"), 0644)
+		if BuildFST(dictionary, fstFile) == nil {
+			t.Fatal("Should be able to reject building from an unsorted dictionary", ballotX)
+		}
+		t.Log("Should be able to reject building from an unsorted dictionary", checkMark)
+
+		inMemory, _ := NewAutoCompleteFSTS(words, 0, 0)
+		if err = inMemory.WriteFST(fstFile); err != nil {
+			t.Fatal(err)
+		}
+		autoComplete, err = NewAutoCompleteFSTM(fstFile, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer autoComplete.Close()
+		ac, _ = autoComplete.Complete("")
+		if !reflect.DeepEqual(ac, []string{"aaa", "bbb", "Munich", "aaabbb", "München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to map a written automaton", ballotX)
+		}
+		t.Log("Should be able to map a written automaton", checkMark)
+
+		saveFile := dir + "/learnt"
+		autoComplete.Accept("aaabbb")
+		autoComplete.Learn("ccc")
+		autoComplete.Learn("eee")
+		autoComplete.Accept("eee")
+		autoComplete.UnLearn("bbb")
+		if err = autoComplete.Save(saveFile); err != nil {
+			t.Fatal("Should be able to save words to a file", ballotX)
+		}
+		t.Log("Should be able to save words to a file", checkMark)
+
+		retrieved, _ := NewAutoCompleteFSTM(fstFile, 0, 0)
+		defer retrieved.Close()
+		if err = retrieved.Retrieve(saveFile); err != nil {
+			t.Fatal(err)
+		}
+		ac, _ = retrieved.Complete("")
+		if !reflect.DeepEqual(ac, []string{"eee", "aaabbb", "aaa", "ccc", "Munich", "München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to get back from retrieve learnt, unlearnt and accepted words", ballotX)
+		}
+		t.Log("Should be able to get back from retrieve learnt, unlearnt and accepted words", checkMark)
+
+		other, _ := NewAutoCompleteFSTS([]string{"zzz"}, 0, 0)
+		if err = other.WriteFST(fstFile); err != nil {
+			t.Fatal(err)
+		}
+		ioutil.WriteFile(dictionary, []byte("yyy"), 0644)
+		if err = BuildFST(dictionary, fstFile); err != nil {
+			t.Fatal(err)
+		}
+		ac, _ = retrieved.Complete("")
+		if !reflect.DeepEqual(ac, []string{"eee", "aaabbb", "aaa", "ccc", "Munich", "München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to replace a mapped automaton leaving the autocompleters mapping it alone", ballotX)
+		}
+		t.Log("Should be able to replace a mapped automaton leaving the autocompleters mapping it alone", checkMark)
+	}
+}
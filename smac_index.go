@@ -0,0 +1,300 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Index files start with indexMagic, a uvarint version, the name of the engine that wrote them and the one of its
+// normalizer (see normalizerName), followed by the words of the engine in a layout of its own, and by an indexState. They end with the big-endian CRC-32 (IEEE) of all
+// that comes before. Integers are uvarints, and strings are their uvarint length followed by their bytes.
+const indexMagic = "\x89SMIX"
+
+const indexVersion = 2
+
+// indexState holds what an index holds besides the words of an engine: the payloads of the words, and what the
+// engine has learnt.
+type indexState struct {
+	Payloads     map[string]interface{}
+	NewWords     []string
+	NewPayloads  []string
+	NewWeights   []string
+	RemovedWords []string
+	Entries      map[string][]string
+	NGramCounts  map[string]map[string]int
+	NGramTotals  map[string]int
+	Decay        map[string]decayedAccepts
+	EntryAccepts map[string]int
+	EntryDecay   map[string]decayedAccepts
+	Changes      map[string]time.Time
+}
+
+// indexWriter writes an index, keeping the first error.
+type indexWriter struct {
+	writer   *bufio.Writer
+	checksum hash.Hash32
+	err      error
+}
+
+func newIndexWriter(writer io.Writer, engine string, normalizer Normalizer) *indexWriter {
+
+	checksum := crc32.NewIEEE()
+	iw := &indexWriter{writer: bufio.NewWriter(io.MultiWriter(writer, checksum)), checksum: checksum}
+	iw.bytes([]byte(indexMagic))
+	iw.uvarint(indexVersion)
+	iw.string(engine)
+	iw.string(normalizerName(normalizer))
+	return iw
+}
+
+func (iw *indexWriter) bytes(b []byte) {
+	if iw.err == nil {
+		_, iw.err = iw.writer.Write(b)
+	}
+}
+
+func (iw *indexWriter) uvarint(v uint64) {
+
+	var buffer [binary.MaxVarintLen64]byte
+	iw.bytes(buffer[:binary.PutUvarint(buffer[:], v)])
+}
+
+func (iw *indexWriter) string(s string) {
+	iw.uvarint(uint64(len(s)))
+	if iw.err == nil {
+		_, iw.err = iw.writer.WriteString(s)
+	}
+}
+
+func (iw *indexWriter) float(f float64) {
+
+	var buffer [8]byte
+	binary.BigEndian.PutUint64(buffer[:], math.Float64bits(f))
+	iw.bytes(buffer[:])
+}
+
+// close writes state and the checksum of the index.
+func (iw *indexWriter) close(state indexState) error {
+
+	if iw.err == nil {
+		iw.err = gob.NewEncoder(iw.writer).Encode(state)
+	}
+	if iw.err == nil {
+		iw.err = iw.writer.Flush()
+	}
+	if iw.err != nil {
+		return iw.err
+	}
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], iw.checksum.Sum32())
+	_, err := iw.writer.Write(checksum[:])
+	if err != nil {
+		return err
+	}
+	return iw.writer.Flush()
+}
+
+// indexReader reads an index, keeping the first error.
+type indexReader struct {
+	reader *bytes.Reader
+	err    error
+}
+
+// openIndex reads the index fileName, checking its checksum and that it was written by engine with normalizer, since
+// the words of an index are keyed by the normalizer they were added with.
+func openIndex(fileName, engine string, normalizer Normalizer) (*indexReader, error) {
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(indexMagic)+4 || string(data[:len(indexMagic)]) != indexMagic {
+		return nil, errors.New("Not an index file")
+	}
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[len(body):]) {
+		return nil, errors.New("Corrupted index file: checksum mismatch")
+	}
+
+	ir := &indexReader{reader: bytes.NewReader(body[len(indexMagic):])}
+	if version := ir.uvarint(); version != indexVersion {
+		return nil, errors.New("Unsupported index file version " + strconv.FormatUint(version, 10))
+	}
+	if written := ir.string(); written != engine {
+		return nil, errors.New("Index file written by a " + written + " autocompleter, not by a " + engine + " one")
+	}
+	if written, name := ir.string(), normalizerName(normalizer); ir.err == nil && written != name {
+		return nil, errors.New("Index file written by an autocompleter with normalizer " + written + ", not " + name)
+	}
+	return ir, ir.err
+}
+
+func (ir *indexReader) uvarint() uint64 {
+
+	if ir.err != nil {
+		return 0
+	}
+	var v uint64
+	v, ir.err = binary.ReadUvarint(ir.reader)
+	return v
+}
+
+func (ir *indexReader) byte() byte {
+
+	if ir.err != nil {
+		return 0
+	}
+	var b byte
+	b, ir.err = ir.reader.ReadByte()
+	return b
+}
+
+func (ir *indexReader) string() string {
+
+	length := ir.uvarint()
+	if ir.err != nil {
+		return ""
+	}
+	if length > uint64(ir.reader.Len()) {
+		ir.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	buffer := make([]byte, length)
+	_, ir.err = io.ReadFull(ir.reader, buffer)
+	return string(buffer)
+}
+
+func (ir *indexReader) float() float64 {
+
+	if ir.err != nil {
+		return 0
+	}
+	var buffer [8]byte
+	_, ir.err = io.ReadFull(ir.reader, buffer[:])
+	return math.Float64frombits(binary.BigEndian.Uint64(buffer[:]))
+}
+
+// close reads the state at the end of the index.
+func (ir *indexReader) close() (indexState, error) {
+
+	var state indexState
+	if ir.err != nil {
+		return state, errors.New("Corrupted index file: " + ir.err.Error())
+	}
+	if err := gob.NewDecoder(ir.reader).Decode(&state); err != nil {
+		return state, errors.New("Corrupted index file: " + err.Error())
+	}
+	return state, nil
+}
+
+// The flags of a word in an index tell which of its optional fields follow it.
+const (
+	indexAccepts = 1 << iota
+	indexWeight
+	indexForms
+	indexWord
+)
+
+// word writes the optional fields of a word, preceded by their flags.
+func (iw *indexWriter) word(flags byte, accepts int, weight float64, forms surfaceForms) {
+
+	if accepts != 0 {
+		flags |= indexAccepts
+	}
+	if weight != 0 {
+		flags |= indexWeight
+	}
+	if forms != nil {
+		flags |= indexForms
+	}
+	iw.bytes([]byte{flags})
+	if accepts != 0 {
+		iw.uvarint(uint64(accepts))
+	}
+	if weight != 0 {
+		iw.float(weight)
+	}
+	if forms != nil {
+		iw.uvarint(uint64(len(forms)))
+		for _, form := range forms {
+			iw.string(form)
+		}
+	}
+}
+
+// word reads the optional fields of a word, and returns their flags.
+func (ir *indexReader) word() (flags byte, accepts int, weight float64, forms surfaceForms) {
+
+	flags = ir.byte()
+	if flags&indexAccepts != 0 {
+		accepts = int(ir.uvarint())
+	}
+	if flags&indexWeight != 0 {
+		weight = ir.float()
+	}
+	if flags&indexForms != 0 {
+		count := ir.uvarint()
+		if count > uint64(ir.reader.Len()) {
+			ir.err = io.ErrUnexpectedEOF
+			return
+		}
+		forms = make(surfaceForms, count)
+		for i := range forms {
+			forms[i] = ir.string()
+		}
+	}
+	return
+}
+
+// setList returns the members of a set.
+func setList(set map[string]bool) []string {
+
+	list := make([]string, 0, len(set))
+	for member := range set {
+		list = append(list, member)
+	}
+	return list
+}
+
+// listSet returns the set of the members of list.
+func listSet(list []string) map[string]bool {
+
+	set := make(map[string]bool, len(list))
+	for _, member := range list {
+		set[member] = true
+	}
+	return set
+}
+
+// byteSetList returns the members of a set of the trie engine.
+func byteSetList(set map[string]byte) []string {
+
+	list := make([]string, 0, len(set))
+	for member := range set {
+		list = append(list, member)
+	}
+	return list
+}
+
+// listByteSet returns the set of the trie engine of the members of list.
+func listByteSet(list []string) map[string]byte {
+
+	set := make(map[string]byte, len(list))
+	for _, member := range list {
+		set[member] = 0
+	}
+	return set
+}
@@ -0,0 +1,103 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dictionary := []Record{{Word: "chair", Payload: "seat", Weight: 3}, {Word: "chairman"}, {Word: "Chairs"}, {Word: "chalk"}, {Word: "cheese"}}
+	teach := func(autoComplete AutoComplete) {
+		autoComplete.Accept("chairman")
+		autoComplete.Learn("Chaplin", "charlie chaplin")
+		autoComplete.LearnWithPayload("chart", 42)
+		autoComplete.SetWeight("chalk", 7)
+		autoComplete.UnLearn("cheese")
+		autoComplete.AcceptSequence([]string{"chair", "chairman"})
+	}
+
+	t.Log("Given the need to load a whole autocompleter without building it again")
+	{
+		lino, _ := NewAutoCompleteLinoP(dictionary, 3, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		teach(&lino)
+		if err = lino.WriteIndex(dir + "/lino.index"); err != nil {
+			t.Fatal(err)
+		}
+		loadedLino, _ := NewAutoCompleteLinoE(1, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		if err = loadedLino.LoadIndex(dir + "/lino.index"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loadedLino.wordMap, lino.wordMap) || loadedLino.head != lino.head || loadedLino.tail != lino.tail ||
+			!reflect.DeepEqual(loadedLino.prefixMap, lino.prefixMap) || loadedLino.prefixMapDepth != 3 || !reflect.DeepEqual(loadedLino.alphabet, lino.alphabet) ||
+			!reflect.DeepEqual(loadedLino.newWords, lino.newWords) || !reflect.DeepEqual(loadedLino.removedWords, lino.removedWords) ||
+			!reflect.DeepEqual(loadedLino.newPayloads, lino.newPayloads) || !reflect.DeepEqual(loadedLino.newWeights, lino.newWeights) ||
+			!reflect.DeepEqual(loadedLino.entries, lino.entries) || !reflect.DeepEqual(loadedLino.ngrams, lino.ngrams) ||
+			!reflect.DeepEqual(loadedLino.changes.at, lino.changes.at) {
+			t.Fatal("Should be able to load a LiNo autocompleter from an index", ballotX)
+		}
+		t.Log("Should be able to load a LiNo autocompleter from an index", checkMark)
+
+		trie, _ := NewAutoCompleteTrieP("abcdefghijklmnopqrstuvwxyz ", dictionary, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		teach(&trie)
+		if err = trie.WriteIndex(dir + "/trie.index"); err != nil {
+			t.Fatal(err)
+		}
+		loadedTrie, _ := NewAutoCompleteTrieE("a", 0, 0, WithNormalizer(FoldingNormalizer{}))
+		if err = loadedTrie.LoadIndex(dir + "/trie.index"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loadedTrie.root, trie.root) || !reflect.DeepEqual(loadedTrie.alphabet, trie.alphabet) ||
+			!reflect.DeepEqual(loadedTrie.newWords, trie.newWords) || !reflect.DeepEqual(loadedTrie.removedWords, trie.removedWords) ||
+			!reflect.DeepEqual(loadedTrie.newPayloads, trie.newPayloads) || !reflect.DeepEqual(loadedTrie.newWeights, trie.newWeights) ||
+			!reflect.DeepEqual(loadedTrie.entries, trie.entries) || !reflect.DeepEqual(loadedTrie.ngrams, trie.ngrams) ||
+			!reflect.DeepEqual(loadedTrie.changes.at, trie.changes.at) {
+			t.Fatal("Should be able to load a trie autocompleter from an index", ballotX)
+		}
+		t.Log("Should be able to load a trie autocompleter from an index", checkMark)
+
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &loadedLino, "trie": &loadedTrie} {
+			results, _ := autoComplete.CompleteScored("cha", CompleteOptions{})
+			words := make([]string, len(results))
+			for i, result := range results {
+				words[i] = result.Word
+			}
+			if !reflect.DeepEqual(words[:2], []string{"chairman", "chalk"}) || len(words) != 6 || results[2].Word != "chair" || results[2].Payload != "seat" {
+				t.Log(results)
+				t.Fatal("Should be able to complete words loaded from an index on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete words loaded from an index on a "+name+" autocompleter", checkMark)
+		}
+
+		if loadedTrie.LoadIndex(dir+"/lino.index") == nil {
+			t.Fatal("Should not be able to load the index of another engine", ballotX)
+		}
+		t.Log("Should not be able to load the index of another engine", checkMark)
+
+		identityLino, _ := NewAutoCompleteLinoE(1, 0, 0)
+		identityTrie, _ := NewAutoCompleteTrieE("a", 0, 0)
+		if identityLino.LoadIndex(dir+"/lino.index") == nil || identityTrie.LoadIndex(dir+"/trie.index") == nil {
+			t.Fatal("Should not be able to load an index written with another normalizer", ballotX)
+		}
+		t.Log("Should not be able to load an index written with another normalizer", checkMark)
+
+		index, _ := ioutil.ReadFile(dir + "/lino.index")
+		index[len(index)/2] ^= 0xff
+		ioutil.WriteFile(dir+"/corrupted.index", index, 0644)
+		if loadedLino.LoadIndex(dir+"/corrupted.index") == nil {
+			t.Fatal("Should not be able to load a corrupted index", ballotX)
+		}
+		t.Log("Should not be able to load a corrupted index", checkMark)
+	}
+}
@@ -0,0 +1,473 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyncPolicy tells a Journal when to sync its log to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs the log after every change, so that no change is lost in a crash, at the cost of a disk write per
+	// change.
+	SyncAlways SyncPolicy = iota
+	// SyncPeriodically syncs the log every JournalOptions.SyncInterval, if it has changed: a crash loses the changes of
+	// the last interval at most.
+	SyncPeriodically
+	// SyncNever leaves syncing the log to the operating system: changes survive the process crashing, but not the
+	// machine.
+	SyncNever
+)
+
+// DefaultSyncInterval is the interval the log of a Journal is synced at with SyncPeriodically, if none is given.
+const DefaultSyncInterval = time.Second
+
+// JournalOptions are the options of a Journal. The zero value syncs the log after every change, and never compacts it
+// unless Compact is called.
+type JournalOptions struct {
+	Sync SyncPolicy
+	// SyncInterval is the interval the log is synced at with SyncPeriodically. If 0, DefaultSyncInterval is used.
+	SyncInterval time.Duration
+	// CompactAfter is the number of changes after which the log is compacted. If 0, it is up to the client to call
+	// Compact.
+	CompactAfter int
+}
+
+// Journal is an AutoComplete that appends every change made to it (Accept, Learn, LearnWithPayload, SetWeight, UnLearn
+// and AcceptSequence) to a log file as it happens, so that nothing learnt is lost in a crash without calling Save.
+//
+// NewJournal replays the log on top of the autocompleter it is given, usually just bootstrapped from a dictionary.
+// Compaction replaces the log with a snapshot of everything learnt, as saved by Save, followed by the changes made
+// afterwards. Retrieve and RetrieveFrom compact the log once they have retrieved a file.
+//
+// As the autocompleter it wraps, a Journal is not safe for concurrent use; it can be wrapped with NewConcurrent.
+type Journal struct {
+	autoComplete AutoComplete
+	fileName     string
+	options      JournalOptions
+	// lock guards the log, which is synced by another goroutine with SyncPeriodically.
+	lock    sync.Mutex
+	log     *os.File
+	changes int
+	dirty   bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type journalOp int
+
+const (
+	journalSnapshot journalOp = iota
+	journalAccept
+	journalLearn
+	journalLearnWithPayload
+	journalSetWeight
+	journalUnLearn
+	journalAcceptSequence
+)
+
+// journalRecord is a change in a log. Records are written as a big-endian uint32 length, the CRC-32 (IEEE) of the
+// record and the record, gob-encoded on its own, so that a record torn by a crash can be told and cut away.
+type journalRecord struct {
+	Op       journalOp
+	Word     string
+	Keys     []string
+	Words    []string
+	Payload  interface{}
+	Weight   float64
+	Snapshot []byte
+	// At is when the change was made, by the clock of the autocompleter (see WithClock), zero in the logs written before
+	// times were kept.
+	At time.Time
+}
+
+// clocked is implemented by the autocompleters whose changes are timed by a clock, for decayed accept counts (see
+// WithHalfLife) and for Merge.
+type clocked interface {
+	// now returns the time by the clock.
+	now() time.Time
+	// at makes change, with the clock reading at.
+	at(at time.Time, change func() error) error
+}
+
+// atTime calls change with each of clocks reading at, and sets them back afterwards.
+func atTime(at time.Time, change func() error, clocks ...*func() time.Time) error {
+
+	saved := make([]func() time.Time, len(clocks))
+	for i, clock := range clocks {
+		saved[i] = *clock
+		*clock = func() time.Time { return at }
+	}
+	defer func() {
+		for i, clock := range clocks {
+			*clock = saved[i]
+		}
+	}()
+	return change()
+}
+
+// NewJournal returns a Journal wrapping ac, which must not be used directly afterwards, logging to fileName. If the log
+// exists, the changes it holds are replayed on ac first; a last record torn by a crash is cut away. Changes that no
+// longer apply, such as learning a word the dictionary of ac now holds, are skipped. Changes are replayed as made when
+// they were logged, for accepts to decay (see WithHalfLife) and for Merge to tell which change came last, if ac is one
+// of the engines of SMAC; through other wrappers, and in logs written before times were kept, they count as made on
+// replaying them.
+//
+// Close should be called once the Journal is no longer used.
+func NewJournal(ac AutoComplete, fileName string, options JournalOptions) (*Journal, error) {
+
+	log, err := os.OpenFile(fileName, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	journal := &Journal{
+		autoComplete: ac,
+		fileName:     fileName,
+		options:      options,
+		log:          log,
+	}
+	if err = journal.replay(); err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	if options.Sync == SyncPeriodically {
+		if journal.options.SyncInterval == 0 {
+			journal.options.SyncInterval = DefaultSyncInterval
+		}
+		journal.stop = make(chan struct{})
+		journal.done = make(chan struct{})
+		go journal.syncEvery(journal.options.SyncInterval)
+	}
+	return journal, nil
+}
+
+// replay applies the records of the log to the autocompleter, and cuts away a torn last record.
+func (journal *Journal) replay() error {
+
+	info, err := journal.log.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	reader := bufio.NewReader(journal.log)
+	var offset int64
+	for offset < size {
+		var frame [8]byte
+		if _, err = io.ReadFull(reader, frame[:]); err != nil {
+			break
+		}
+		length := int64(binary.BigEndian.Uint32(frame[:4]))
+		if offset+8+length > size {
+			// a record running past the end of the log is the last one, torn by a crash, unless a whole record follows
+			// it: then its length is corrupted.
+			rest := make([]byte, size-offset-8)
+			if _, err = io.ReadFull(reader, rest); err != nil {
+				return err
+			}
+			if holdsRecord(rest) {
+				return errors.New("Corrupted journal record length at offset " + strconv.FormatInt(offset, 10))
+			}
+			break
+		}
+		data := make([]byte, length)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return err
+		}
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(frame[4:]) {
+			if offset+8+length == size {
+				break
+			}
+			return errors.New("Corrupted journal record at offset " + strconv.FormatInt(offset, 10))
+		}
+		var record journalRecord
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+			return errors.New("Corrupted journal record at offset " + strconv.FormatInt(offset, 10) + ": " + err.Error())
+		}
+		err = journal.apply(record)
+		if record.Op == journalSnapshot && err != nil {
+			return err
+		} else if record.Op != journalSnapshot {
+			journal.changes++
+		}
+		offset += 8 + length
+	}
+
+	if offset < size {
+		return journal.log.Truncate(offset)
+	}
+	return nil
+}
+
+// apply applies record to the autocompleter, as made when it was logged.
+func (journal *Journal) apply(record journalRecord) error {
+
+	if clock, isClocked := journal.autoComplete.(clocked); isClocked && !record.At.IsZero() {
+		return clock.at(record.At, func() error {
+			return journal.applyNow(record)
+		})
+	}
+	return journal.applyNow(record)
+}
+
+func (journal *Journal) applyNow(record journalRecord) error {
+
+	switch record.Op {
+	case journalSnapshot:
+		return journal.autoComplete.RetrieveFrom(bytes.NewReader(record.Snapshot))
+	case journalAccept:
+		return journal.autoComplete.Accept(record.Word)
+	case journalLearn:
+		return journal.autoComplete.Learn(record.Word, record.Keys...)
+	case journalLearnWithPayload:
+		return journal.autoComplete.LearnWithPayload(record.Word, record.Payload, record.Keys...)
+	case journalSetWeight:
+		return journal.autoComplete.SetWeight(record.Word, record.Weight)
+	case journalUnLearn:
+		return journal.autoComplete.UnLearn(record.Word)
+	case journalAcceptSequence:
+		return journal.autoComplete.AcceptSequence(record.Words)
+	}
+	return errors.New("Unknown journal record: " + strconv.Itoa(int(record.Op)))
+}
+
+// holdsRecord reports whether data holds the frame of a whole record, at any offset.
+func holdsRecord(data []byte) bool {
+
+	for i := 0; i+8 < len(data); i++ {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		if length > 0 && length <= len(data)-i-8 && crc32.ChecksumIEEE(data[i+8:i+8+length]) == binary.BigEndian.Uint32(data[i+4:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRecord writes the frame of record to writer.
+func writeRecord(writer io.Writer, record journalRecord) error {
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(record); err != nil {
+		return err
+	}
+	frame := make([]byte, 8, 8+data.Len())
+	binary.BigEndian.PutUint32(frame[:4], uint32(data.Len()))
+	binary.BigEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(data.Bytes()))
+	_, err := writer.Write(append(frame, data.Bytes()...))
+	return err
+}
+
+// change appends record to the log and then applies it to the autocompleter, so that a change is never made without
+// being logged. A record that does not apply is cut away from the log again.
+func (journal *Journal) change(record journalRecord) error {
+
+	if clock, isClocked := journal.autoComplete.(clocked); isClocked {
+		record.At = clock.now().UTC()
+	}
+	journal.lock.Lock()
+	defer journal.lock.Unlock()
+	offset, err := journal.log.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if err = writeRecord(journal.log, record); err != nil {
+		journal.log.Truncate(offset)
+		return err
+	}
+	journal.dirty = true
+	if journal.options.Sync == SyncAlways {
+		err = journal.sync()
+	}
+	if err == nil {
+		err = journal.apply(record)
+	}
+	if err != nil {
+		if truncateErr := journal.log.Truncate(offset); truncateErr != nil {
+			return truncateErr
+		}
+		return err
+	}
+	journal.changes++
+	if journal.options.CompactAfter > 0 && journal.changes >= journal.options.CompactAfter {
+		return journal.compact()
+	}
+	return nil
+}
+
+func (journal *Journal) sync() error {
+
+	if !journal.dirty {
+		return nil
+	}
+	journal.dirty = false
+	return journal.log.Sync()
+}
+
+func (journal *Journal) syncEvery(syncInterval time.Duration) {
+
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	defer close(journal.done)
+	for {
+		select {
+		case <-ticker.C:
+			journal.lock.Lock()
+			journal.sync()
+			journal.lock.Unlock()
+		case <-journal.stop:
+			return
+		}
+	}
+}
+
+// Compact replaces the log with a snapshot of everything the autocompleter has learnt. The log is replaced atomically:
+// should compaction fail, the previous log is left as it was.
+func (journal *Journal) Compact() error {
+
+	journal.lock.Lock()
+	defer journal.lock.Unlock()
+	return journal.compact()
+}
+
+func (journal *Journal) compact() error {
+
+	var snapshot bytes.Buffer
+	if err := journal.autoComplete.SaveTo(&snapshot); err != nil {
+		return err
+	}
+	err := SaveFile(journal.fileName, func(writer io.Writer) error {
+		return writeRecord(writer, journalRecord{Op: journalSnapshot, Snapshot: snapshot.Bytes()})
+	})
+	if err != nil {
+		return err
+	}
+
+	log, err := os.OpenFile(journal.fileName, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	journal.log.Close()
+	journal.log = log
+	journal.changes = 0
+	journal.dirty = false
+	return nil
+}
+
+// Close stops the periodic syncing started by NewJournal, syncs the log and closes it. The Journal must not be used
+// afterwards.
+func (journal *Journal) Close() error {
+
+	if journal.stop != nil {
+		close(journal.stop)
+		<-journal.done
+		journal.stop = nil
+	}
+
+	journal.lock.Lock()
+	defer journal.lock.Unlock()
+	journal.dirty = true
+	if err := journal.sync(); err != nil {
+		journal.log.Close()
+		return err
+	}
+	return journal.log.Close()
+}
+
+// Accept : see description in AutoComplete interface
+func (journal *Journal) Accept(acceptedWord string) error {
+	return journal.change(journalRecord{Op: journalAccept, Word: acceptedWord})
+}
+
+// Learn : see description in AutoComplete interface
+func (journal *Journal) Learn(word string, keys ...string) error {
+	return journal.change(journalRecord{Op: journalLearn, Word: word, Keys: keys})
+}
+
+// LearnWithPayload : see description in AutoComplete interface. Payloads are logged with encoding/gob, as Save saves
+// them.
+func (journal *Journal) LearnWithPayload(word string, payload interface{}, keys ...string) error {
+	return journal.change(journalRecord{Op: journalLearnWithPayload, Word: word, Payload: payload, Keys: keys})
+}
+
+// SetWeight : see description in AutoComplete interface
+func (journal *Journal) SetWeight(word string, weight float64) error {
+	return journal.change(journalRecord{Op: journalSetWeight, Word: word, Weight: weight})
+}
+
+// UnLearn : see description in AutoComplete interface
+func (journal *Journal) UnLearn(word string) error {
+	return journal.change(journalRecord{Op: journalUnLearn, Word: word})
+}
+
+// AcceptSequence : see description in AutoComplete interface
+func (journal *Journal) AcceptSequence(words []string) error {
+	return journal.change(journalRecord{Op: journalAcceptSequence, Words: words})
+}
+
+// Predict : see description in AutoComplete interface
+func (journal *Journal) Predict(previousWords []string, stem string) ([]string, error) {
+	return journal.autoComplete.Predict(previousWords, stem)
+}
+
+// Complete : see description in AutoComplete interface
+func (journal *Journal) Complete(word string) ([]string, error) {
+	return journal.autoComplete.Complete(word)
+}
+
+// CompleteWith : see description in AutoComplete interface
+func (journal *Journal) CompleteWith(stem string, opts CompleteOptions) ([]string, error) {
+	return journal.autoComplete.CompleteWith(stem, opts)
+}
+
+// CompleteFuzzy : see description in AutoComplete interface
+func (journal *Journal) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	return journal.autoComplete.CompleteFuzzy(stem, maxEdits)
+}
+
+// CompleteScored : see description in AutoComplete interface
+func (journal *Journal) CompleteScored(stem string, opts CompleteOptions) ([]Result, error) {
+	return journal.autoComplete.CompleteScored(stem, opts)
+}
+
+// CompleteQuery : see description in AutoComplete interface
+func (journal *Journal) CompleteQuery(query string) ([]string, error) {
+	return journal.autoComplete.CompleteQuery(query)
+}
+
+// Save : see description in AutoComplete interface
+func (journal *Journal) Save(fileName string) error {
+	return journal.autoComplete.Save(fileName)
+}
+
+// SaveTo : see description in AutoComplete interface
+func (journal *Journal) SaveTo(writer io.Writer) error {
+	return journal.autoComplete.SaveTo(writer)
+}
+
+// Retrieve : see description in AutoComplete interface. The log is compacted afterwards.
+func (journal *Journal) Retrieve(fileName string) error {
+	return retrieveFile(fileName, journal.RetrieveFrom)
+}
+
+// RetrieveFrom : see description in AutoComplete interface. The log is compacted afterwards.
+func (journal *Journal) RetrieveFrom(reader io.Reader) error {
+
+	if err := journal.autoComplete.RetrieveFrom(reader); err != nil {
+		return err
+	}
+	return journal.Compact()
+}
@@ -0,0 +1,185 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Log("Given the need to keep what an autocompleter learns across crashes")
+	{
+		for name, autoComplete := range saveEngines() {
+			fileName := dir + "/" + name
+			journal, err := NewJournal(autoComplete, fileName, JournalOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal.Accept("yam")
+			journal.Learn("yarn")
+			journal.UnLearn("yes")
+			logged, _ := os.Stat(fileName)
+			if journal.Learn("yak") == nil {
+				t.Fatal("Should be able to report changes that do not apply on a "+name+" autocompleter", ballotX)
+			}
+			if unchanged, _ := os.Stat(fileName); unchanged.Size() != logged.Size() {
+				t.Fatal("Should be able to cut changes that do not apply from the log on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to cut changes that do not apply from the log on a "+name+" autocompleter", checkMark)
+			journal.Close()
+
+			journal, err = NewJournal(saveEngines()[name], fileName, JournalOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ac, _ := journal.Complete("y")
+			if !reflect.DeepEqual(ac, []string{"yam", "yak", "yarn"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to replay a journal on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to replay a journal on a "+name+" autocompleter", checkMark)
+			journal.Close()
+
+			whole, _ := ioutil.ReadFile(fileName)
+			torn, _ := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND, 0644)
+			torn.Write(whole[:12])
+			torn.Close()
+			journal, err = NewJournal(saveEngines()[name], fileName, JournalOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ac, _ = journal.Complete("y")
+			recovered, _ := ioutil.ReadFile(fileName)
+			if !reflect.DeepEqual(ac, []string{"yam", "yak", "yarn"}) || len(recovered) != len(whole) {
+				t.Log(ac, len(recovered), len(whole))
+				t.Fatal("Should be able to cut away a torn last record on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to cut away a torn last record on a "+name+" autocompleter", checkMark)
+
+			journal.Accept("yak")
+			journal.Close()
+			corrupted, _ := ioutil.ReadFile(fileName)
+			corrupted[10] ^= 0xff
+			ioutil.WriteFile(fileName, corrupted, 0644)
+			if _, err = NewJournal(saveEngines()[name], fileName, JournalOptions{}); err == nil {
+				t.Fatal("Should not be able to replay a journal corrupted before its last record on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should not be able to replay a journal corrupted before its last record on a "+name+" autocompleter", checkMark)
+
+			corrupted[10] ^= 0xff
+			corrupted[0] ^= 0x7f
+			ioutil.WriteFile(fileName, corrupted, 0644)
+			if _, err = NewJournal(saveEngines()[name], fileName, JournalOptions{}); err == nil {
+				t.Fatal("Should not be able to replay a journal with a corrupted record length before its last record on a "+name+" autocompleter", ballotX)
+			}
+			if unchanged, _ := ioutil.ReadFile(fileName); len(unchanged) != len(corrupted) {
+				t.Fatal("Should not be able to replay a journal with a corrupted record length before its last record on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should not be able to replay a journal with a corrupted record length before its last record on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to keep journals small")
+	{
+		for name, autoComplete := range saveEngines() {
+			fileName := dir + "/compacted-" + name
+			journal, err := NewJournal(autoComplete, fileName, JournalOptions{Sync: SyncPeriodically, SyncInterval: time.Millisecond, CompactAfter: 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				journal.Accept("yes")
+			}
+			journal.Learn("yarn")
+			if journal.changes != 0 {
+				t.Log(journal.changes)
+				t.Fatal("Should be able to compact a journal after a number of changes on a "+name+" autocompleter", ballotX)
+			}
+			journal.Accept("yarn")
+			journal.Close()
+
+			journal, err = NewJournal(saveEngines()[name], fileName, JournalOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, _ := journal.CompleteScored("y", CompleteOptions{})
+			if len(results) != 4 || results[0].Word != "yes" || results[0].Accepts != 5 || results[1].Word != "yarn" || journal.changes != 1 {
+				t.Log(results, journal.changes)
+				t.Fatal("Should be able to replay a compacted journal on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to replay a compacted journal on a "+name+" autocompleter", checkMark)
+
+			if err = journal.Compact(); err != nil {
+				t.Fatal(err)
+			}
+			journal.Close()
+			journal, _ = NewJournal(saveEngines()[name], fileName, JournalOptions{})
+			results, _ = journal.CompleteScored("y", CompleteOptions{})
+			if len(results) != 4 || results[1].Word != "yarn" || results[1].Accepts != 1 || journal.changes != 0 {
+				t.Log(results, journal.changes)
+				t.Fatal("Should be able to compact a journal on demand on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to compact a journal on demand on a "+name+" autocompleter", checkMark)
+			journal.Close()
+		}
+	}
+
+	t.Log("Given the need to replay changes as made when they were logged")
+	{
+		now := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		options := []Option{WithHalfLife(time.Hour), WithClock(func() time.Time { return now })}
+		for name := range rankEngines() {
+			start := now
+			fileName := dir + "/timed-" + name
+			journal, err := NewJournal(rankEngines(options...)[name], fileName, JournalOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			now = start.Add(100 * time.Minute)
+			journal.Learn("chairwoman")
+			journal.Accept("chairs")
+			journal.Close()
+
+			other := rankEngines(options...)[name]
+			now = start.Add(50 * time.Minute)
+			other.Learn("chairwoman")
+			now = start.Add(150 * time.Minute)
+			other.UnLearn("chairwoman")
+
+			now = start.Add(160 * time.Minute)
+			journal, err = NewJournal(rankEngines(options...)[name], fileName, JournalOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, _ := journal.CompleteScored("chairs", CompleteOptions{})
+			if len(results) != 1 || results[0].Score != 0.5 {
+				t.Log(results)
+				t.Fatal("Should be able to replay accepts as made when they were logged on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to replay accepts as made when they were logged on a "+name+" autocompleter", checkMark)
+
+			if err = Merge(journal, MergeOptions{Learn: LastWriterWins}, other); err != nil {
+				t.Fatal(err)
+			}
+			if ac, _ := journal.Complete("chairw"); len(ac) != 0 {
+				t.Log(ac)
+				t.Fatal("Should be able to replay words learnt as learnt when they were logged on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to replay words learnt as learnt when they were logged on a "+name+" autocompleter", checkMark)
+			journal.Close()
+			now = start
+		}
+	}
+}
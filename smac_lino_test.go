@@ -341,7 +341,11 @@ func TestLinoSaveAndRetrieve(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		dec := gob.NewDecoder(f)
+		_, body, err := readSaveHeader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec := gob.NewDecoder(body)
 		readWords := make(map[string]int)
 		for {
 			var wA wordAccepts
@@ -455,6 +459,70 @@ func TestLinoComplete(t *testing.T) {
 
 }
 
+func TestLinoCompleteFuzzy(t *testing.T) {
+
+	words := []string{"chai", "chain", "chair", "chairman", "chairperson", "chalk", "cheer", "table"}
+	autoComplete, _ := NewAutoCompleteLinoS(words, 2, 0, 0)
+
+	t.Log("Given the need to test the fuzzy completion feature")
+	{
+		ac, err := autoComplete.CompleteFuzzy("chair", 0)
+		if err != nil || !reflect.DeepEqual(ac, []string{"chair", "chairman", "chairperson"}) {
+			t.Fatal("Should be able to complete exactly with no edits", ballotX)
+		}
+		t.Log("Should be able to complete exactly with no edits", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("chiar", 1)
+		if !reflect.DeepEqual(ac, []string{"chair", "chairman", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a stem with transposed runes", ballotX)
+		}
+		t.Log("Should be able to complete a stem with transposed runes", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("chaim", 1)
+		if !reflect.DeepEqual(ac, []string{"chai", "chain", "chair", "chairman", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a stem with a wrong rune", ballotX)
+		}
+		t.Log("Should be able to complete a stem with a wrong rune", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("chairm", 1)
+		if !reflect.DeepEqual(ac, []string{"chairman", "chair", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to rank closer matches first", ballotX)
+		}
+		t.Log("Should be able to rank closer matches first", checkMark)
+
+		autoComplete.Accept("chairperson")
+		ac, _ = autoComplete.CompleteFuzzy("chairm", 1)
+		if !reflect.DeepEqual(ac, []string{"chairman", "chairperson", "chair"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to prioritize accepted words within the same distance", ballotX)
+		}
+		t.Log("Should be able to prioritize accepted words within the same distance", checkMark)
+
+		autoComplete.Learn("chimney")
+		ac, _ = autoComplete.CompleteFuzzy("chimnye", 1)
+		if !reflect.DeepEqual(ac, []string{"chimney"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete learnt words", ballotX)
+		}
+		t.Log("Should be able to complete learnt words", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("xyz", 1)
+		if !reflect.DeepEqual(ac, []string{}) {
+			t.Fatal("Should be able to return no completions for a distant stem", ballotX)
+		}
+		t.Log("Should be able to return no completions for a distant stem", checkMark)
+
+		_, err = autoComplete.CompleteFuzzy("chair", -1)
+		if err == nil {
+			t.Fatal("Should be able to reject a negative number of edits", ballotX)
+		}
+		t.Log("Should be able to reject a negative number of edits", checkMark)
+	}
+}
+
 func TestLinoE(t *testing.T) {
 
 	autoComplete, _ := NewAutoCompleteLinoE(2, 0, 0)
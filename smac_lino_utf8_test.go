@@ -0,0 +1,151 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// multilingualWords is a corpus of words sharing multi-byte prefixes, so that byte and rune prefixes disagree.
+var multilingualWords = []string{
+	// Russian
+	"абажур", "абзац", "абонент", "абрикос", "ёж", "ёлка", "жёлтый", "жёсткий", "жук", "журнал", "язык", "яблоко", "яблоня",
+	// Greek
+	"αγάπη", "αγγελία", "αγορά", "άγαλμα", "ελιά", "ελπίδα", "ωκεανός", "ώρα", "ωραίος",
+	// accented Latin
+	"café", "cafés", "caféine", "château", "châteaux", "élève", "élan", "éléphant", "über", "übung", "ähnlich", "ärger",
+	// mixed scripts and plain ASCII
+	"abc", "abcé", "abcя", "z", "zé", "zя", "zα",
+}
+
+// checkLinoCoherence verifies that the word list, the prefix map and completions of autoComplete match what a freshly
+// built autocompleter on dictionary would have.
+func checkLinoCoherence(t *testing.T, autoComplete *AutoCompleteLiNo, dictionary map[string]bool, step string) {
+
+	var sorted []string
+	for word := range dictionary {
+		sorted = append(sorted, word)
+	}
+	sort.Strings(sorted)
+
+	var listed []string
+	for word := autoComplete.head; word != ""; word = autoComplete.wordMap[word].next {
+		listed = append(listed, word)
+	}
+	if len(sorted) == 0 {
+		sorted = nil
+	}
+	if !reflect.DeepEqual(listed, sorted) {
+		t.Log(listed)
+		t.Fatal("Should be able to keep the word list sorted after "+step, ballotX)
+	}
+
+	prefixes := makePrefixMap(sorted, autoComplete.prefixMapDepth)
+	if !reflect.DeepEqual(autoComplete.prefixMap, prefixes) {
+		t.Log(autoComplete.prefixMap, prefixes)
+		t.Fatal("Should be able to keep the prefix map coherent after "+step, ballotX)
+	}
+	for prefix := range autoComplete.prefixMap {
+		if !utf8.ValidString(prefix) {
+			t.Fatal("Should be able to keep only whole-rune prefixes after "+step, ballotX)
+		}
+	}
+
+	for _, word := range sorted {
+		runes := []rune(word)
+		for depth := 1; depth <= len(runes); depth++ {
+			stem := string(runes[:depth])
+			var expected []string
+			for _, candidate := range sorted {
+				if strings.HasPrefix(candidate, stem) && len(expected) < autoComplete.resultSize {
+					expected = append(expected, candidate)
+				}
+			}
+			completes, _ := autoComplete.Complete(stem)
+			if !reflect.DeepEqual(completes, expected) {
+				t.Log(stem, completes, expected)
+				t.Fatal("Should be able to complete every rune prefix after "+step, ballotX)
+			}
+		}
+	}
+}
+
+func TestLinoUTF8Construction(t *testing.T) {
+
+	t.Log("Given the need to test a LiNo on a multilingual dictionary")
+	{
+		for depth := uint(1); depth <= 4; depth++ {
+			words := append([]string{}, multilingualWords...)
+			autoComplete, _ := NewAutoCompleteLinoS(words, depth, 0, 0)
+			dictionary := make(map[string]bool)
+			for _, w := range multilingualWords {
+				dictionary[w] = true
+			}
+			checkLinoCoherence(t, &autoComplete, dictionary, "construction")
+		}
+		t.Log("Should be able to build a multilingual LiNo", checkMark)
+
+		autoComplete, _ := NewAutoCompleteLinoS(append([]string{}, multilingualWords...), 1, 0, 0)
+		if _, exists := autoComplete.prefixMap["а"]; !exists || len(autoComplete.prefixMap["а"]) == 1 {
+			t.Fatal("Should be able to measure prefix depth in runes", ballotX)
+		}
+		ac, _ := autoComplete.Complete("жё")
+		if !reflect.DeepEqual(ac, []string{"жёлтый", "жёсткий"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to back off a stem by whole runes", ballotX)
+		}
+		t.Log("Should be able to back off a stem by whole runes", checkMark)
+	}
+}
+
+func TestLinoUTF8LearnUnLearn(t *testing.T) {
+
+	t.Log("Given the need to test Learn/UnLearn/Complete interleavings on a multilingual dictionary")
+	{
+		random := rand.New(rand.NewSource(42))
+
+		for depth := uint(1); depth <= 4; depth++ {
+			initial := multilingualWords[:len(multilingualWords)/2]
+			autoComplete, _ := NewAutoCompleteLinoS(append([]string{}, initial...), depth, 0, 0)
+			dictionary := make(map[string]bool)
+			for _, w := range initial {
+				dictionary[w] = true
+			}
+
+			for i := 0; i < 200; i++ {
+				word := multilingualWords[random.Intn(len(multilingualWords))]
+				if dictionary[word] {
+					if err := autoComplete.UnLearn(word); err != nil {
+						t.Fatal(err)
+					}
+					delete(dictionary, word)
+					checkLinoCoherence(t, &autoComplete, dictionary, "unlearning "+word)
+				} else {
+					if err := autoComplete.Learn(word); err != nil {
+						t.Fatal(err)
+					}
+					dictionary[word] = true
+					checkLinoCoherence(t, &autoComplete, dictionary, "learning "+word)
+				}
+			}
+		}
+		t.Log("Should be able to keep a multilingual LiNo coherent across Learn and UnLearn", checkMark)
+
+		autoComplete, _ := NewAutoCompleteLinoE(3, 0, 0)
+		for i := len(multilingualWords) - 1; i >= 0; i-- {
+			autoComplete.Learn(multilingualWords[i])
+		}
+		dictionary := make(map[string]bool)
+		for _, w := range multilingualWords {
+			dictionary[w] = true
+		}
+		checkLinoCoherence(t, &autoComplete, dictionary, "learning from scratch")
+		t.Log("Should be able to learn a multilingual dictionary from scratch", checkMark)
+	}
+}
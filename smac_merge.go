@@ -0,0 +1,347 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// AcceptsPolicy tells Merge how to combine the accept counts a word has in several sources.
+type AcceptsPolicy int
+
+const (
+	// SumAccepts adds up the accept counts, for sources that accepted words independently of each other. The accepts of
+	// a source merged twice are counted twice.
+	SumAccepts AcceptsPolicy = iota
+	// MaxAccepts keeps the highest of the accept counts, for sources that share a history: merging a source twice
+	// changes nothing.
+	MaxAccepts
+)
+
+// LearnPolicy tells Merge whether a word learnt by a source and unlearnt by another is kept.
+type LearnPolicy int
+
+const (
+	// LastWriterWins keeps the change made last, by the time each source learnt or unlearnt the word. A change whose time
+	// is not known (save files written before times were kept, words of the dictionary that were only accepted) counts as
+	// made before any whose time is known, so that a timed unlearn beats an untimed learn. Changes made at the same time,
+	// or both at unknown times, are won by the last of the sources.
+	LastWriterWins LearnPolicy = iota
+	// LearnWins keeps the word if any of the sources has it.
+	LearnWins
+	// UnLearnWins removes the word if any of the sources unlearnt it.
+	UnLearnWins
+)
+
+// MergeOptions are the conflict policies of Merge.
+type MergeOptions struct {
+	Accepts AcceptsPolicy
+	Learn   LearnPolicy
+}
+
+// Merge merges into autoComplete what sources have learnt: the words they have learnt and unlearnt, their accept counts
+// and the counts of their n-gram models, resolving conflicts with options. autoComplete is itself the first of the
+// sources. Payloads, weights, keys and decayed accept counts are the ones of the last of the sources having them.
+func Merge(autoComplete AutoComplete, options MergeOptions, sources ...AutoComplete) error {
+
+	saves := make([]io.Reader, len(sources))
+	for i, source := range sources {
+		var save bytes.Buffer
+		if err := source.SaveTo(&save); err != nil {
+			return err
+		}
+		saves[i] = &save
+	}
+	return MergeFrom(autoComplete, options, saves...)
+}
+
+// MergeFrom is like Merge, with sources the save files read from readers.
+func MergeFrom(autoComplete AutoComplete, options MergeOptions, readers ...io.Reader) error {
+
+	var save bytes.Buffer
+	if err := autoComplete.SaveTo(&save); err != nil {
+		return err
+	}
+	merger := newMerger(options)
+	if _, err := merger.read(&save); err != nil {
+		return err
+	}
+	for _, reader := range readers {
+		if _, err := merger.read(reader); err != nil {
+			return err
+		}
+	}
+
+	var body bytes.Buffer
+	if err := merger.write(&body); err != nil {
+		return err
+	}
+	return autoComplete.RetrieveFrom(&body)
+}
+
+// MergeSaves merges the save files read from readers (see Merge) into a save file written to writer, for Retrieve to
+// read. The save files must have been written by the same engine, with the same normalizer; save files without header
+// merge into one without header.
+func MergeSaves(writer io.Writer, options MergeOptions, readers ...io.Reader) error {
+
+	merger := newMerger(options)
+	var merged saveHeader
+	for _, reader := range readers {
+		header, err := merger.read(reader)
+		if err != nil {
+			return err
+		}
+		switch {
+		case header.Engine == "":
+		case merged.Engine == "":
+			merged = header
+		case header.Engine != merged.Engine:
+			return errors.New("Cannot merge the save files of a " + merged.Engine + " and of a " + header.Engine + " autocompleter")
+		default:
+			if err = header.checkConfiguration(merged); err != nil {
+				return err
+			}
+			if merged.Normalizer == "" {
+				merged.Normalizer = header.Normalizer
+			}
+			for _, r := range header.Alphabet {
+				if !strings.ContainsRune(merged.Alphabet, r) {
+					merged.Alphabet += string(r)
+				}
+			}
+		}
+	}
+
+	if merged.Engine == "" {
+		return merger.write(writer)
+	}
+	return writeSave(writer, merged, merger.write)
+}
+
+// merger merges the records of save files.
+type merger struct {
+	options MergeOptions
+	// words holds the records of the words merged, and grams the ones of the n-gram model, in the order they were first
+	// read. They are found by word, and by context and word.
+	words     []*mergedWord
+	wordIndex map[string]int
+	grams     []wordAccepts
+	gramIndex map[string]int
+	// source counts the save files read.
+	source int
+}
+
+// mergedWord is a word of several sources: learnt the latest at learntAt by source learntBy, and unlearnt the latest at
+// unlearntAt by source unlearntBy.
+type mergedWord struct {
+	wordAccepts
+	learnt, unlearnt     bool
+	learntAt, unlearntAt time.Time
+	learntBy, unlearntBy int
+}
+
+func newMerger(options MergeOptions) *merger {
+	return &merger{
+		options:   options,
+		wordIndex: make(map[string]int),
+		gramIndex: make(map[string]int),
+	}
+}
+
+// read merges the records of the save file read from reader, and returns its header.
+func (m *merger) read(reader io.Reader) (saveHeader, error) {
+
+	header, body, err := readSaveHeader(reader)
+	if err != nil {
+		return header, err
+	}
+	m.source++
+	dec := gob.NewDecoder(body)
+	for {
+		var wA wordAccepts
+		if err = dec.Decode(&wA); err == io.EOF {
+			break
+		} else if err != nil {
+			return header, err
+		}
+		if len(wA.Context) > 0 {
+			m.gram(wA)
+		} else {
+			m.word(wA)
+		}
+	}
+	return header, nil
+}
+
+func (m *merger) accepts(merged, accepts int) int {
+
+	if m.options.Accepts == MaxAccepts {
+		if accepts > merged {
+			return accepts
+		}
+		return merged
+	}
+	return merged + accepts
+}
+
+func (m *merger) gram(wA wordAccepts) {
+
+	key := contextKey(wA.Context) + "\x00\x00" + wA.Word
+	if i, exists := m.gramIndex[key]; exists {
+		m.grams[i].Accepts = m.accepts(m.grams[i].Accepts, wA.Accepts)
+		return
+	}
+	m.gramIndex[key] = len(m.grams)
+	m.grams = append(m.grams, wA)
+}
+
+func (m *merger) word(wA wordAccepts) {
+
+	i, exists := m.wordIndex[wA.Word]
+	if !exists {
+		i = len(m.words)
+		m.wordIndex[wA.Word] = i
+		m.words = append(m.words, &mergedWord{wordAccepts: wordAccepts{Word: wA.Word}})
+	}
+	merged := m.words[i]
+
+	// sources are read in order, so that a later source wins at the same time: !Before rather than After.
+	if wA.Accepts < 0 {
+		if !merged.unlearnt || !wA.Changed.Before(merged.unlearntAt) {
+			merged.unlearntAt, merged.unlearntBy = wA.Changed, m.source
+		}
+		merged.unlearnt = true
+		return
+	}
+	if !merged.learnt || !wA.Changed.Before(merged.learntAt) {
+		merged.learntAt, merged.learntBy = wA.Changed, m.source
+	}
+	merged.learnt = true
+	merged.Accepts = m.accepts(merged.Accepts, wA.Accepts)
+	merged.New = merged.New || wA.New
+	if wA.Payload != nil {
+		merged.Payload = wA.Payload
+	}
+	if wA.Weight != nil {
+		merged.Weight = wA.Weight
+	}
+	if len(wA.Keys) > 0 {
+		merged.Keys = wA.Keys
+	}
+	if wA.Decay != nil {
+		merged.Decay = wA.Decay
+	}
+}
+
+// removed tells whether merged is removed by the policy of m.
+func (m *merger) removed(merged *mergedWord) bool {
+
+	if !merged.learnt || !merged.unlearnt {
+		return merged.unlearnt
+	}
+	switch m.options.Learn {
+	case LearnWins:
+		return false
+	case UnLearnWins:
+		return true
+	}
+	if merged.unlearntAt.Equal(merged.learntAt) {
+		return merged.unlearntBy > merged.learntBy
+	}
+	return merged.unlearntAt.After(merged.learntAt)
+}
+
+// write writes the records merged as the body of a save file.
+func (m *merger) write(writer io.Writer) error {
+
+	enc := &wordEncoder{enc: gob.NewEncoder(writer)}
+	for _, merged := range m.words {
+		if m.removed(merged) {
+			enc.encode(wordAccepts{Word: merged.Word, Accepts: -1, Changed: merged.unlearntAt})
+			continue
+		}
+		wA := merged.wordAccepts
+		wA.Changed = merged.learntAt
+		enc.encode(wA)
+	}
+	for _, gram := range m.grams {
+		enc.encode(gram)
+	}
+	return enc.err
+}
+
+// changes keeps when the words of an autocompleter were last learnt or unlearnt, for Merge to tell which of two
+// conflicting changes came last.
+type changes struct {
+	clock func() time.Time
+	at    map[string]time.Time
+}
+
+func newChanges(cfg config) changes {
+
+	clock := cfg.clock
+	if clock == nil {
+		clock = time.Now
+	}
+	return changes{
+		clock: clock,
+		at:    make(map[string]time.Time),
+	}
+}
+
+// record records that word was learnt or unlearnt now, in UTC and without the monotonic clock reading, as save files keep
+// it.
+func (c *changes) record(word string) {
+	c.set(word, c.clock().UTC())
+}
+
+// get returns when word was last learnt or unlearnt, the zero time if it is not known.
+func (c *changes) get(word string) time.Time {
+	return c.at[word]
+}
+
+// set sets when word was last learnt or unlearnt, as retrieved.
+func (c *changes) set(word string, at time.Time) {
+
+	if c.at == nil {
+		c.at = make(map[string]time.Time)
+	}
+	if at.IsZero() {
+		delete(c.at, word)
+		return
+	}
+	c.at[word] = at
+}
+
+// save writes to enc the times of the words enc has not written: the words of the dictionary learnt again after being
+// unlearnt, for which isWord is true, and the words learnt and then unlearnt.
+func (c *changes) save(enc *wordEncoder, isWord func(word string) bool) {
+
+	for word, at := range c.at {
+		if enc.words[word] {
+			continue
+		}
+		if isWord(word) {
+			enc.encode(wordAccepts{Word: word, Changed: at})
+		} else {
+			enc.encode(wordAccepts{Word: word, Accepts: -1, Changed: at})
+		}
+	}
+}
+
+// copy returns a copy of c, to be modified by a fork.
+func (c changes) copy() changes {
+
+	copied := c
+	copied.at = make(map[string]time.Time, len(c.at))
+	for word, at := range c.at {
+		copied.at[word] = at
+	}
+	return copied
+}
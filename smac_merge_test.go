@@ -0,0 +1,145 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mergeEngines returns the engines of two nodes that learnt and accepted words independently, reading the time from
+// clock.
+func mergeEngines(clock *time.Time) (map[string]AutoComplete, map[string]AutoComplete) {
+
+	words := []string{"yak", "yam", "yes"}
+	engines := func() map[string]AutoComplete {
+		withClock := WithClock(func() time.Time { return *clock })
+		lino, _ := NewAutoCompleteLinoS(words, 2, 0, 0, withClock)
+		trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz", words, 0, 0, withClock)
+		radix, _ := NewAutoCompleteRadixS(words, 0, 0, withClock)
+		automaton, _ := NewAutoCompleteFSTS(words, 0, 0, withClock)
+		return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}
+	}
+	first, second := engines(), engines()
+
+	start := *clock
+	for name := range first {
+		*clock = start.Add(time.Hour)
+		first[name].Learn("yarn")
+		second[name].Learn("yarn")
+		second[name].Learn("yawn")
+		first[name].Accept("yam")
+		first[name].Accept("yam")
+		for i := 0; i < 3; i++ {
+			second[name].Accept("yam")
+		}
+		second[name].Accept("yes")
+		first[name].AcceptSequence([]string{"yak", "yam"})
+		second[name].AcceptSequence([]string{"yak", "yam"})
+
+		*clock = start.Add(2 * time.Hour)
+		second[name].UnLearn("yarn")
+		*clock = start.Add(3 * time.Hour)
+		first[name].UnLearn("yes")
+	}
+	*clock = start
+	return first, second
+}
+
+func TestMerge(t *testing.T) {
+
+	clock := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Log("Given the need to merge what several autocompleters have learnt")
+	{
+		merges := []struct {
+			name     string
+			options  MergeOptions
+			complete []string
+			accepts  int
+		}{
+			{"adding up accepts and keeping the last change", MergeOptions{}, []string{"yam", "yak", "yawn"}, 5},
+			{"keeping the highest accepts and the words learnt", MergeOptions{Accepts: MaxAccepts, Learn: LearnWins}, []string{"yam", "yes", "yak", "yarn", "yawn"}, 3},
+			{"keeping the words unlearnt", MergeOptions{Learn: UnLearnWins}, []string{"yam", "yak", "yawn"}, 5},
+		}
+		for _, merge := range merges {
+			first, second := mergeEngines(&clock)
+			for name, autoComplete := range first {
+				if err := Merge(autoComplete, merge.options, second[name]); err != nil {
+					t.Fatal(err)
+				}
+				ac, _ := autoComplete.Complete("y")
+				results, _ := autoComplete.CompleteScored("yam", CompleteOptions{})
+				if !reflect.DeepEqual(ac, merge.complete) || results[0].Accepts != merge.accepts {
+					t.Log(ac, results)
+					t.Fatal("Should be able to merge "+merge.name+" into a "+name+" autocompleter", ballotX)
+				}
+				t.Log("Should be able to merge "+merge.name+" into a "+name+" autocompleter", checkMark)
+			}
+		}
+
+		first, second := mergeEngines(&clock)
+		for name, autoComplete := range first {
+			Merge(autoComplete, MergeOptions{Accepts: MaxAccepts}, second[name])
+			Merge(autoComplete, MergeOptions{Accepts: MaxAccepts}, second[name])
+			results, _ := autoComplete.CompleteScored("yam", CompleteOptions{})
+			predicted, _ := autoComplete.Predict([]string{"yak"}, "")
+			if results[0].Accepts != 3 || len(predicted) == 0 || predicted[0] != "yam" {
+				t.Log(results, predicted)
+				t.Fatal("Should be able to merge the same autocompleter twice into a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to merge the same autocompleter twice into a "+name+" autocompleter", checkMark)
+		}
+
+		first, second = mergeEngines(&clock)
+		for name, autoComplete := range first {
+			clock = clock.Add(4 * time.Hour)
+			autoComplete.UnLearn("yarn")
+			clock = clock.Add(time.Hour)
+			autoComplete.Learn("yarn")
+			clock = clock.Add(-5 * time.Hour)
+			Merge(autoComplete, MergeOptions{}, second[name])
+			ac, _ := autoComplete.Complete("y")
+			if !reflect.DeepEqual(ac, []string{"yam", "yak", "yarn", "yawn"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to keep a word learnt again after it was unlearnt in a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to keep a word learnt again after it was unlearnt in a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to merge save files offline")
+	{
+		first, second := mergeEngines(&clock)
+		for name, autoComplete := range first {
+			var firstSave, secondSave, merged bytes.Buffer
+			autoComplete.SaveTo(&firstSave)
+			second[name].SaveTo(&secondSave)
+			if err := MergeSaves(&merged, MergeOptions{}, &firstSave, &secondSave); err != nil {
+				t.Fatal(err)
+			}
+			retrieved := saveEngines()[name]
+			if err := retrieved.RetrieveFrom(&merged); err != nil {
+				t.Fatal(err)
+			}
+			ac, _ := retrieved.Complete("y")
+			results, _ := retrieved.CompleteScored("yam", CompleteOptions{})
+			if !reflect.DeepEqual(ac, []string{"yam", "yak", "yawn"}) || results[0].Accepts != 5 {
+				t.Log(ac, results)
+				t.Fatal("Should be able to merge the save files of a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to merge the save files of a "+name+" autocompleter", checkMark)
+		}
+
+		var lino, trie bytes.Buffer
+		first["LiNo"].SaveTo(&lino)
+		first["trie"].SaveTo(&trie)
+		if MergeSaves(&bytes.Buffer{}, MergeOptions{}, &lino, &trie) == nil {
+			t.Fatal("Should not be able to merge the save files of different engines", ballotX)
+		}
+		t.Log("Should not be able to merge the save files of different engines", checkMark)
+	}
+}
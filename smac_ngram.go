@@ -0,0 +1,229 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// backoff is the factor the probability of a word after the last word only is discounted by, when the last two words
+// have not been seen followed by it ("stupid backoff").
+const backoff = 0.4
+
+// nGrams counts the words seen after each word (bigrams) and after each pair of words (trigrams) in accepted
+// sequences. Contexts are keyed by their normalized words; following words are kept in the form they were accepted in.
+type nGrams struct {
+	counts map[string]map[string]int
+	totals map[string]int
+}
+
+// contextKey returns the key of the normalized words of context.
+func contextKey(context []string) string {
+	return strings.Join(context, "\x00")
+}
+
+// add adds count to the times word was seen after context.
+func (grams *nGrams) add(context []string, word string, count int) {
+
+	if grams.counts == nil {
+		grams.counts = make(map[string]map[string]int)
+		grams.totals = make(map[string]int)
+	}
+	key := contextKey(context)
+	if grams.counts[key] == nil {
+		grams.counts[key] = make(map[string]int)
+	}
+	grams.counts[key][word] += count
+	grams.totals[key] += count
+}
+
+// accept counts the bigrams and trigrams of sequence.
+func (grams *nGrams) accept(normalizer Normalizer, sequence []string) error {
+
+	if len(sequence) < 2 {
+		return errors.New("Sequence shorter than two words")
+	}
+	keys := make([]string, len(sequence))
+	for i, word := range sequence {
+		if keys[i] = normalizer.Normalize(word); keys[i] == "" {
+			return errors.New("Empty word")
+		}
+	}
+	for i := 1; i < len(sequence); i++ {
+		grams.add(keys[i-1:i], sequence[i], 1)
+		if i > 1 {
+			grams.add(keys[i-2:i], sequence[i], 1)
+		}
+	}
+	return nil
+}
+
+// probabilities returns the predictions of the words seen after context whose key starts with stem, keyed by the key of
+// their word: forms of the same word add up, and the one seen most often stands for them.
+func (grams *nGrams) probabilities(normalizer Normalizer, context []string, stem string) map[string]prediction {
+
+	probabilities := make(map[string]prediction)
+	forms := make(map[string]int)
+	total := float64(grams.totals[contextKey(context)])
+	for word, count := range grams.counts[contextKey(context)] {
+		key := normalizer.Normalize(word)
+		if !strings.HasPrefix(key, stem) {
+			continue
+		}
+		p := probabilities[key]
+		if count > forms[key] || count == forms[key] && word < p.word {
+			p.word, forms[key] = word, count
+		}
+		p.probability += float64(count) / total
+		probabilities[key] = p
+	}
+	return probabilities
+}
+
+type prediction struct {
+	word        string
+	probability float64
+}
+
+type predictions []prediction
+
+func (p predictions) Len() int { return len(p) }
+func (p predictions) Less(i, j int) bool {
+	if p[i].probability != p[j].probability {
+		return p[i].probability > p[j].probability
+	}
+	return p[i].word < p[j].word
+}
+func (p predictions) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+// predict returns up to resultSize words completing stem after previousWords: the words seen after the last two of
+// previousWords first, then the ones seen after the last one, by probability, then the completions of autoComplete.
+// Words are told apart by their key, so that a word seen in several forms is predicted once.
+func (grams *nGrams) predict(autoComplete AutoComplete, normalizer Normalizer, resultSize int, previousWords []string, stem string) ([]string, error) {
+
+	var context []string
+	for _, word := range previousWords {
+		context = append(context, normalizer.Normalize(word))
+	}
+	if len(context) > 2 {
+		context = context[len(context)-2:]
+	}
+	key := normalizer.Normalize(stem)
+
+	scores := make(map[string]prediction)
+	if len(context) == 2 {
+		scores = grams.probabilities(normalizer, context, key)
+	}
+	if len(context) > 0 {
+		for wordKey, p := range grams.probabilities(normalizer, context[len(context)-1:], key) {
+			if _, seen := scores[wordKey]; !seen {
+				p.probability *= backoff
+				scores[wordKey] = p
+			}
+		}
+	}
+	ranked := make(predictions, 0, len(scores))
+	for _, p := range scores {
+		ranked = append(ranked, p)
+	}
+	sort.Sort(ranked)
+
+	words := []string{}
+	for _, p := range ranked {
+		if len(words) == resultSize {
+			return words, nil
+		}
+		words = append(words, p.word)
+	}
+	completions, err := autoComplete.Complete(stem)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range completions {
+		if len(words) == resultSize {
+			break
+		}
+		if _, seen := scores[normalizer.Normalize(word)]; !seen {
+			words = append(words, word)
+		}
+	}
+	return words, nil
+}
+
+// copy returns a copy of grams, to be modified by a fork.
+func (grams nGrams) copy() nGrams {
+
+	copied := nGrams{
+		counts: make(map[string]map[string]int, len(grams.counts)),
+		totals: make(map[string]int, len(grams.totals)),
+	}
+	for key, words := range grams.counts {
+		copied.counts[key] = make(map[string]int, len(words))
+		for word, count := range words {
+			copied.counts[key][word] = count
+		}
+		copied.totals[key] = grams.totals[key]
+	}
+	return copied
+}
+
+// save writes the counts of grams to enc, as records holding their context.
+func (grams *nGrams) save(enc *wordEncoder) {
+
+	for key, words := range grams.counts {
+		context := strings.Split(key, "\x00")
+		for word, count := range words {
+			enc.encode(wordAccepts{
+				Word:    word,
+				Accepts: count,
+				Context: context,
+			})
+		}
+	}
+}
+
+// retrieve restores the count of wA, a record written by save.
+func (grams *nGrams) retrieve(wA wordAccepts) {
+
+	if grams.counts != nil {
+		if count, exists := grams.counts[contextKey(wA.Context)][wA.Word]; exists {
+			grams.add(wA.Context, wA.Word, -count)
+		}
+	}
+	grams.add(wA.Context, wA.Word, wA.Accepts)
+}
+
+// maxCorpusLine is the longest line AcceptCorpus reads.
+const maxCorpusLine = 64 * 1024 * 1024
+
+// AcceptCorpus teaches the n-gram model of autoComplete (see AcceptSequence) the sequences of words of a text corpus.
+// Sentences end at line breaks and at '.', '!', '?' and ';'; words are made of letters, digits, apostrophes and
+// hyphens. Lines are at most maxCorpusLine bytes long.
+func AcceptCorpus(autoComplete AutoComplete, corpus io.Reader) error {
+
+	lineScanner := bufio.NewScanner(corpus)
+	lineScanner.Buffer(make([]byte, bufio.MaxScanTokenSize), maxCorpusLine)
+	for lineScanner.Scan() {
+		sentences := strings.FieldsFunc(lineScanner.Text(), func(r rune) bool {
+			return r == '.' || r == '!' || r == '?' || r == ';'
+		})
+		for _, sentence := range sentences {
+			words := strings.FieldsFunc(sentence, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+			})
+			if len(words) < 2 {
+				continue
+			}
+			if err := autoComplete.AcceptSequence(words); err != nil {
+				return err
+			}
+		}
+	}
+	return lineScanner.Err()
+}
@@ -0,0 +1,136 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func nGramEngines(options ...Option) map[string]AutoComplete {
+
+	words := []string{"new", "york", "yorkshire", "jersey", "year", "you", "your", "happy"}
+	lino, _ := NewAutoCompleteLinoS(words, 2, 0, 0, options...)
+	trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz", words, 0, 0, options...)
+	radix, _ := NewAutoCompleteRadixS(words, 0, 0, options...)
+	automaton, _ := NewAutoCompleteFSTS(words, 0, 0, options...)
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}
+}
+
+func TestPredict(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Log("Given the need to predict the next word")
+	{
+		retrieved := nGramEngines()
+		for name, autoComplete := range nGramEngines() {
+			autoComplete.AcceptSequence([]string{"new", "york"})
+			autoComplete.AcceptSequence([]string{"new", "york"})
+			autoComplete.AcceptSequence([]string{"new", "jersey"})
+			autoComplete.AcceptSequence([]string{"happy", "new", "year"})
+
+			predicted, _ := autoComplete.Predict([]string{"new"}, "")
+			if len(predicted) < 3 || !reflect.DeepEqual(predicted[:3], []string{"york", "jersey", "year"}) {
+				t.Log(predicted)
+				t.Fatal("Should be able to predict the words seen after the last word on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to predict the words seen after the last word on a "+name+" autocompleter", checkMark)
+
+			predicted, _ = autoComplete.Predict([]string{"a", "happy", "new"}, "y")
+			if len(predicted) != 5 || !reflect.DeepEqual(predicted[:2], []string{"year", "york"}) {
+				t.Log(predicted)
+				t.Fatal("Should be able to predict the words seen after the last two words first on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to predict the words seen after the last two words first on a "+name+" autocompleter", checkMark)
+
+			predicted, _ = autoComplete.Predict([]string{"old"}, "yo")
+			completed, _ := autoComplete.Complete("yo")
+			if !reflect.DeepEqual(predicted, completed) {
+				t.Log(predicted, completed)
+				t.Fatal("Should be able to fall back to completion on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to fall back to completion on a "+name+" autocompleter", checkMark)
+
+			if autoComplete.AcceptSequence([]string{"new"}) == nil {
+				t.Fatal("Should not be able to accept a sequence of a single word on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should not be able to accept a sequence of a single word on a "+name+" autocompleter", checkMark)
+
+			saveFile := dir + "/" + name
+			if err = autoComplete.Save(saveFile); err != nil {
+				t.Fatal(err)
+			}
+			if err = retrieved[name].Retrieve(saveFile); err != nil {
+				t.Fatal(err)
+			}
+			if err = retrieved[name].Retrieve(saveFile); err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := autoComplete.Predict([]string{"happy", "new"}, "")
+			predicted, _ = retrieved[name].Predict([]string{"happy", "new"}, "")
+			if !reflect.DeepEqual(predicted, expected) {
+				t.Log(predicted, expected)
+				t.Fatal("Should be able to save and retrieve the n-gram model on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to save and retrieve the n-gram model on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to learn the n-gram model from a corpus")
+	{
+		for name, autoComplete := range nGramEngines() {
+			if err = AcceptCorpus(autoComplete, strings.NewReader("The new york times; happy new year!\nnew.")); err != nil {
+				t.Fatal(err)
+			}
+			predicted, _ := autoComplete.Predict([]string{"new"}, "y")
+			if len(predicted) < 2 || !reflect.DeepEqual(predicted[:2], []string{"year", "york"}) {
+				t.Log(predicted)
+				t.Fatal("Should be able to learn the n-gram model from a corpus on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to learn the n-gram model from a corpus on a "+name+" autocompleter", checkMark)
+
+			long := strings.Repeat("happy jersey ", 10000) + "happy you"
+			if err = AcceptCorpus(autoComplete, strings.NewReader(long)); err != nil {
+				t.Fatal(err)
+			}
+			if predicted, _ = autoComplete.Predict([]string{"happy"}, "y"); len(predicted) == 0 || predicted[0] != "you" {
+				t.Log(predicted)
+				t.Fatal("Should be able to learn the n-gram model from a corpus with lines longer than 64 KB on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to learn the n-gram model from a corpus with lines longer than 64 KB on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to predict a word seen in several forms once")
+	{
+		for name, autoComplete := range nGramEngines(WithNormalizer(FoldingNormalizer{})) {
+			autoComplete.AcceptSequence([]string{"happy", "New"})
+			autoComplete.AcceptSequence([]string{"happy", "new"})
+			autoComplete.AcceptSequence([]string{"happy", "new"})
+			autoComplete.AcceptSequence([]string{"happy", "year"})
+			autoComplete.AcceptSequence([]string{"happy", "year"})
+
+			predicted, _ := autoComplete.Predict([]string{"happy"}, "")
+			if len(predicted) < 2 || !reflect.DeepEqual(predicted[:2], []string{"new", "year"}) {
+				t.Log(predicted)
+				t.Fatal("Should be able to predict a word seen in several forms once, by the count of all of them, on a "+name+" autocompleter", ballotX)
+			}
+			for _, word := range predicted[2:] {
+				if strings.ToLower(word) == "new" || word == "year" {
+					t.Log(predicted)
+					t.Fatal("Should be able to predict a word seen in several forms once, by the count of all of them, on a "+name+" autocompleter", ballotX)
+				}
+			}
+			t.Log("Should be able to predict a word seen in several forms once, by the count of all of them, on a "+name+" autocompleter", checkMark)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type identityNormalizer struct{}
+
+func (identityNormalizer) Normalize(word string) string {
+	return word
+}
+
+// IdentityNormalizer leaves words as they are: completion is case- and accent-sensitive. It is the default normalizer.
+var IdentityNormalizer Normalizer = identityNormalizer{}
+
+// FoldingNormalizer makes completion case- and accent-insensitive: words are decomposed (NFKD), stripped of their
+// diacritics, lowercased and recomposed (NFC), so that "munc" matches "München" and "ﬁne" matches "fine".
+type FoldingNormalizer struct {
+	// Special maps runes to their folded form before any other rule is applied, for locale-specific folding. For Turkish,
+	// {'I': "ı", 'İ': "i"} keeps dotted and dotless i apart.
+	Special map[rune]string
+}
+
+// letters that carry a diacritic but have no canonical decomposition, or that fold to more than one letter.
+var foldedLetters = map[rune]string{
+	'ß': "ss",
+	'ẞ': "ss",
+	'æ': "ae",
+	'Æ': "ae",
+	'œ': "oe",
+	'Œ': "oe",
+	'ø': "o",
+	'Ø': "o",
+	'đ': "d",
+	'Đ': "d",
+	'ħ': "h",
+	'Ħ': "h",
+	'ł': "l",
+	'Ł': "l",
+	'þ': "th",
+	'Þ': "th",
+}
+
+// Normalize : see description in Normalizer interface
+func (normalizer FoldingNormalizer) Normalize(word string) string {
+
+	if len(normalizer.Special) > 0 {
+		var special []rune
+		for _, r := range word {
+			if folded, exists := normalizer.Special[r]; exists {
+				special = append(special, []rune(folded)...)
+			} else {
+				special = append(special, r)
+			}
+		}
+		word = string(special)
+	}
+
+	var folded []rune
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if letters, exists := foldedLetters[r]; exists {
+			folded = append(folded, []rune(letters)...)
+			continue
+		}
+		folded = append(folded, unicode.ToLower(r))
+	}
+	return norm.NFC.String(string(folded))
+}
+
+// normalizerName identifies normalizer in save files and indexes, so that what was learnt with a normalizer is not
+// retrieved with another: normalizers of the same type and configuration have the same name.
+func normalizerName(normalizer Normalizer) string {
+
+	name := fmt.Sprintf("%T", normalizer)
+	if folding, isFolding := normalizer.(FoldingNormalizer); isFolding && len(folding.Special) > 0 {
+		var special []string
+		for r, folded := range folding.Special {
+			special = append(special, string(r)+"="+folded)
+		}
+		sort.Strings(special)
+		name += fmt.Sprint(special)
+	}
+	return name
+}
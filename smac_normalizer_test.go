@@ -0,0 +1,194 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFoldingNormalizer(t *testing.T) {
+
+	t.Log("Given the need to test the folding normalizer")
+	{
+		normalizer := FoldingNormalizer{}
+		cases := map[string]string{
+			"München":        "munchen",
+			"Mu\u0308nchen":  "munchen",
+			"iPhone":         "iphone",
+			"Straße":         "strasse",
+			"ﬁne":            "fine",
+			"Ελλάδα":         "ελλαδα",
+			"Łódź":           "lodz",
+			"already folded": "already folded",
+			"ＦＵＬＬＷＩＤＴＨ":      "fullwidth",
+		}
+		for word, key := range cases {
+			if normalizer.Normalize(word) != key {
+				t.Fatal("Should be able to fold "+word, ballotX)
+			}
+		}
+		t.Log("Should be able to fold case, diacritics and compatibility forms", checkMark)
+
+		turkish := FoldingNormalizer{
+			Special: map[rune]string{'I': "ı", 'İ': "i"},
+		}
+		if turkish.Normalize("İstanbul") != "istanbul" || turkish.Normalize("ILIK") != "ılık" {
+			t.Fatal("Should be able to apply a locale-specific folding", ballotX)
+		}
+		t.Log("Should be able to apply a locale-specific folding", checkMark)
+		if normalizer.Normalize("İstanbul") != "istanbul" || normalizer.Normalize("ILIK") != "ilik" {
+			t.Fatal("Should be able to fold dotted and dotless i by default", ballotX)
+		}
+		t.Log("Should be able to fold dotted and dotless i by default", checkMark)
+
+		sameTurkish := FoldingNormalizer{Special: map[rune]string{'İ': "i", 'I': "ı"}}
+		if normalizerName(turkish) != normalizerName(sameTurkish) || normalizerName(turkish) == normalizerName(normalizer) || normalizerName(normalizer) == normalizerName(IdentityNormalizer) {
+			t.Log(normalizerName(turkish), normalizerName(normalizer))
+			t.Fatal("Should be able to tell folding normalizers apart by their locale-specific folding", ballotX)
+		}
+		t.Log("Should be able to tell folding normalizers apart by their locale-specific folding", checkMark)
+	}
+}
+
+func TestLinoNormalization(t *testing.T) {
+
+	words := []string{"München", "Munich", "iPhone", "IPHONE", "zebra"}
+	autoComplete, _ := NewAutoCompleteLinoS(words, 2, 0, 0, WithNormalizer(FoldingNormalizer{}))
+
+	t.Log("Given the need to test case- and diacritic-insensitive completion")
+	{
+		ac, _ := autoComplete.Complete("munc")
+		if !reflect.DeepEqual(ac, []string{"München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a word in its stored form", ballotX)
+		}
+		t.Log("Should be able to complete a word in its stored form", checkMark)
+
+		ac, _ = autoComplete.Complete("IPH")
+		if !reflect.DeepEqual(ac, []string{"iPhone", "IPHONE"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete all the forms sharing a key", ballotX)
+		}
+		t.Log("Should be able to complete all the forms sharing a key", checkMark)
+
+		if err := autoComplete.Learn("Iphone"); err != nil {
+			t.Fatal("Should be able to learn a new form of a key", ballotX)
+		}
+		if err := autoComplete.Learn("iPhone"); err == nil {
+			t.Fatal("Should be able to reject a form already learnt", ballotX)
+		}
+		t.Log("Should be able to learn new forms of a key", checkMark)
+
+		autoComplete.Accept("munchen")
+		autoComplete.Accept("MUNCHEN")
+		ac, _ = autoComplete.Complete("mu")
+		if !reflect.DeepEqual(ac, []string{"München", "Munich"}) || autoComplete.wordMap["munchen"].accepts != 2 {
+			t.Log(ac)
+			t.Fatal("Should be able to accept a word by any of its forms", ballotX)
+		}
+		t.Log("Should be able to accept a word by any of its forms", checkMark)
+
+		autoComplete.UnLearn("IPHONE")
+		ac, _ = autoComplete.Complete("iphone")
+		if !reflect.DeepEqual(ac, []string{"iPhone", "Iphone"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to unlearn a single form of a key", ballotX)
+		}
+		t.Log("Should be able to unlearn a single form of a key", checkMark)
+
+		autoComplete.UnLearn("munich")
+		ac, _ = autoComplete.Complete("mu")
+		if !reflect.DeepEqual(ac, []string{"München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to unlearn a word by its key", ballotX)
+		}
+		t.Log("Should be able to unlearn a word by its key", checkMark)
+
+		tempFile, err := ioutil.TempFile(os.TempDir(), "smac")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tempFile.Name())
+		if err = autoComplete.Save(tempFile.Name()); err != nil {
+			t.Fatal(err)
+		}
+		autoComplete, _ = NewAutoCompleteLinoS(words, 2, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		if err = autoComplete.Retrieve(tempFile.Name()); err != nil {
+			t.Fatal(err)
+		}
+		ac, _ = autoComplete.Complete("i")
+		ac2, _ := autoComplete.Complete("m")
+		if !reflect.DeepEqual(ac, []string{"iPhone", "Iphone"}) || !reflect.DeepEqual(ac2, []string{"München"}) ||
+			autoComplete.wordMap["munchen"].accepts != 2 {
+			t.Log(ac, ac2)
+			t.Fatal("Should be able to save and retrieve normalized words", ballotX)
+		}
+		t.Log("Should be able to save and retrieve normalized words", checkMark)
+	}
+}
+
+func TestTrieNormalization(t *testing.T) {
+
+	words := []string{"München", "Munich", "iPhone", "IPHONE", "zebra"}
+	autoComplete, err := NewAutoCompleteTrieS(alphabet, words, 0, 0, WithNormalizer(FoldingNormalizer{}))
+	if err != nil {
+		t.Fatal("Should be able to build a trie on normalized words", ballotX)
+	}
+
+	t.Log("Given the need to test case- and diacritic-insensitive completion")
+	{
+		ac, _ := autoComplete.Complete("MÜNC")
+		if !reflect.DeepEqual(ac, []string{"München"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a word in its stored form", ballotX)
+		}
+		t.Log("Should be able to complete a word in its stored form", checkMark)
+
+		ac, _ = autoComplete.Complete("iph")
+		if !reflect.DeepEqual(ac, []string{"iPhone", "IPHONE"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete all the forms sharing a key", ballotX)
+		}
+		t.Log("Should be able to complete all the forms sharing a key", checkMark)
+
+		if err := autoComplete.Learn("IPHONE"); err == nil {
+			t.Fatal("Should be able to reject a form already learnt", ballotX)
+		}
+		t.Log("Should be able to reject a form already learnt", checkMark)
+
+		autoComplete.Accept("munchen")
+		autoComplete.UnLearn("IPHONE")
+		autoComplete.UnLearn("munich")
+		ac, _ = autoComplete.Complete("mu")
+		ac2, _ := autoComplete.Complete("i")
+		if !reflect.DeepEqual(ac, []string{"München"}) || !reflect.DeepEqual(ac2, []string{"iPhone"}) {
+			t.Log(ac, ac2)
+			t.Fatal("Should be able to unlearn forms and keys", ballotX)
+		}
+		t.Log("Should be able to unlearn forms and keys", checkMark)
+
+		tempFile, err := ioutil.TempFile(os.TempDir(), "smac")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tempFile.Name())
+		if err = autoComplete.Save(tempFile.Name()); err != nil {
+			t.Fatal(err)
+		}
+		autoComplete, _ = NewAutoCompleteTrieS(alphabet, words, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		if err = autoComplete.Retrieve(tempFile.Name()); err != nil {
+			t.Fatal(err)
+		}
+		ac, _ = autoComplete.Complete("m")
+		ac2, _ = autoComplete.Complete("i")
+		if !reflect.DeepEqual(ac, []string{"München"}) || !reflect.DeepEqual(ac2, []string{"iPhone"}) {
+			t.Log(ac, ac2)
+			t.Fatal("Should be able to save and retrieve normalized words", ballotX)
+		}
+		t.Log("Should be able to save and retrieve normalized words", checkMark)
+	}
+}
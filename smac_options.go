@@ -0,0 +1,182 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Option customizes an autocompleter at construction time. Options are passed as trailing arguments to the constructors.
+type Option func(*config)
+
+type config struct {
+	normalizer       Normalizer
+	tokenizer        Tokenizer
+	ranker           Ranker
+	payloadSeparator string
+	weightSeparator  string
+	csv              bool
+	commentPrefix    string
+	skipBlankLines   bool
+	halfLife         time.Duration
+	clock            func() time.Time
+}
+
+func newConfig(options []Option) config {
+
+	cfg := config{
+		normalizer: IdentityNormalizer,
+		ranker:     AcceptsRanker,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// WithNormalizer makes an autocompleter match words by the key normalizer maps them to. The same normalizer is applied
+// to stems and to the words passed to Learn, UnLearn, Accept and Retrieve.
+func WithNormalizer(normalizer Normalizer) Option {
+	return func(cfg *config) {
+		cfg.normalizer = normalizer
+	}
+}
+
+// WithTokenizer makes an autocompleter match the words with more than one token from each of their tokens too, so that
+// "york" completes to "New York". Such a word is an entry (see Learn) matched by its own key and by its tokens from each
+// one but the first on, joined by spaces ("york"); the words matched from their start are ranked above the ones matched
+// from a token. Words learnt with keys of their own are not tokenized.
+func WithTokenizer(tokenizer Tokenizer) Option {
+	return func(cfg *config) {
+		cfg.tokenizer = tokenizer
+	}
+}
+
+// WithRanker makes an autocompleter order the words found for a stem by the score ranker gives them, instead of by
+// their accept count (see AcceptsRanker).
+func WithRanker(ranker Ranker) Option {
+	return func(cfg *config) {
+		cfg.ranker = ranker
+	}
+}
+
+// WithHalfLife makes an autocompleter rank accepted words by an accept count that halves every halfLife, so that the
+// words accepted lately beat the ones that were popular long ago. Save stores the decayed counts with the time they were
+// last accepted at, so that they keep decaying across restarts.
+func WithHalfLife(halfLife time.Duration) Option {
+	return func(cfg *config) {
+		cfg.halfLife = halfLife
+	}
+}
+
+// WithClock makes an autocompleter read the time from clock instead of time.Now, for accept counts to decay (see
+// WithHalfLife) deterministically in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
+
+// WithPayloadSeparator makes the constructors reading a dictionary file read a record per line: the word up to the first
+// occurrence of separator, and the rest of the line as its payload, a string. Lines without separator hold a word
+// without payload.
+func WithPayloadSeparator(separator string) Option {
+	return func(cfg *config) {
+		cfg.payloadSeparator = separator
+	}
+}
+
+// WithWeightSeparator makes the constructors reading a dictionary file read the weight of each word (see Record) at the
+// end of its line, after the last occurrence of separator: "the\t23135851162" with a separator of "\t". Lines without
+// separator hold a word without weight. With WithPayloadSeparator too, the payload comes between word and weight.
+func WithWeightSeparator(separator string) Option {
+	return func(cfg *config) {
+		cfg.weightSeparator = separator
+	}
+}
+
+// WithCSV makes the constructors reading a dictionary file read it as CSV (RFC 4180): the first field of each record is
+// the word, the second one, if any, its weight (see Record), and the third one, if any, its payload, a string.
+func WithCSV() Option {
+	return func(cfg *config) {
+		cfg.csv = true
+	}
+}
+
+// WithCommentPrefix makes the constructors reading a dictionary skip the lines starting with prefix ("#"), or with WithCSV
+// the records whose word does.
+func WithCommentPrefix(prefix string) Option {
+	return func(cfg *config) {
+		cfg.commentPrefix = prefix
+	}
+}
+
+// WithSkipBlankLines makes the constructors reading a dictionary skip the lines made only of white space, instead of
+// failing on them with an "Empty word in dictionary" error.
+func WithSkipBlankLines() Option {
+	return func(cfg *config) {
+		cfg.skipBlankLines = true
+	}
+}
+
+// isComment reports whether line is a comment in a dictionary.
+func (cfg config) isComment(line string) bool {
+	return cfg.commentPrefix != "" && strings.HasPrefix(line, cfg.commentPrefix)
+}
+
+// record splits a line of a dictionary file into a record.
+func (cfg config) record(line string) (Record, error) {
+
+	var weight string
+	if cfg.weightSeparator != "" {
+		if i := strings.LastIndex(line, cfg.weightSeparator); i >= 0 {
+			line, weight = line[:i], line[i+len(cfg.weightSeparator):]
+		}
+	}
+	record := Record{
+		Word: line,
+	}
+	if cfg.payloadSeparator != "" {
+		if i := strings.Index(line, cfg.payloadSeparator); i >= 0 {
+			record.Word = line[:i]
+			record.Payload = line[i+len(cfg.payloadSeparator):]
+		}
+	}
+	var err error
+	record.Weight, err = parseWeight(weight)
+	return record, err
+}
+
+// csvRecord returns the record of the fields of a line of a CSV dictionary file.
+func (cfg config) csvRecord(fields []string) (Record, error) {
+
+	var record Record
+	var err error
+	switch {
+	case len(fields) > 2:
+		record.Payload = fields[2]
+		fallthrough
+	case len(fields) > 1:
+		record.Weight, err = parseWeight(fields[1])
+		fallthrough
+	default:
+		record.Word = fields[0]
+	}
+	return record, err
+}
+
+func parseWeight(weight string) (float64, error) {
+
+	if weight = strings.TrimSpace(weight); weight == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseFloat(weight, 64)
+	if err != nil {
+		return 0, errors.New("Invalid weight in dictionary: " + weight)
+	}
+	return parsed, nil
+}
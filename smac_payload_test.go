@@ -0,0 +1,106 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var payloadRecords = []Record{{Word: "chair", Payload: "C1"}, {Word: "chairman", Payload: 2}, {Word: "table"}}
+
+func payloadEngines() map[string]AutoComplete {
+
+	lino, _ := NewAutoCompleteLinoP(payloadRecords, 2, 0, 0)
+	trie, _ := NewAutoCompleteTrieP("abcdefghijklmnopqrstuvwxyz", payloadRecords, 0, 0)
+	radix, _ := NewAutoCompleteRadixP(payloadRecords, 0, 0)
+	automaton, _ := NewAutoCompleteFSTP(payloadRecords, 0, 0)
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}
+}
+
+// payloads returns the payloads of the completions of stems by word.
+func payloads(autoComplete AutoComplete, stems ...string) map[string]interface{} {
+
+	payloads := make(map[string]interface{})
+	for _, stem := range stems {
+		results, _ := autoComplete.CompleteScored(stem, CompleteOptions{})
+		for _, result := range results {
+			payloads[result.Word] = result.Payload
+		}
+	}
+	return payloads
+}
+
+func TestPayloads(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Log("Given the need to attach payloads to words")
+	{
+		retrieved := payloadEngines()
+		for name, autoComplete := range payloadEngines() {
+			p := payloads(autoComplete, "c", "t")
+			if len(p) != 3 || p["chair"] != "C1" || p["chairman"] != 2 || p["table"] != nil {
+				t.Log(p)
+				t.Fatal("Should be able to load payloads with a dictionary on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to load payloads with a dictionary on a "+name+" autocompleter", checkMark)
+
+			autoComplete.LearnWithPayload("chairs", "C2")
+			autoComplete.LearnWithPayload("table", "T")
+			autoComplete.Accept("table")
+			p = payloads(autoComplete, "c", "t")
+			if p["chairs"] != "C2" || p["table"] != "T" || p["chair"] != "C1" {
+				t.Log(p)
+				t.Fatal("Should be able to learn payloads on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to learn payloads on a "+name+" autocompleter", checkMark)
+
+			saveFile := dir + "/" + name
+			if err = autoComplete.Save(saveFile); err != nil {
+				t.Fatal(err)
+			}
+			if err = retrieved[name].Retrieve(saveFile); err != nil {
+				t.Fatal(err)
+			}
+			p = payloads(retrieved[name], "c", "t")
+			if len(p) != 4 || p["chairs"] != "C2" || p["table"] != "T" || p["chair"] != "C1" || p["chairman"] != 2 {
+				t.Log(p)
+				t.Fatal("Should be able to save and retrieve payloads on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to save and retrieve payloads on a "+name+" autocompleter", checkMark)
+
+			if err = autoComplete.LearnWithPayload("chairs", struct{}{}); err != nil {
+				t.Fatal(err)
+			}
+			if autoComplete.Save(saveFile) == nil {
+				t.Fatal("Should be able to report payloads that cannot be saved on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to report payloads that cannot be saved on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to read payloads from a dictionary file")
+	{
+		dictionary := dir + "/records.txt"
+		ioutil.WriteFile(dictionary, []byte("chair\tC1\tchairs\ntable"), 0644)
+		lino, _ := NewAutoCompleteLinoF(dictionary, 2, 0, 0, WithPayloadSeparator("\t"))
+		trie, _ := NewAutoCompleteTrieF("abcdefghijklmnopqrstuvwxyz", dictionary, 0, 0, WithPayloadSeparator("\t"))
+		radix, _ := NewAutoCompleteRadixF(dictionary, 0, 0, WithPayloadSeparator("\t"))
+		automaton, _ := NewAutoCompleteFSTF(dictionary, 0, 0, WithPayloadSeparator("\t"))
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			p := payloads(autoComplete, "c", "t")
+			if len(p) != 2 || p["chair"] != "C1\tchairs" || p["table"] != nil {
+				t.Log(p)
+				t.Fatal("Should be able to read a record per line on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to read a record per line on a "+name+" autocompleter", checkMark)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// completeQuery completes query for autoComplete, whose words are phrases tokenized by tokenizer and normalized by
+// normalizer (see CompleteQuery). Phrases are stored under a key from each of their tokens on (see WithTokenizer), so
+// that all the ones holding a token of query are the completions of that token: the phrases are looked up from the
+// longest token typed, all of its completions being scanned, and the ones holding the other tokens of query are ranked
+// by the score the Ranker of autoComplete gives them.
+//
+// Without a tokenizer, phrases are only found from their start: query is completed as a whole instead.
+func completeQuery(autoComplete AutoComplete, tokenizer Tokenizer, normalizer Normalizer, resultSize int, query string) ([]string, error) {
+
+	split := tokenizer
+	if split == nil {
+		split = BoundaryTokenizer
+	}
+	tokens := split.Tokenize(query)
+	prefix := ""
+	if len(tokens) > 0 && strings.HasSuffix(query, tokens[len(tokens)-1]) {
+		prefix = normalizer.Normalize(tokens[len(tokens)-1])
+		tokens = tokens[:len(tokens)-1]
+	}
+	for i, token := range tokens {
+		tokens[i] = normalizer.Normalize(token)
+	}
+
+	stem := prefix
+	opts := CompleteOptions{}
+	if tokenizer == nil {
+		stem = normalizer.Normalize(query)
+	} else if len(tokens) > 0 {
+		stem = tokens[0]
+		for _, token := range tokens[1:] {
+			if utf8.RuneCountInString(token) > utf8.RuneCountInString(stem) {
+				stem = token
+			}
+		}
+		opts = CompleteOptions{Limit: math.MaxInt32, Radius: math.MaxInt32}
+	}
+
+	results, err := autoComplete.CompleteScored(stem, opts)
+	if err != nil {
+		return nil, err
+	}
+	phrases := newTopHits(resultSize)
+	for _, result := range results {
+		if queryMatches(split, normalizer, result.Word, tokens, prefix) {
+			phrases.insert(&wordHit{
+				word:   result.Word,
+				weight: result.Score,
+			})
+		}
+	}
+	list := phrases.list()
+	return list.flush(), nil
+}
+
+// queryMatches reports whether each of tokens is a token of phrase, and prefix, if not empty, is the prefix of one more.
+func queryMatches(tokenizer Tokenizer, normalizer Normalizer, phrase string, tokens []string, prefix string) bool {
+
+	phraseTokens := tokenizer.Tokenize(phrase)
+	for i, token := range phraseTokens {
+		phraseTokens[i] = normalizer.Normalize(token)
+	}
+	used := make([]bool, len(phraseTokens))
+
+	for _, token := range tokens {
+		matched := false
+		for i, phraseToken := range phraseTokens {
+			if !used[i] && phraseToken == token {
+				used[i], matched = true, true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if prefix == "" {
+		return true
+	}
+	for i, phraseToken := range phraseTokens {
+		if !used[i] && strings.HasPrefix(phraseToken, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompleteQuery(t *testing.T) {
+
+	phrases := []string{"red running shoes", "blue running shoes", "red dress", "running shorts", "red running shorts"}
+	options := []Option{WithNormalizer(FoldingNormalizer{}), WithTokenizer(BoundaryTokenizer)}
+	lino, _ := NewAutoCompleteLinoS(phrases, 2, 0, 0, options...)
+	trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz ", phrases, 0, 20, options...)
+	radix, _ := NewAutoCompleteRadixS(phrases, 0, 20, options...)
+	automaton, _ := NewAutoCompleteFSTS(phrases, 0, 20, options...)
+
+	// sorted returns the completions of query, sorted.
+	sorted := func(autoComplete AutoComplete, query string) []string {
+		ac, _ := autoComplete.CompleteQuery(query)
+		sort.Strings(ac)
+		return ac
+	}
+
+	red := []string{"red running shoes", "red running shorts"}
+
+	t.Log("Given the need to complete queries of several tokens")
+	{
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			if ac := sorted(autoComplete, "red running sh"); !reflect.DeepEqual(ac, red) {
+				t.Log(ac)
+				t.Fatal("Should be able to complete the last token of a query filtering by the others on a "+name+" autocompleter", ballotX)
+			}
+			if ac := sorted(autoComplete, "Running RED sh"); !reflect.DeepEqual(ac, red) {
+				t.Log(ac)
+				t.Fatal("Should be able to complete the tokens of a query in any order on a "+name+" autocompleter", ballotX)
+			}
+			if ac := sorted(autoComplete, "red r"); !reflect.DeepEqual(ac, red) {
+				t.Log(ac)
+				t.Fatal("Should be able to not complete a token of a query with another on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete the last token of a query filtering by the others on a "+name+" autocompleter", checkMark)
+
+			if ac := sorted(autoComplete, "red "); len(ac) != 3 || ac[0] != "red dress" {
+				t.Log(ac)
+				t.Fatal("Should be able to complete a query with no token being typed on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete a query with no token being typed on a "+name+" autocompleter", checkMark)
+
+			autoComplete.Accept("red running shorts")
+			if ac, _ := autoComplete.CompleteQuery("red running sh"); len(ac) != 2 || ac[0] != "red running shorts" {
+				t.Log(ac)
+				t.Fatal("Should be able to rank accepted phrases first on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank accepted phrases first on a "+name+" autocompleter", checkMark)
+		}
+
+		lino, _ = NewAutoCompleteLinoS(phrases, 2, 0, 0)
+		trie, _ = NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz ", phrases, 0, 20)
+		radix, _ = NewAutoCompleteRadixS(phrases, 0, 20)
+		automaton, _ = NewAutoCompleteFSTS(phrases, 0, 20)
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			if ac := sorted(autoComplete, "red running sh"); !reflect.DeepEqual(ac, red) {
+				t.Log(ac)
+				t.Fatal("Should be able to complete a query as a whole without a tokenizer on a "+name+" autocompleter", ballotX)
+			}
+			if ac := sorted(autoComplete, "red "); !reflect.DeepEqual(ac, []string{"red dress", "red running shoes", "red running shorts"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to complete a query as a whole without a tokenizer on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete a query as a whole without a tokenizer on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to complete queries among many phrases")
+	{
+		many := append([]string{}, phrases...)
+		for r := 'a'; r < 'u'; r++ {
+			many = append(many, "blue sh"+string(r)+"rt")
+		}
+		longest := RankerFunc(func(candidate Candidate) float64 {
+			return float64(candidate.Length)
+		})
+		options := []Option{WithNormalizer(FoldingNormalizer{}), WithTokenizer(BoundaryTokenizer), WithRanker(longest)}
+		lino, _ := NewAutoCompleteLinoS(many, 2, 0, 0, options...)
+		trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz ", many, 0, 20, options...)
+		radix, _ := NewAutoCompleteRadixS(many, 0, 20, options...)
+		automaton, _ := NewAutoCompleteFSTS(many, 0, 20, options...)
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			ac, _ := autoComplete.CompleteQuery("red running sh")
+			if !reflect.DeepEqual(ac, []string{"red running shorts", "red running shoes"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to find the phrases of a query past the radius and rank them by the ranker on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to find the phrases of a query past the radius and rank them by the ranker on a "+name+" autocompleter", checkMark)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRadixConstruction(t *testing.T) {
+
+	t.Log("Given the need to test the radix tree construction")
+	{
+		autoComplete, _ := NewAutoCompleteRadixS([]string{"chair"}, 0, 0)
+		if len(autoComplete.root.links) != 1 || autoComplete.root.links[0].label != "chair" {
+			t.Fatal("Should be able to store a word on a single edge", ballotX)
+		}
+		t.Log("Should be able to store a word on a single edge", checkMark)
+
+		autoComplete.Learn("chain")
+		split := autoComplete.root.links[0]
+		if split.label != "chai" || split.word != nil || len(split.links) != 2 ||
+			split.links[0].label != "n" || split.links[1].label != "r" {
+			t.Fatal("Should be able to split an edge", ballotX)
+		}
+		t.Log("Should be able to split an edge", checkMark)
+
+		autoComplete.Learn("chai")
+		if split.word == nil || len(split.links) != 2 {
+			t.Fatal("Should be able to make a word of a split node", ballotX)
+		}
+		t.Log("Should be able to make a word of a split node", checkMark)
+
+		autoComplete.Learn("ch")
+		if autoComplete.root.links[0].label != "ch" || autoComplete.root.links[0].links[0] != split || split.label != "ai" {
+			t.Fatal("Should be able to split an edge above a word", ballotX)
+		}
+		t.Log("Should be able to split an edge above a word", checkMark)
+
+		autoComplete.UnLearn("chain")
+		autoComplete.UnLearn("chai")
+		if split.label != "air" || split.word == nil || len(split.links) != 0 {
+			t.Fatal("Should be able to merge an edge left with a single child", ballotX)
+		}
+		t.Log("Should be able to merge an edge left with a single child", checkMark)
+
+		autoComplete.UnLearn("ch")
+		if len(autoComplete.root.links) != 1 || autoComplete.root.links[0].label != "chair" {
+			t.Fatal("Should be able to merge an edge back to a single word", ballotX)
+		}
+		t.Log("Should be able to merge an edge back to a single word", checkMark)
+
+		autoComplete.UnLearn("chair")
+		if len(autoComplete.root.links) != 0 {
+			t.Fatal("Should be able to unlearn the whole tree", ballotX)
+		}
+		t.Log("Should be able to unlearn the whole tree", checkMark)
+
+		autoComplete, _ = NewAutoCompleteRadixS([]string{"абзац", "абажур", "a"}, 0, 0)
+		ac, _ := autoComplete.Complete("аб")
+		if !reflect.DeepEqual(ac, []string{"абзац", "абажур"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to split edges on non-ASCII runes", ballotX)
+		}
+		t.Log("Should be able to split edges on non-ASCII runes", checkMark)
+
+		if _, err := NewAutoCompleteRadixS([]string{"a", ""}, 0, 0); err == nil {
+			t.Fatal("Should be able to reject an empty word", ballotX)
+		}
+		t.Log("Should be able to reject an empty word", checkMark)
+	}
+}
+
+func TestRadixCompletion(t *testing.T) {
+
+	words := []string{"aaa", "aaab", "aaac", "aaabbb", "aaad", "abbbbb"}
+	autoComplete, _ := NewAutoCompleteRadixS(words, 0, 0)
+
+	t.Log("Given the need to test the completion feature")
+	{
+		ac, _ := autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaab", "aaac", "aaad", "aaabbb"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to autocomplete on a stem word by length and then by alphabetical order", ballotX)
+		}
+		ac, _ = autoComplete.Complete("aa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaab", "aaac", "aaad", "aaabbb"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to autocomplete on a stem ending mid-edge", ballotX)
+		}
+		t.Log("Should be able to autocomplete on a stem word by length and then by alphabetical order", checkMark)
+
+		ac, _ = autoComplete.Complete("aaax")
+		if !reflect.DeepEqual(ac, []string{}) {
+			t.Fatal("Should be able to return no completions for an unknown stem", ballotX)
+		}
+		t.Log("Should be able to return no completions for an unknown stem", checkMark)
+
+		autoComplete.Accept("aaad")
+		autoComplete.Accept("aaad")
+		autoComplete.Accept("aaabbb")
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaad", "aaabbb", "aaa", "aaab", "aaac"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to prioritize accepted words", ballotX)
+		}
+		t.Log("Should be able to prioritize accepted words", checkMark)
+		if !reflect.DeepEqual(*plainWord, radixWord{}) || autoComplete.find("aaa").word != plainWord || autoComplete.find("aaad").word == plainWord {
+			t.Fatal("Should be able to share a word between the words never changed", ballotX)
+		}
+		t.Log("Should be able to share a word between the words never changed", checkMark)
+
+		if autoComplete.Accept("aa") == nil {
+			t.Fatal("Should be able to reject accepting a non-word", ballotX)
+		}
+		t.Log("Should be able to reject accepting a non-word", checkMark)
+
+		autoComplete, _ = NewAutoCompleteRadixS(words, 3, 4)
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaab", "aaac"}) {
+			t.Fatal("Should be able to limit result set size", ballotX)
+		}
+		t.Log("Should be able to limit result set size", checkMark)
+
+		autoComplete, _ = NewAutoCompleteRadixS(words, 10, 4)
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaab", "aaac", "aaad"}) {
+			t.Fatal("Should be able to limit radius", ballotX)
+		}
+		t.Log("Should be able to limit radius", checkMark)
+	}
+}
+
+func TestRadixLearn(t *testing.T) {
+
+	t.Log("Given the need to test the Learn and UnLearn features")
+	{
+		autoComplete, _ := NewAutoCompleteRadixE(0, 0)
+		autoComplete.Learn("aaa")
+		autoComplete.Learn("aaabbb")
+		autoComplete.Learn("aa")
+		ac, _ := autoComplete.Complete("a")
+		if !reflect.DeepEqual(ac, []string{"aa", "aaa", "aaabbb"}) {
+			t.Fatal("Should be able to learn from scratch", ballotX)
+		}
+		t.Log("Should be able to learn from scratch", checkMark)
+
+		if autoComplete.Learn("aaa") == nil {
+			t.Fatal("Should be able to reject a word already learnt", ballotX)
+		}
+		t.Log("Should be able to reject a word already learnt", checkMark)
+
+		words := []string{"aaa", "aaab", "aaabbb", "aaabbbc", "ddd"}
+		autoComplete, _ = NewAutoCompleteRadixS(words, 0, 0)
+		autoComplete.UnLearn("aaabbbc")
+		autoComplete.UnLearn("aaab")
+		ac, _ = autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaa", "aaabbb"}) {
+			t.Fatal("Should be able to unlearn leaves and non-leaves", ballotX)
+		}
+		t.Log("Should be able to unlearn leaves and non-leaves", checkMark)
+
+		if autoComplete.UnLearn("aaab") == nil {
+			t.Fatal("Should be able to reject unlearning a word not in the dictionary", ballotX)
+		}
+		t.Log("Should be able to reject unlearning a word not in the dictionary", checkMark)
+
+		autoComplete.Learn("aaab")
+		if len(autoComplete.newWords) != 0 || len(autoComplete.removedWords) != 1 {
+			t.Fatal("Should be able to keep track of learnt and unlearnt words", ballotX)
+		}
+		t.Log("Should be able to keep track of learnt and unlearnt words", checkMark)
+	}
+}
+
+func TestRadixCompleteFuzzy(t *testing.T) {
+
+	words := []string{"chai", "chain", "chair", "chairman", "chairperson", "chalk", "cheer", "table"}
+	autoComplete, _ := NewAutoCompleteRadixS(words, 0, 0)
+
+	t.Log("Given the need to test the fuzzy completion feature")
+	{
+		ac, _ := autoComplete.CompleteFuzzy("chiar", 1)
+		if !reflect.DeepEqual(ac, []string{"chair", "chairman", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a stem with transposed runes", ballotX)
+		}
+		t.Log("Should be able to complete a stem with transposed runes", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("chaim", 1)
+		if !reflect.DeepEqual(ac, []string{"chai", "chain", "chair", "chairman", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a stem with a wrong rune", ballotX)
+		}
+		t.Log("Should be able to complete a stem with a wrong rune", checkMark)
+	}
+}
+
+func TestRadixSaveRetrieve(t *testing.T) {
+
+	tempFile, err := ioutil.TempFile(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fName := tempFile.Name()
+	defer os.Remove(fName)
+
+	words := []string{"aaa", "aaabbb", "bbb", "ccc", "München"}
+	autoComplete, _ := NewAutoCompleteRadixS(words, 0, 0, WithNormalizer(FoldingNormalizer{}))
+	autoComplete.Accept("aaabbb")
+	autoComplete.Accept("munchen")
+	autoComplete.Learn("ddd")
+	autoComplete.Learn("eee")
+	autoComplete.Accept("eee")
+	autoComplete.UnLearn("ccc")
+
+	t.Log("Given the need to test the save/retrieve feature")
+	{
+		if err = autoComplete.Save(fName); err != nil {
+			t.Fatal("Should be able to save words to a file", ballotX)
+		}
+		t.Log("Should be able to save words to a file", checkMark)
+
+		autoComplete, _ = NewAutoCompleteRadixS(words, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		if err = autoComplete.Retrieve(fName); err != nil {
+			t.Fatal(err)
+		}
+		ac, _ := autoComplete.Complete("aaa")
+		if !reflect.DeepEqual(ac, []string{"aaabbb", "aaa"}) {
+			t.Fatal("Should be able to get back from retrieve an accepted word", ballotX)
+		}
+		t.Log("Should be able to get back from retrieve an accepted word", checkMark)
+		ac, _ = autoComplete.Complete("MU")
+		if !reflect.DeepEqual(ac, []string{"München"}) || autoComplete.find("munchen").word.accepts != 1 {
+			t.Fatal("Should be able to get back from retrieve a normalized word", ballotX)
+		}
+		t.Log("Should be able to get back from retrieve a normalized word", checkMark)
+		ac, _ = autoComplete.Complete("ddd")
+		if !reflect.DeepEqual(ac, []string{"ddd"}) {
+			t.Fatal("Should be able to get back from retrieve a learned word", ballotX)
+		}
+		t.Log("Should be able to get back from retrieve a learned word", checkMark)
+		ac, _ = autoComplete.Complete("ccc")
+		if !reflect.DeepEqual(ac, []string{}) {
+			t.Fatal("Should be able to erase from retrieve a deleted word", ballotX)
+		}
+		t.Log("Should be able to erase from retrieve a deleted word", checkMark)
+	}
+}
+
+func ExampleNewAutoCompleteRadixS() {
+
+	words := []string{"chair", "chairman", "chairperson", "chairwoman", "chairmaker", "chairmaking"}
+	autoComplete, err := NewAutoCompleteRadixS(words, 0, 0)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	completes, err := autoComplete.Complete("chairm")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(completes)
+	// Output: [chairman chairmaker chairmaking]
+}
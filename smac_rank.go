@@ -0,0 +1,153 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import "unicode/utf8"
+
+type acceptsRanker struct{}
+
+// Rank : see description in Ranker interface
+func (acceptsRanker) Rank(candidate Candidate) float64 {
+	return candidate.Decayed
+}
+
+// AcceptsRanker scores words by their accept count, decayed if the autocompleter has a half-life: accepted words bubble
+// up to the top, and the others stay in the order they were found in. It is the default ranker.
+var AcceptsRanker Ranker = acceptsRanker{}
+
+// RankerFunc adapts a function to a Ranker.
+type RankerFunc func(candidate Candidate) float64
+
+// Rank : see description in Ranker interface
+func (f RankerFunc) Rank(candidate Candidate) float64 {
+	return f(candidate)
+}
+
+// mergeCandidates returns the candidate of a word whose accepts are split between a and b, as the FST engine's are
+// between its automaton and its overlay. The word keeps the highest of their weights.
+func mergeCandidates(a, b Candidate) Candidate {
+
+	a.Accepts += b.Accepts
+	a.Decayed += b.Decayed
+	if b.Weight > a.Weight {
+		a.Weight = b.Weight
+	}
+	if b.LastAccepted.After(a.LastAccepted) {
+		a.LastAccepted = b.LastAccepted
+	}
+	return a
+}
+
+// hit returns the hit of form, a word of candidate found on completing a stem matchLength runes long, weighted by the
+// score of ranker unless NoBoost is set.
+func (opts CompleteOptions) hit(ranker Ranker, form string, candidate Candidate, payload interface{}, learnt bool, matchLength int) *wordHit {
+
+	candidate.Word = form
+	candidate.Length = utf8.RuneCountInString(form)
+	candidate.Learnt = learnt
+	candidate.MatchLength = matchLength
+
+	hit := &wordHit{
+		word:        form,
+		accepts:     candidate.Accepts,
+		learnt:      learnt,
+		matchLength: matchLength,
+		payload:     payload,
+	}
+	if !opts.NoBoost {
+		hit.weight = ranker.Rank(candidate)
+		hit.staticWeight = candidate.Weight
+	}
+	return hit
+}
+
+// topHits selects the k heaviest of the hits inserted, each in O(log k) time. Hits of the same weight are ordered by
+// static weight, and then keep the order they were inserted in. Hits are kept in a heap whose root is the lightest, and among the lightest the last inserted.
+type topHits struct {
+	k     int
+	order int
+	heap  []*wordHit
+}
+
+func newTopHits(k int) topHits {
+	return topHits{k: k}
+}
+
+// lighter reports whether hit a ranks below hit b.
+func lighter(a, b *wordHit) bool {
+	if a.weight != b.weight {
+		return a.weight < b.weight
+	}
+	if a.staticWeight != b.staticWeight {
+		return a.staticWeight < b.staticWeight
+	}
+	return a.order > b.order
+}
+
+func (top *topHits) insert(hit *wordHit) {
+
+	hit.order = top.order
+	top.order++
+	if len(top.heap) < top.k {
+		top.heap = append(top.heap, hit)
+		top.up(len(top.heap) - 1)
+		return
+	}
+	if len(top.heap) > 0 && lighter(top.heap[0], hit) {
+		top.heap[0] = hit
+		top.down(0)
+	}
+}
+
+func (top *topHits) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !lighter(top.heap[i], top.heap[parent]) {
+			return
+		}
+		top.heap[i], top.heap[parent] = top.heap[parent], top.heap[i]
+		i = parent
+	}
+}
+
+func (top *topHits) down(i int) {
+	for {
+		lightest := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(top.heap) && lighter(top.heap[child], top.heap[lightest]) {
+				lightest = child
+			}
+		}
+		if lightest == i {
+			return
+		}
+		top.heap[i], top.heap[lightest] = top.heap[lightest], top.heap[i]
+		i = lightest
+	}
+}
+
+func (top *topHits) size() int {
+	return len(top.heap)
+}
+
+// list returns the hits of top as a sOLILI, heaviest first.
+func (top *topHits) list() sOLILI {
+
+	remaining := topHits{heap: append([]*wordHit(nil), top.heap...)}
+	list := sOLILI{}
+	for len(remaining.heap) > 0 {
+		hit := remaining.heap[0]
+		last := len(remaining.heap) - 1
+		remaining.heap[0] = remaining.heap[last]
+		remaining.heap = remaining.heap[:last]
+		remaining.down(0)
+
+		hit.next = list.start
+		list.start = hit
+		if list.end == nil {
+			list.end = hit
+		}
+	}
+	return list
+}
@@ -0,0 +1,86 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func rankEngines(options ...Option) map[string]AutoComplete {
+
+	words := []string{"chair", "chairman", "chairmanship", "chairs"}
+	lino, _ := NewAutoCompleteLinoS(words, 2, 0, 0, options...)
+	trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz", words, 0, 0, options...)
+	radix, _ := NewAutoCompleteRadixS(words, 0, 0, options...)
+	automaton, _ := NewAutoCompleteFSTS(words, 0, 0, options...)
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}
+}
+
+func TestRanker(t *testing.T) {
+
+	t.Log("Given the need to rank completions with a ranker of our own")
+	{
+		candidates := make(map[string]Candidate)
+		longest := RankerFunc(func(candidate Candidate) float64 {
+			candidates[candidate.Word] = candidate
+			return float64(candidate.Length + candidate.Accepts)
+		})
+		for name, autoComplete := range rankEngines(WithRanker(longest)) {
+			ac, _ := autoComplete.Complete("chai")
+			if !reflect.DeepEqual(ac, []string{"chairmanship", "chairman", "chairs", "chair"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to rank words by a ranker on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank words by a ranker on a "+name+" autocompleter", checkMark)
+
+			autoComplete.Learn("chairlift")
+			for i := 0; i < 4; i++ {
+				autoComplete.Accept("chairs")
+			}
+			results, _ := autoComplete.CompleteScored("chai", CompleteOptions{})
+			if len(results) != 5 || results[0].Word != "chairmanship" || results[1].Word != "chairs" || results[1].Score != 10 || results[2].Word != "chairlift" {
+				t.Log(results)
+				t.Fatal("Should be able to score words by a ranker on a "+name+" autocompleter", ballotX)
+			}
+			if candidate := candidates["chairlift"]; candidate.Key != "chairlift" || candidate.Length != 9 || !candidate.Learnt || candidate.MatchLength != 4 {
+				t.Log(candidate)
+				t.Fatal("Should be able to pass a ranker the candidates found on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to score words by a ranker on a "+name+" autocompleter", checkMark)
+
+			ac, _ = autoComplete.CompleteWith("chai", CompleteOptions{NoBoost: true, Limit: 1})
+			if len(ac) != 1 || ac[0] == "chairmanship" {
+				t.Log(ac)
+				t.Fatal("Should be able to bypass the ranker with NoBoost on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to bypass the ranker with NoBoost on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to rank recently accepted words first")
+	{
+		now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		recent := RankerFunc(func(candidate Candidate) float64 {
+			if candidate.LastAccepted.IsZero() {
+				return 0
+			}
+			return float64(candidate.LastAccepted.Unix())
+		})
+		options := []Option{WithRanker(recent), WithHalfLife(time.Hour), WithClock(func() time.Time { return now })}
+		for name, autoComplete := range rankEngines(options...) {
+			for _, word := range []string{"chairman", "chairman", "chairs", "chair"} {
+				autoComplete.Accept(word)
+				now = now.Add(time.Minute)
+			}
+			ac, _ := autoComplete.Complete("chai")
+			if !reflect.DeepEqual(ac, []string{"chair", "chairs", "chairman", "chairmanship"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to rank words by the time they were last accepted on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank words by the time they were last accepted on a "+name+" autocompleter", checkMark)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+// bzip2Dictionary is "# common words\nthe\nthen\n\nthese\n" compressed with bzip2.
+var bzip2Dictionary = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xf9, 0xa0,
+	0x25, 0xb6, 0x00, 0x00, 0x03, 0xd1, 0x80, 0x00, 0x10, 0x48, 0x00, 0x0e,
+	0x43, 0x9c, 0x80, 0x20, 0x00, 0x31, 0x00, 0x00, 0x0a, 0xd3, 0x6a, 0x7a,
+	0x41, 0xe4, 0x9c, 0xa5, 0x90, 0x75, 0x25, 0x57, 0x61, 0xc3, 0x7e, 0x94,
+	0x2c, 0x59, 0x50, 0xbe, 0x2e, 0xe4, 0x8a, 0x70, 0xa1, 0x21, 0xf3, 0x40,
+	0x4b, 0x6c,
+}
+
+func readerEngines(dictionary []byte, options ...Option) (map[string]AutoComplete, error) {
+
+	lino, err := NewAutoCompleteLinoR(bytes.NewReader(dictionary), 2, 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	trie, err := NewAutoCompleteTrieR("abcdefghijklmnopqrstuvwxyz", bytes.NewReader(dictionary), 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	radix, err := NewAutoCompleteRadixR(bytes.NewReader(dictionary), 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	automaton, err := NewAutoCompleteFSTR(bytes.NewReader(dictionary), 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}, nil
+}
+
+func TestReader(t *testing.T) {
+
+	plain := []byte("# common words\nthe\nthen\n\nthese\n")
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write(plain)
+	gzipWriter.Close()
+
+	t.Log("Given the need to stream dictionaries from a reader")
+	{
+		dictionaries := map[string][]byte{"plain": plain, "gzip": gzipped.Bytes(), "bzip2": bzip2Dictionary}
+		for format, dictionary := range dictionaries {
+			autoCompletes, err := readerEngines(dictionary, WithCommentPrefix("#"), WithSkipBlankLines())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, autoComplete := range autoCompletes {
+				ac, _ := autoComplete.Complete("th")
+				if !reflect.DeepEqual(ac, []string{"the", "then", "these"}) {
+					t.Log(ac)
+					t.Fatal("Should be able to read a "+format+" dictionary, skipping comments and blank lines, on a "+name+" autocompleter", ballotX)
+				}
+				t.Log("Should be able to read a "+format+" dictionary, skipping comments and blank lines, on a "+name+" autocompleter", checkMark)
+			}
+		}
+
+		bad := map[string]struct {
+			dictionary []byte
+			options    []Option
+			err        string
+		}{
+			"a blank line":          {plain, []Option{WithCommentPrefix("#")}, "Line 4: Empty word in dictionary"},
+			"an invalid weight":     {[]byte("the\t10\nthat\tmany\n"), []Option{WithWeightSeparator("\t")}, "Line 2: Invalid weight in dictionary: many"},
+			"an invalid CSV weight": {[]byte("# word,weight\nthe,10\nthat,many\n"), []Option{WithCSV(), WithCommentPrefix("#")}, "Line 3: Invalid weight in dictionary: many"},
+		}
+		for input, test := range bad {
+			_, err := readerEngines(test.dictionary, test.options...)
+			if err == nil || err.Error() != test.err {
+				t.Log(err)
+				t.Fatal("Should not be able to read a dictionary with "+input+", reporting its line", ballotX)
+			}
+			t.Log("Should not be able to read a dictionary with "+input+", reporting its line", checkMark)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Save files start with saveMagic and a big-endian uint16 version, followed, in version 1, by a gob-encoded saveHeader
+// and by the body the header describes: the gob-encoded wordAccepts of what the autocompleter has learnt. Files saved
+// before versioning are headerless bodies; since 0x89 cannot start a gob stream, they are told apart by their first
+// byte.
+const saveMagic = "\x89SMAC"
+
+const saveVersion = 1
+
+// saveHeader describes the engine a save file was written by, and its body.
+type saveHeader struct {
+	Engine   string
+	Alphabet string
+	// Normalizer identifies the normalizer of the autocompleter (see normalizerName), as what is learnt is keyed by it.
+	Normalizer string
+	// PrefixMapDepth, ResultSize and Radius tell how the autocompleter was configured, which does not change what it
+	// learns: files are retrieved whatever they are.
+	PrefixMapDepth int
+	ResultSize     int
+	Radius         int
+	// Length is the length in bytes of the body, and Checksum its CRC-32 (IEEE).
+	Length   int64
+	Checksum uint32
+}
+
+// writeSave writes to writer a save file with header, and the body written by save.
+func writeSave(writer io.Writer, header saveHeader, save func(writer io.Writer) error) error {
+
+	var body bytes.Buffer
+	if err := save(&body); err != nil {
+		return err
+	}
+	header.Length = int64(body.Len())
+	header.Checksum = crc32.ChecksumIEEE(body.Bytes())
+
+	if _, err := io.WriteString(writer, saveMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(writer, binary.BigEndian, uint16(saveVersion)); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(writer).Encode(header); err != nil {
+		return err
+	}
+	_, err := body.WriteTo(writer)
+	return err
+}
+
+// readSave reads a save file from reader, passing its body to retrieve once it has checked that the file is whole and
+// was written by an engine like the one expected describes. Files saved before versioning are passed as they are.
+func readSave(reader io.Reader, expected saveHeader, retrieve func(reader io.Reader) error) error {
+
+	header, body, err := readSaveHeader(reader)
+	if err != nil {
+		return err
+	}
+	if header.Engine != "" {
+		if err = header.check(expected); err != nil {
+			return err
+		}
+	}
+	return retrieve(body)
+}
+
+// readSaveHeader reads the header of a save file from reader, and returns it along with the body of the file, once it
+// has checked it against its checksum. The header of a file saved before versioning is empty.
+func readSaveHeader(reader io.Reader) (saveHeader, io.Reader, error) {
+
+	var header saveHeader
+
+	// gob does not read past the end of a message from an io.ByteReader.
+	buffered := bufio.NewReader(reader)
+	magic, _ := buffered.Peek(len(saveMagic))
+	if string(magic) != saveMagic {
+		if len(magic) > 0 && magic[0] == saveMagic[0] {
+			return header, nil, errors.New("Corrupted save file: bad magic number")
+		}
+		return header, buffered, nil
+	}
+	buffered.Discard(len(saveMagic))
+
+	var version uint16
+	if err := binary.Read(buffered, binary.BigEndian, &version); err != nil {
+		return header, nil, errors.New("Corrupted save file: " + truncated(err).Error())
+	}
+	if version == 0 || version > saveVersion {
+		return header, nil, errors.New("Unsupported save file version " + strconv.Itoa(int(version)) + ", expected 1 to " + strconv.Itoa(saveVersion))
+	}
+
+	if err := gob.NewDecoder(buffered).Decode(&header); err != nil {
+		return header, nil, errors.New("Corrupted save file header: " + truncated(err).Error())
+	}
+	if header.Length < 0 || header.Engine == "" {
+		return header, nil, errors.New("Corrupted save file header")
+	}
+	var body bytes.Buffer
+	if n, err := io.CopyN(&body, buffered, header.Length); err != nil {
+		return header, nil, errors.New("Corrupted save file: body truncated at " + strconv.FormatInt(n, 10) + " of " + strconv.FormatInt(header.Length, 10) + " bytes")
+	}
+	if crc32.ChecksumIEEE(body.Bytes()) != header.Checksum {
+		return header, nil, errors.New("Corrupted save file: checksum mismatch")
+	}
+	return header, &body, nil
+}
+
+// check returns an error if a file saved with header cannot be retrieved by an engine like the one expected describes:
+// a file saved by another engine, by one with another normalizer, or by a trie with runes its alphabet does not have.
+func (header saveHeader) check(expected saveHeader) error {
+
+	if header.Engine != expected.Engine {
+		return errors.New("Save file written by a " + header.Engine + " autocompleter, not by a " + expected.Engine + " one")
+	}
+	if err := header.checkConfiguration(expected); err != nil {
+		return err
+	}
+	for _, r := range header.Alphabet {
+		if !strings.ContainsRune(expected.Alphabet, r) {
+			return errors.New("Save file alphabet has runes not in the alphabet of the autocompleter: " + string(r))
+		}
+	}
+	return nil
+}
+
+// checkConfiguration returns an error if the normalizer of header differs from the one of expected. Save files written
+// by ImportSave or by earlier versions of SMAC have no normalizer, which is left empty.
+func (header saveHeader) checkConfiguration(expected saveHeader) error {
+
+	if header.Normalizer != "" && expected.Normalizer != "" && header.Normalizer != expected.Normalizer {
+		return errors.New("Save file written by an autocompleter with normalizer " + header.Normalizer + ", not " + expected.Normalizer)
+	}
+	return nil
+}
+
+// truncated turns the end of file errors of a file cut short into an error saying so.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("file truncated")
+	}
+	return err
+}
+
+// SaveFile writes fileName with save atomically, as Save does: save writes to a temporary file in the same directory,
+// which is synced and then renamed to fileName, so that a crash or an error half way through leaves the previous file,
+// if any, as it was. The previous file can therefore be read while writing the new one. A new file is created with
+// mode 0644, and an existing one keeps its mode.
+func SaveFile(fileName string, save func(writer io.Writer) error) error {
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(fileName)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	tempFileName := f.Name()
+
+	buffered := bufio.NewWriter(f)
+	err = save(buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFileName, fileName)
+	}
+	if err != nil {
+		os.Remove(tempFileName)
+		return err
+	}
+
+	// the rename is durable only once the directory is synced too. Not all platforms can sync a directory.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// retrieveFile reads fileName with retrieve.
+func retrieveFile(fileName string, retrieve func(reader io.Reader) error) error {
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return retrieve(bufio.NewReader(f))
+}
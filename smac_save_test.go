@@ -0,0 +1,192 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// failingWriter fails once it has written n bytes.
+type failingWriter struct {
+	n int
+}
+
+func (writer *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > writer.n {
+		written := writer.n
+		writer.n = 0
+		return written, errors.New("Disk full")
+	}
+	writer.n -= len(p)
+	return len(p), nil
+}
+
+type unregisteredPayload struct {
+	ID int
+}
+
+func saveEngines() map[string]AutoComplete {
+
+	words := []string{"yak", "yam", "yes"}
+	lino, _ := NewAutoCompleteLinoS(words, 2, 0, 0)
+	trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz", words, 0, 0)
+	radix, _ := NewAutoCompleteRadixS(words, 0, 0)
+	automaton, _ := NewAutoCompleteFSTS(words, 0, 0)
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}
+}
+
+func TestSave(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Log("Given the need to save what an autocompleter has learnt without losing it")
+	{
+		retrieved := saveEngines()
+		for name, autoComplete := range saveEngines() {
+			autoComplete.Accept("yam")
+			autoComplete.Learn("yarn")
+
+			var buffer bytes.Buffer
+			if err = autoComplete.SaveTo(&buffer); err != nil {
+				t.Fatal(err)
+			}
+			if err = retrieved[name].RetrieveFrom(&buffer); err != nil {
+				t.Fatal(err)
+			}
+			ac, _ := retrieved[name].Complete("ya")
+			if !reflect.DeepEqual(ac, []string{"yam", "yak", "yarn"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to save to a writer and retrieve from a reader on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to save to a writer and retrieve from a reader on a "+name+" autocompleter", checkMark)
+
+			if err = autoComplete.SaveTo(&failingWriter{n: 10}); err == nil {
+				t.Fatal("Should be able to report a failing writer on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to report a failing writer on a "+name+" autocompleter", checkMark)
+
+			fileName := dir + "/" + name
+			if err = autoComplete.Save(fileName); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(fileName)
+			if err != nil || info.Mode().Perm() != 0644 {
+				t.Log(info.Mode())
+				t.Fatal("Should be able to save to a file that is not executable on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to save to a file that is not executable on a "+name+" autocompleter", checkMark)
+
+			saved, _ := ioutil.ReadFile(fileName)
+			autoComplete.LearnWithPayload("yawn", unregisteredPayload{ID: 1})
+			if err = autoComplete.Save(fileName); err == nil {
+				t.Fatal("Should be able to report an encoding error on a "+name+" autocompleter", ballotX)
+			}
+			survived, _ := ioutil.ReadFile(fileName)
+			if !bytes.Equal(saved, survived) {
+				t.Fatal("Should be able to keep the previous file when saving fails on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to keep the previous file when saving fails on a "+name+" autocompleter", checkMark)
+		}
+
+		files, _ := ioutil.ReadDir(dir)
+		if len(files) != 4 {
+			for _, file := range files {
+				t.Log(file.Name())
+			}
+			t.Fatal("Should be able to leave no temporary files behind", ballotX)
+		}
+		t.Log("Should be able to leave no temporary files behind", checkMark)
+	}
+
+	t.Log("Given the need to tell save files apart, and to reject the ones that cannot be retrieved")
+	{
+		// legacyWordAccepts is wordAccepts as save files without header held it.
+		type legacyWordAccepts struct {
+			Word    string
+			Accepts int
+		}
+		var legacy bytes.Buffer
+		enc := gob.NewEncoder(&legacy)
+		enc.Encode(legacyWordAccepts{Word: "yam", Accepts: 2})
+		enc.Encode(legacyWordAccepts{Word: "yarn"})
+		enc.Encode(legacyWordAccepts{Word: "yes", Accepts: -1})
+		for name, autoComplete := range saveEngines() {
+			if err = autoComplete.RetrieveFrom(bytes.NewReader(legacy.Bytes())); err != nil {
+				t.Fatal(err)
+			}
+			ac, _ := autoComplete.Complete("y")
+			if !reflect.DeepEqual(ac, []string{"yam", "yak", "yarn"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to retrieve a save file without header on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to retrieve a save file without header on a "+name+" autocompleter", checkMark)
+		}
+
+		engines := saveEngines()
+		var saved bytes.Buffer
+		engines["LiNo"].Accept("yam")
+		engines["LiNo"].SaveTo(&saved)
+		file := saved.Bytes()
+		header, _, err := readSaveHeader(bytes.NewReader(file))
+		if err != nil || header.Engine != "LiNo" || header.PrefixMapDepth != 2 || header.ResultSize != DefaultResultSize || header.Radius != DefaultRadius {
+			t.Log(header, err)
+			t.Fatal("Should be able to describe the autocompleter in the header of a save file", ballotX)
+		}
+		t.Log("Should be able to describe the autocompleter in the header of a save file", checkMark)
+
+		corrupted := append([]byte(nil), file...)
+		corrupted[len(corrupted)-3] ^= 0xff
+		unsupported := append([]byte(nil), file...)
+		unsupported[len(saveMagic)+1] = 9
+		alphabet, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyzñ", []string{"yak"}, 0, 0)
+		var accented bytes.Buffer
+		alphabet.SaveTo(&accented)
+		deeper, _ := NewAutoCompleteLinoS([]string{"yak"}, 3, 0, 0)
+		var deep bytes.Buffer
+		deeper.SaveTo(&deep)
+		larger, _ := NewAutoCompleteRadixS([]string{"yak"}, 20, 40)
+		var large bytes.Buffer
+		larger.SaveTo(&large)
+		folding, _ := NewAutoCompleteLinoS([]string{"yak"}, 2, 0, 0, WithNormalizer(FoldingNormalizer{}))
+		var folded bytes.Buffer
+		folding.SaveTo(&folded)
+
+		if engines["LiNo"].RetrieveFrom(bytes.NewReader(deep.Bytes())) != nil || engines["radix"].RetrieveFrom(bytes.NewReader(large.Bytes())) != nil {
+			t.Fatal("Should be able to retrieve a save file of an autocompleter with another prefix map depth, result size or radius", ballotX)
+		}
+		t.Log("Should be able to retrieve a save file of an autocompleter with another prefix map depth, result size or radius", checkMark)
+
+		bad := []struct {
+			name   string
+			engine AutoComplete
+			file   []byte
+			err    string
+		}{
+			{"a save file of another engine", engines["trie"], file, "Save file written by a LiNo autocompleter, not by a trie one"},
+			{"a save file with runes not in the alphabet", engines["trie"], accented.Bytes(), "Save file alphabet has runes not in the alphabet of the autocompleter: ñ"},
+			{"a save file with another normalizer", engines["LiNo"], folded.Bytes(), "Save file written by an autocompleter with normalizer smac.FoldingNormalizer, not smac.identityNormalizer"},
+			{"a corrupted save file", engines["LiNo"], corrupted, "Corrupted save file: checksum mismatch"},
+			{"a truncated save file", engines["LiNo"], file[:len(file)-10], "Corrupted save file: body truncated"},
+			{"a save file of an unsupported version", engines["LiNo"], unsupported, "Unsupported save file version 9"},
+		}
+		for _, test := range bad {
+			if err = test.engine.RetrieveFrom(bytes.NewReader(test.file)); err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Log(err)
+				t.Fatal("Should not be able to retrieve "+test.name, ballotX)
+			}
+			t.Log("Should not be able to retrieve "+test.name, checkMark)
+		}
+	}
+}
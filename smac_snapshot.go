@@ -0,0 +1,258 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// forker is implemented by the engines that can be wrapped by NewCopyOnWrite.
+type forker interface {
+	AutoComplete
+	// fork returns a copy of the engine to apply changes to, leaving the engine itself untouched so that it can still
+	// be read from concurrently.
+	fork() forker
+}
+
+// Snapshot is a read-only view of an autocompleter, which does not change as words are learnt, unlearnt or accepted.
+type Snapshot interface {
+	Complete(word string) ([]string, error)
+	CompleteWith(stem string, opts CompleteOptions) ([]string, error)
+	CompleteFuzzy(stem string, maxEdits int) ([]string, error)
+	CompleteScored(stem string, opts CompleteOptions) ([]Result, error)
+	CompleteQuery(query string) ([]string, error)
+	Predict(previousWords []string, stem string) ([]string, error)
+}
+
+type snapshot struct {
+	engine forker
+}
+
+func (s snapshot) Complete(word string) ([]string, error) {
+	return s.engine.Complete(word)
+}
+
+func (s snapshot) CompleteWith(stem string, opts CompleteOptions) ([]string, error) {
+	return s.engine.CompleteWith(stem, opts)
+}
+
+func (s snapshot) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	return s.engine.CompleteFuzzy(stem, maxEdits)
+}
+
+func (s snapshot) CompleteScored(stem string, opts CompleteOptions) ([]Result, error) {
+	return s.engine.CompleteScored(stem, opts)
+}
+
+func (s snapshot) CompleteQuery(query string) ([]string, error) {
+	return s.engine.CompleteQuery(query)
+}
+
+func (s snapshot) Predict(previousWords []string, stem string) ([]string, error) {
+	return s.engine.Predict(previousWords, stem)
+}
+
+// CopyOnWrite is an AutoComplete that is safe for concurrent use, whose completions never wait for writes.
+//
+// Completions read from an immutable snapshot of the engine. Accept, Learn, UnLearn and Retrieve are applied, one at a
+// time, to a copy of the snapshot, which is published as the new snapshot by Fold. Changes are therefore not visible to
+// completions until the next Fold.
+type CopyOnWrite struct {
+	published atomic.Value
+	lock      sync.Mutex
+	pending   forker
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewCopyOnWrite returns a CopyOnWrite wrapping ac, which can be an AutoCompleteLiNo, an AutoCompleteTrie, an
+// AutoCompleteRadix or an AutoCompleteFST. ac must not be used directly afterwards.
+//
+// Copies share as much as possible with the snapshot they are made from: trie and radix nodes are only copied with
+// their path from the root when they are modified, and AutoCompleteLiNo only copies the nodes and prefixes it modifies.
+//
+// If foldInterval is not 0, Fold is called every foldInterval until Close is called; otherwise it is up to the client
+// to call Fold.
+func NewCopyOnWrite(ac AutoComplete, foldInterval time.Duration) (*CopyOnWrite, error) {
+
+	engine, ok := ac.(forker)
+	if !ok {
+		return nil, errors.New("Autocompleter does not support snapshots")
+	}
+
+	cow := &CopyOnWrite{}
+	cow.published.Store(snapshot{engine})
+
+	if foldInterval > 0 {
+		cow.stop = make(chan struct{})
+		cow.done = make(chan struct{})
+		go cow.foldEvery(foldInterval)
+	}
+	return cow, nil
+}
+
+func (cow *CopyOnWrite) foldEvery(foldInterval time.Duration) {
+
+	ticker := time.NewTicker(foldInterval)
+	defer ticker.Stop()
+	defer close(cow.done)
+	for {
+		select {
+		case <-ticker.C:
+			cow.Fold()
+		case <-cow.stop:
+			return
+		}
+	}
+}
+
+// Snapshot returns the current snapshot, to run a batch of completions against a consistent view.
+func (cow *CopyOnWrite) Snapshot() Snapshot {
+	return cow.published.Load().(snapshot)
+}
+
+// Fold publishes the changes made since the last Fold as the new snapshot.
+func (cow *CopyOnWrite) Fold() {
+
+	cow.lock.Lock()
+	defer cow.lock.Unlock()
+	if cow.pending != nil {
+		cow.published.Store(snapshot{cow.pending})
+		cow.pending = nil
+	}
+}
+
+// Close stops the periodic folding started by NewCopyOnWrite, and publishes pending changes.
+func (cow *CopyOnWrite) Close() {
+
+	if cow.stop != nil {
+		close(cow.stop)
+		<-cow.done
+		cow.stop = nil
+	}
+	cow.Fold()
+}
+
+// write applies change to the copy of the snapshot, making the copy if needed.
+func (cow *CopyOnWrite) write(change func(engine AutoComplete) error) error {
+
+	cow.lock.Lock()
+	defer cow.lock.Unlock()
+	if cow.pending == nil {
+		cow.pending = cow.published.Load().(snapshot).engine.fork()
+	}
+	return change(cow.pending)
+}
+
+// Accept : see description in AutoComplete interface
+func (cow *CopyOnWrite) Accept(acceptedWord string) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.Accept(acceptedWord)
+	})
+}
+
+// Learn : see description in AutoComplete interface
+func (cow *CopyOnWrite) Learn(word string, keys ...string) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.Learn(word, keys...)
+	})
+}
+
+// LearnWithPayload : see description in AutoComplete interface
+func (cow *CopyOnWrite) LearnWithPayload(word string, payload interface{}, keys ...string) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.LearnWithPayload(word, payload, keys...)
+	})
+}
+
+// SetWeight : see description in AutoComplete interface
+func (cow *CopyOnWrite) SetWeight(word string, weight float64) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.SetWeight(word, weight)
+	})
+}
+
+// UnLearn : see description in AutoComplete interface
+func (cow *CopyOnWrite) UnLearn(word string) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.UnLearn(word)
+	})
+}
+
+// Complete : see description in AutoComplete interface. It completes against the current snapshot.
+func (cow *CopyOnWrite) Complete(word string) ([]string, error) {
+	return cow.Snapshot().Complete(word)
+}
+
+// CompleteWith : see description in AutoComplete interface. It completes against the current snapshot.
+func (cow *CopyOnWrite) CompleteWith(stem string, opts CompleteOptions) ([]string, error) {
+	return cow.Snapshot().CompleteWith(stem, opts)
+}
+
+// CompleteFuzzy : see description in AutoComplete interface. It completes against the current snapshot.
+func (cow *CopyOnWrite) CompleteFuzzy(stem string, maxEdits int) ([]string, error) {
+	return cow.Snapshot().CompleteFuzzy(stem, maxEdits)
+}
+
+// CompleteScored : see description in AutoComplete interface. It completes against the current snapshot.
+func (cow *CopyOnWrite) CompleteScored(stem string, opts CompleteOptions) ([]Result, error) {
+	return cow.Snapshot().CompleteScored(stem, opts)
+}
+
+// CompleteQuery : see description in AutoComplete interface. It completes against the current snapshot.
+func (cow *CopyOnWrite) CompleteQuery(query string) ([]string, error) {
+	return cow.Snapshot().CompleteQuery(query)
+}
+
+// AcceptSequence : see description in AutoComplete interface
+func (cow *CopyOnWrite) AcceptSequence(words []string) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.AcceptSequence(words)
+	})
+}
+
+// Predict : see description in AutoComplete interface. It predicts against the current snapshot.
+func (cow *CopyOnWrite) Predict(previousWords []string, stem string) ([]string, error) {
+	return cow.Snapshot().Predict(previousWords, stem)
+}
+
+// Save : see description in AutoComplete interface. Changes not folded yet are saved too.
+func (cow *CopyOnWrite) Save(fileName string) error {
+
+	cow.lock.Lock()
+	defer cow.lock.Unlock()
+	if cow.pending != nil {
+		return cow.pending.Save(fileName)
+	}
+	return cow.published.Load().(snapshot).engine.Save(fileName)
+}
+
+// SaveTo : see description in AutoComplete interface. Changes not folded yet are saved too.
+func (cow *CopyOnWrite) SaveTo(writer io.Writer) error {
+
+	cow.lock.Lock()
+	defer cow.lock.Unlock()
+	if cow.pending != nil {
+		return cow.pending.SaveTo(writer)
+	}
+	return cow.published.Load().(snapshot).engine.SaveTo(writer)
+}
+
+// Retrieve : see description in AutoComplete interface
+func (cow *CopyOnWrite) Retrieve(fileName string) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.Retrieve(fileName)
+	})
+}
+
+// RetrieveFrom : see description in AutoComplete interface
+func (cow *CopyOnWrite) RetrieveFrom(reader io.Reader) error {
+	return cow.write(func(engine AutoComplete) error {
+		return engine.RetrieveFrom(reader)
+	})
+}
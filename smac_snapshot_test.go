@@ -0,0 +1,203 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var snapshotWords = []string{"chai", "chain", "chair", "chairman", "chairperson", "chalk", "cheer", "table", "tab", "taboo"}
+
+// snapshotEngines returns pairs of identical autocompleters for each engine, one to be wrapped and one for reference.
+func snapshotEngines() map[string][2]AutoComplete {
+
+	engines := make(map[string][2]AutoComplete)
+	for i := 0; i < 2; i++ {
+		lino, _ := NewAutoCompleteLinoS(snapshotWords, 2, 0, 0)
+		trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz", snapshotWords, 0, 0)
+		radix, _ := NewAutoCompleteRadixS(snapshotWords, 0, 0)
+		automaton, _ := NewAutoCompleteFSTS(snapshotWords, 0, 0)
+		for name, engine := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			pair := engines[name]
+			pair[i] = engine
+			engines[name] = pair
+		}
+	}
+	return engines
+}
+
+func snapshotCompletions(s Snapshot) [][]string {
+
+	var completions [][]string
+	for _, stem := range []string{"c", "ch", "cha", "chai", "chair", "t", "ta", "tab", "x"} {
+		ac, _ := s.Complete(stem)
+		completions = append(completions, ac)
+	}
+	return completions
+}
+
+func TestCopyOnWrite(t *testing.T) {
+
+	t.Log("Given the need to complete against snapshots while learning")
+	{
+		for name, pair := range snapshotEngines() {
+			cow, err := NewCopyOnWrite(pair[0], 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reference := pair[1]
+			original, _ := reference.Complete("chai")
+			reference.Learn("chairs")
+			reference.Accept("table")
+			reference.UnLearn("chain")
+			learnt, _ := reference.Complete("chai")
+
+			before := cow.Snapshot()
+			cow.Learn("chairs")
+			cow.Accept("table")
+			cow.UnLearn("chain")
+			ac, _ := cow.Complete("chai")
+			if !reflect.DeepEqual(ac, original) {
+				t.Log(ac)
+				t.Fatal("Should be able to complete against the snapshot until a fold with a "+name+" autocompleter", ballotX)
+			}
+			cow.Fold()
+			ac, _ = cow.Complete("chai")
+			if !reflect.DeepEqual(ac, learnt) || reflect.DeepEqual(ac, original) {
+				t.Log(ac)
+				t.Fatal("Should be able to complete against the snapshot until a fold with a "+name+" autocompleter", ballotX)
+			}
+			ac, _ = before.Complete("chai")
+			if !reflect.DeepEqual(ac, original) {
+				t.Log(ac)
+				t.Fatal("Should be able to keep a snapshot unchanged after a fold with a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete against the snapshot until a fold with a "+name+" autocompleter", checkMark)
+		}
+
+		if _, err := NewCopyOnWrite(NewConcurrent(&AutoCompleteRadix{}), 0); err == nil {
+			t.Fatal("Should be able to reject an autocompleter that does not support snapshots", ballotX)
+		}
+		t.Log("Should be able to reject an autocompleter that does not support snapshots", checkMark)
+	}
+}
+
+func TestCopyOnWriteSharing(t *testing.T) {
+
+	words := append(append([]string{}, snapshotWords...), "chairs", "chalks", "tabs", "tables", "cha", "ta", "c")
+
+	t.Log("Given the need to share structure between snapshots")
+	{
+		for name, pair := range snapshotEngines() {
+			cow, _ := NewCopyOnWrite(pair[0], 0)
+			reference := pair[1]
+			random := rand.New(rand.NewSource(1))
+
+			var snapshots []Snapshot
+			var expected [][][]string
+			for round := 0; round < 50; round++ {
+				for i := 0; i < 5; i++ {
+					word := words[random.Intn(len(words))]
+					switch random.Intn(3) {
+					case 0:
+						cow.Learn(word)
+						reference.Learn(word)
+					case 1:
+						cow.UnLearn(word)
+						reference.UnLearn(word)
+					default:
+						cow.Accept(word)
+						reference.Accept(word)
+					}
+				}
+				cow.Fold()
+				snapshots = append(snapshots, cow.Snapshot())
+				expected = append(expected, snapshotCompletions(reference))
+			}
+			for i, s := range snapshots {
+				if !reflect.DeepEqual(snapshotCompletions(s), expected[i]) {
+					t.Log(i, snapshotCompletions(s), expected[i])
+					t.Fatal("Should be able to keep every snapshot of a "+name+" autocompleter as it was", ballotX)
+				}
+			}
+			t.Log("Should be able to keep every snapshot of a "+name+" autocompleter as it was", checkMark)
+
+			if lino, isLiNo := cow.Snapshot().(snapshot).engine.(*AutoCompleteLiNo); isLiNo {
+				layers := 0
+				for layer := lino.shared; layer != nil; layer = layer.below {
+					layers++
+				}
+				if layers > 8 {
+					t.Fatal("Should be able to keep the layers of a LiNo autocompleter few", ballotX)
+				}
+				t.Log("Should be able to keep the layers of a LiNo autocompleter few", checkMark)
+			}
+		}
+
+		lino, _ := NewAutoCompleteLinoS(snapshotWords, 2, 0, 0)
+		forked := lino.fork().(*AutoCompleteLiNo)
+		forked.Learn("chairs")
+		forked.UnLearn("taboo")
+		if len(forked.wordMap) > 4 || len(forked.prefixMap) > 0 || len(lino.wordMap) != len(snapshotWords) {
+			t.Log(forked.wordMap, forked.prefixMap)
+			t.Fatal("Should be able to copy only the nodes and prefixes a LiNo fork modifies", ballotX)
+		}
+		t.Log("Should be able to copy only the nodes and prefixes a LiNo fork modifies", checkMark)
+	}
+}
+
+func TestCopyOnWriteConcurrent(t *testing.T) {
+
+	t.Log("Given the need to complete while learning from other goroutines")
+	{
+		for name, pair := range snapshotEngines() {
+			cow, _ := NewCopyOnWrite(pair[0], 0)
+
+			var wg sync.WaitGroup
+			for g := 0; g < 4; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 500; i++ {
+						cow.Complete("cha")
+						cow.CompleteFuzzy("chiar", 1)
+					}
+				}()
+			}
+			for i := 0; i < 200; i++ {
+				word := snapshotWords[i%len(snapshotWords)] + "s"
+				if i%2 == 0 {
+					cow.Learn(word)
+				} else {
+					cow.Accept(word)
+					cow.UnLearn(word)
+				}
+				if i%10 == 0 {
+					cow.Fold()
+				}
+			}
+			wg.Wait()
+			t.Log("Should be able to use a "+name+" autocompleter from many goroutines", checkMark)
+		}
+	}
+}
+
+func TestCopyOnWriteFoldInterval(t *testing.T) {
+
+	t.Log("Given the need to fold periodically")
+	{
+		autoComplete, _ := NewAutoCompleteRadixS(snapshotWords, 0, 0)
+		cow, _ := NewCopyOnWrite(&autoComplete, 1)
+		cow.Learn("chairs")
+		cow.Close()
+		ac, _ := cow.Complete("chairs")
+		if !reflect.DeepEqual(ac, []string{"chairs"}) {
+			t.Fatal("Should be able to fold on Close", ballotX)
+		}
+		t.Log("Should be able to fold on Close", checkMark)
+	}
+}
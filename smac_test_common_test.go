@@ -7,16 +7,24 @@ import (
 	"bufio"
 	"os"
 	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
 	"testing"
 )
 
 const checkMark = "\u2713"
 const ballotX = "\u2717"
 
+var benchmarkOnce sync.Once
+
+// initBenchmark loads the test data once, however many of the benchmark files call it from their init.
 func initBenchmark() {
-	goPath := os.Getenv("GOPATH")
+	benchmarkOnce.Do(loadBenchmark)
+}
 
-	wordFile := goPath + "/src/github.com/pierods/smac/demo/allwords.txt"
+func loadBenchmark() {
+	wordFile := "demo/allwords.txt"
 
 	f, err := os.Open(wordFile)
 
@@ -50,6 +58,21 @@ func initBenchmark() {
 
 }
 
+// reportRetained reports as retained-B the heap still held, once garbage is collected, by what build returns: the memory
+// an autocompleter occupies, which B/op overstates with the garbage of building it.
+func reportRetained(b *testing.B, build func() interface{}) {
+
+	b.StopTimer()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	built := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(built)
+	b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)), "retained-B")
+}
+
 var testWords []string
 var testPrefixes []string
 var wordsInTestData, prefixesInTestData int
@@ -69,60 +92,77 @@ func TestTrieSOLILI(t *testing.T) {
 		}
 		t.Log("When operating on a solili")
 		{
-			list := sOLILI{}
-			list.insert("aaa", 0)
-			slice := list.flush()
+			top := newTopHits(100)
+			insert := func(word string, weight float64) []string {
+				top.insert(&wordHit{word: word, weight: weight})
+				list := top.list()
+				return list.flush()
+			}
+			slice := insert("aaa", 0)
 			if !reflect.DeepEqual(slice, []string{"aaa"}) {
 				t.Fatal("Should be able to correctly add an element to a solili", ballotX)
 			}
 			t.Log("Should be able to correctly add an element to a solili", checkMark)
-			list.insert("bbb", 0)
-			slice = list.flush()
+			slice = insert("bbb", 0)
 			if !reflect.DeepEqual(slice, []string{"aaa", "bbb"}) {
 				t.Fatal("Should be able to correctly add an element to the back of a solili", ballotX)
 			}
 			t.Log("Should be able to correctly add an element to the back of a solili", checkMark)
-			list.insert("jjj", 100)
-			slice = list.flush()
+			slice = insert("jjj", 100)
 			if !reflect.DeepEqual(slice, []string{"jjj", "aaa", "bbb"}) {
 				t.Fatal("Should be able to correctly add an element to the front of a solili", ballotX)
 			}
 			t.Log("Should be able to correctly add an element to the front of a solili", checkMark)
-			list.insert("kkk", 90)
-			slice = list.flush()
+			slice = insert("kkk", 90)
 			if !reflect.DeepEqual(slice, []string{"jjj", "kkk", "aaa", "bbb"}) {
 				t.Fatal("Should be able to correctly add an element in the middle of a solili", ballotX)
 			}
 			t.Log("Should be able to correctly add an element in the middle of a solili", checkMark)
-			list.insert("lll", 100)
-			slice = list.flush()
+			slice = insert("lll", 100)
 			if !reflect.DeepEqual(slice, []string{"jjj", "lll", "kkk", "aaa", "bbb"}) {
 				t.Fatal("Should be able to maintain arrival order for same-weight elements (at head)", ballotX)
 			}
 			t.Log("Should be able to maintain arrival order for same-weight elements (at head)", checkMark)
-			list.insert("mmm", 90)
-			slice = list.flush()
+			slice = insert("mmm", 90)
 			if !reflect.DeepEqual(slice, []string{"jjj", "lll", "kkk", "mmm", "aaa", "bbb"}) {
 				t.Fatal("Should be able to maintain arrival order for same-weight elements", ballotX)
 			}
 			t.Log("Should be able to maintain arrival order for same-weight elements", checkMark)
 
-			list = sOLILI{}
-			list.insert("1", 0)
-			list.insert("2", 0)
-			list.insert("3", 0)
-			list.insert("4", 0)
-			list.insert("5", 0)
-			list.insert("6", 0)
-			list.insert("7", 0)
-			list.insert("8", 0)
-			list.insert("8", 0)
+			top = newTopHits(3)
+			for i, weight := range []float64{1, 5, 0, 5, 3, 5, 2} {
+				top.insert(&wordHit{word: strconv.Itoa(i), weight: weight})
+			}
+			list := top.list()
+			if !reflect.DeepEqual(list.flush(), []string{"1", "3", "5"}) {
+				t.Log(list.flush())
+				t.Fatal("Should be able to keep the heaviest elements only, in arrival order for same-weight elements", ballotX)
+			}
+			t.Log("Should be able to keep the heaviest elements only, in arrival order for same-weight elements", checkMark)
+
+			top = newTopHits(100)
+			for _, word := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "8"} {
+				top.insert(&wordHit{word: word})
+			}
+			list = top.list()
 
 			slice = list.flushL(5)
 			if len(slice) != 5 {
 				t.Fatal("Should be able to limit flushing on a lili", ballotX)
 			}
 			t.Log("Should be able to limit flushing on a lili", checkMark)
+
+			top = newTopHits(100)
+			top.insert(&wordHit{word: "x", weight: 5, accepts: 5})
+			top.insert(&wordHit{word: "y", weight: 3, accepts: 3})
+			top.insert(&wordHit{word: "z", accepts: 7, learnt: true, matchLength: 1, distance: 1})
+			list = top.list()
+			results := list.results(10)
+			if !reflect.DeepEqual(list.flush(), []string{"x", "y", "z"}) || !reflect.DeepEqual(results[2], Result{Word: "z", Accepts: 7, MatchLength: 1, Learnt: true, Distance: 1}) {
+				t.Log(results)
+				t.Fatal("Should be able to carry hit metadata through a lili", ballotX)
+			}
+			t.Log("Should be able to carry hit metadata through a lili", checkMark)
 		}
 	}
 }
@@ -0,0 +1,43 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import "unicode"
+
+type boundaryTokenizer struct{}
+
+// Tokenize : see description in Tokenizer interface
+func (boundaryTokenizer) Tokenize(word string) []string {
+
+	var tokens []string
+	var token []rune
+	runes := []rune(word)
+
+	for i, r := range runes {
+		if unicode.IsSpace(r) || r == '-' || r == '_' {
+			if len(token) > 0 {
+				tokens = append(tokens, string(token))
+				token = nil
+			}
+			continue
+		}
+		if len(token) > 0 && unicode.IsUpper(r) {
+			previous := token[len(token)-1]
+			// "giftCard" splits before C, and "HTTPServer" before S.
+			if unicode.IsLower(previous) || unicode.IsUpper(previous) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				tokens = append(tokens, string(token))
+				token = nil
+			}
+		}
+		token = append(token, r)
+	}
+	if len(token) > 0 {
+		tokens = append(tokens, string(token))
+	}
+	return tokens
+}
+
+// BoundaryTokenizer splits words at whitespace, hyphens and underscores, and between the words of camelCase: "New York",
+// "gift-card", "gift_card" and "giftCard" all have two tokens.
+var BoundaryTokenizer Tokenizer = boundaryTokenizer{}
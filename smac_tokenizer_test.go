@@ -0,0 +1,88 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBoundaryTokenizer(t *testing.T) {
+
+	t.Log("Given the need to split words at token boundaries")
+	{
+		cases := map[string][]string{
+			"New York":       {"New", "York"},
+			"gift-card":      {"gift", "card"},
+			"gift_card":      {"gift", "card"},
+			"giftCard":       {"gift", "Card"},
+			"HTTPServer":     {"HTTP", "Server"},
+			"  New  York-- ": {"New", "York"},
+			"york":           {"york"},
+			"":               nil,
+		}
+		for word, tokens := range cases {
+			if actual := BoundaryTokenizer.Tokenize(word); !reflect.DeepEqual(actual, tokens) {
+				t.Log(actual)
+				t.Fatal("Should be able to tokenize "+word, ballotX)
+			}
+		}
+		t.Log("Should be able to split words at whitespace, hyphens, underscores and camelCase", checkMark)
+	}
+}
+
+func TestTokenCompletion(t *testing.T) {
+
+	dictionary := []string{"New York", "York Minster", "Yorkshire", "Gift Card", "Gift Shop"}
+	options := []Option{WithNormalizer(FoldingNormalizer{}), WithTokenizer(BoundaryTokenizer)}
+	lino, _ := NewAutoCompleteLinoS(dictionary, 2, 0, 0, options...)
+	trie, _ := NewAutoCompleteTrieS("abcdefghijklmnopqrstuvwxyz ", dictionary, 0, 20, options...)
+	radix, _ := NewAutoCompleteRadixS(dictionary, 0, 0, options...)
+	automaton, _ := NewAutoCompleteFSTS(dictionary, 0, 0, options...)
+
+	t.Log("Given the need to complete words from any of their tokens")
+	{
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			results, _ := autoComplete.CompleteScored("york", CompleteOptions{})
+			if len(results) != 3 || results[2].Word != "New York" || !results[2].Infix || results[0].Infix || results[1].Infix {
+				t.Log(results)
+				t.Fatal("Should be able to rank words matched from a token below the others on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank words matched from a token below the others on a "+name+" autocompleter", checkMark)
+
+			ac, _ := autoComplete.Complete("card")
+			if !reflect.DeepEqual(ac, []string{"Gift Card"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to complete a word from its last token on a "+name+" autocompleter", ballotX)
+			}
+			ac, _ = autoComplete.Complete("gift")
+			if len(ac) != 2 {
+				t.Log(ac)
+				t.Fatal("Should be able to complete a word once from its start on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to complete a word from its start and from its tokens on a "+name+" autocompleter", checkMark)
+
+			autoComplete.Learn("Los Angeles City")
+			ac, _ = autoComplete.Complete("angeles c")
+			if !reflect.DeepEqual(ac, []string{"Los Angeles City"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to tokenize learnt words on a "+name+" autocompleter", ballotX)
+			}
+			autoComplete.UnLearn("Los Angeles City")
+			if ac, _ = autoComplete.Complete("city"); len(ac) != 0 {
+				t.Log(ac)
+				t.Fatal("Should be able to unlearn tokenized words on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to learn and unlearn tokenized words on a "+name+" autocompleter", checkMark)
+		}
+	}
+
+	t.Log("Given the need to build a FST file")
+	{
+		if BuildFST("dictionary.txt", "dictionary.fst", WithTokenizer(BoundaryTokenizer)) == nil {
+			t.Fatal("Should not be able to build a FST file with a tokenizer", ballotX)
+		}
+		t.Log("Should not be able to build a FST file with a tokenizer", checkMark)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 )
 
 const alphabet = "abcdefghijklmnopqrstuvwxyz"
@@ -180,7 +181,7 @@ func TestTrieConstruction(t *testing.T) {
 
 	t.Log("Given the need to test the putIter() function")
 	{
-		a := autoComplete.root.links[0]
+		a := autoComplete.child(autoComplete.root, 'a')
 		if a == nil {
 			t.Fatal("Should be able to insert first character of a word in tree", ballotX)
 		}
@@ -191,7 +192,7 @@ func TestTrieConstruction(t *testing.T) {
 			t.Fatal("Should be able to insert first character of a word in tree", ballotX)
 		}
 		t.Log("Should be able to insert first character of a word in tree", checkMark)
-		b := a.links[1]
+		b := autoComplete.child(a, 'b')
 		if b.intRune != 98 {
 			t.Fatal("Should be able to insert second character of a word in tree", ballotX)
 		}
@@ -199,7 +200,7 @@ func TestTrieConstruction(t *testing.T) {
 			t.Fatal("Should be able to insert second character of a word in tree", ballotX)
 		}
 		t.Log("Should be able to insert second character of a word in tree", checkMark)
-		c := b.links[2]
+		c := autoComplete.child(b, 'c')
 		if !c.isWord {
 			t.Fatal("Should be able to insert a word in tree", ballotX)
 		}
@@ -214,7 +215,7 @@ func TestTrieConstruction(t *testing.T) {
 			t.Fatal("Should be able to instantiate an autcomplete on a non-ASCII alphabet", ballotX)
 		}
 		t.Log("Should be able to instantiate an autcomplete on a non-ASCII alphabet", checkMark)
-		russianA := autoComplete.root.links[0]
+		russianA := autoComplete.child(autoComplete.root, 'а')
 		if russianA == nil {
 			t.Fatal("Should be able to insert first character of a non-ASCII word in tree", ballotX)
 		}
@@ -232,6 +233,78 @@ func TestTrieConstruction(t *testing.T) {
 	}
 }
 
+func TestTrieLinks(t *testing.T) {
+
+	t.Log("Given the need to test sparse and dense links")
+	{
+		var words []string
+		for _, r := range "zyxwvutsrqponmlkjihgfedcba" {
+			words = append(words, "a"+string(r))
+		}
+		autoComplete, _ := NewAutoCompleteTrieS(alphabet, words[:sparseLinksMax], 0, 0)
+		a := autoComplete.child(autoComplete.root, 'a')
+		if a.dense || len(a.links) != sparseLinksMax {
+			t.Fatal("Should be able to keep few links sparse", ballotX)
+		}
+		t.Log("Should be able to keep few links sparse", checkMark)
+		for _, w := range words[sparseLinksMax:] {
+			autoComplete.Learn(w)
+		}
+		if !a.dense || len(a.links) != len(alphabet) {
+			t.Fatal("Should be able to switch to dense links past a threshold", ballotX)
+		}
+		t.Log("Should be able to switch to dense links past a threshold", checkMark)
+
+		if autoComplete.child(a, 'é') != nil || autoComplete.child(a, 'a') == nil {
+			t.Fatal("Should be able to find no dense link for a rune not in the alphabet", ballotX)
+		}
+		t.Log("Should be able to find no dense link for a rune not in the alphabet", checkMark)
+		if autoComplete.Learn("aé") == nil {
+			t.Fatal("Should not be able to link a rune not in the alphabet", ballotX)
+		}
+		t.Log("Should not be able to link a rune not in the alphabet", checkMark)
+
+		autoComplete, _ = NewAutoCompleteTrieS(alphabet, words, 30, 0)
+		ac, _ := autoComplete.Complete("a")
+		sort.Strings(words)
+		if !reflect.DeepEqual(ac, words) {
+			t.Fatal("Should be able to complete in rune order on dense links", ballotX)
+		}
+		autoComplete, _ = NewAutoCompleteTrieS(alphabet, words[:5], 30, 0)
+		ac, _ = autoComplete.Complete("a")
+		if !reflect.DeepEqual(ac, words[:5]) {
+			t.Fatal("Should be able to complete in rune order on sparse links", ballotX)
+		}
+		t.Log("Should be able to complete in rune order", checkMark)
+		autoComplete.UnLearn("ac")
+		ac, _ = autoComplete.Complete("a")
+		if !reflect.DeepEqual(ac, []string{"aa", "ab", "ad", "ae"}) {
+			t.Fatal("Should be able to remove sparse links", ballotX)
+		}
+		t.Log("Should be able to remove sparse links", checkMark)
+	}
+	t.Log("Given the need to test a mixed-script alphabet")
+	{
+		mixedAlphabet := alphabet + "абвгдеёжзийклмнопрстуфхцчшщъыьэюя😀"
+		words := []string{"tea", "teapot", "tea😀", "чай", "чайник"}
+		autoComplete, err := NewAutoCompleteTrieS(mixedAlphabet, words, 0, 0)
+		if err != nil {
+			t.Fatal("Should be able to build a trie on a mixed-script alphabet", ballotX)
+		}
+		ac, _ := autoComplete.Complete("tea")
+		ac2, _ := autoComplete.Complete("ча")
+		if !reflect.DeepEqual(ac, []string{"tea", "tea😀", "teapot"}) || !reflect.DeepEqual(ac2, []string{"чай", "чайник"}) {
+			t.Log(ac, ac2)
+			t.Fatal("Should be able to complete on a mixed-script alphabet", ballotX)
+		}
+		t.Log("Should be able to complete on a mixed-script alphabet", checkMark)
+		if _, err = autoComplete.runesToInts("ⓐ"); err == nil {
+			t.Fatal("Should be able to reject runes outside of the alphabet", ballotX)
+		}
+		t.Log("Should be able to reject runes outside of the alphabet", checkMark)
+	}
+}
+
 func TestTrieCompletion(t *testing.T) {
 
 	words := []string{"aaa", "aaab", "aaac", "aaad", "abbbbb"}
@@ -358,6 +431,10 @@ func TestTrieLearn(t *testing.T) {
 			t.Fatal("Should be able to correctly manage the removed word list for new words", ballotX)
 		}
 		t.Log("Should be able to correctly manage the removed word list for new words", checkMark)
+		if autoComplete.UnLearn("whatever") == nil || len(autoComplete.removedWords) != len(words) {
+			t.Fatal("Should not be able to unlearn a word not in the dictionary", ballotX)
+		}
+		t.Log("Should not be able to unlearn a word not in the dictionary", checkMark)
 	}
 }
 func TestTrieAccept(t *testing.T) {
@@ -407,6 +484,49 @@ func TestTrieResultSizeAndRadius(t *testing.T) {
 	}
 }
 
+func TestTrieCompleteFuzzy(t *testing.T) {
+
+	words := []string{"chai", "chain", "chair", "chairman", "chairperson", "chalk", "cheer", "table"}
+	autoComplete, _ := NewAutoCompleteTrieS(alphabet, words, 0, 0)
+
+	t.Log("Given the need to test the fuzzy completion feature")
+	{
+		ac, err := autoComplete.CompleteFuzzy("chair", 0)
+		if err != nil || !reflect.DeepEqual(ac, []string{"chair", "chairman", "chairperson"}) {
+			t.Fatal("Should be able to complete exactly with no edits", ballotX)
+		}
+		t.Log("Should be able to complete exactly with no edits", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("chiar", 1)
+		if !reflect.DeepEqual(ac, []string{"chair", "chairman", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a stem with transposed runes", ballotX)
+		}
+		t.Log("Should be able to complete a stem with transposed runes", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("chaim", 1)
+		if !reflect.DeepEqual(ac, []string{"chai", "chain", "chair", "chairman", "chairperson"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to complete a stem with a wrong rune", ballotX)
+		}
+		t.Log("Should be able to complete a stem with a wrong rune", checkMark)
+
+		autoComplete.Accept("chairperson")
+		ac, _ = autoComplete.CompleteFuzzy("chairm", 1)
+		if !reflect.DeepEqual(ac, []string{"chairman", "chairperson", "chair"}) {
+			t.Log(ac)
+			t.Fatal("Should be able to prioritize accepted words within the same distance", ballotX)
+		}
+		t.Log("Should be able to prioritize accepted words within the same distance", checkMark)
+
+		ac, _ = autoComplete.CompleteFuzzy("xyz", 1)
+		if !reflect.DeepEqual(ac, []string{}) {
+			t.Fatal("Should be able to return no completions for a distant stem", ballotX)
+		}
+		t.Log("Should be able to return no completions for a distant stem", checkMark)
+	}
+}
+
 func TestTrieSaveRetrieve(t *testing.T) {
 
 	tempDir := os.TempDir()
@@ -416,7 +536,8 @@ func TestTrieSaveRetrieve(t *testing.T) {
 	}
 	fName := tempFile.Name()
 	words := []string{"aaa", "aaabbb", "bbb", "ccc"}
-	autoComplete, _ := NewAutoCompleteTrieS(alphabet, words, 0, 0)
+	learntAt := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	autoComplete, _ := NewAutoCompleteTrieS(alphabet, words, 0, 0, WithClock(func() time.Time { return learntAt }))
 	autoComplete.Accept("aaabbb")
 	autoComplete.Learn("ddd")
 	autoComplete.Learn("eee")
@@ -437,14 +558,20 @@ func TestTrieSaveRetrieve(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		dec := gob.NewDecoder(f)
+		_, body, err := readSaveHeader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec := gob.NewDecoder(body)
 
 		var wA wordAccepts
 		dec.Decode(&wA)
 
 		result1 := wordAccepts{
-			"ddd",
-			0,
+			Word:    "ddd",
+			Accepts: 0,
+			New:     true,
+			Changed: learntAt,
 		}
 		if !reflect.DeepEqual(wA, result1) {
 			t.Fatal("Should be able to read back a saved word", ballotX)
@@ -452,8 +579,10 @@ func TestTrieSaveRetrieve(t *testing.T) {
 		t.Log("Should be able to read back a saved word", checkMark)
 
 		result2 := wordAccepts{
-			"eee",
-			1,
+			Word:    "eee",
+			Accepts: 1,
+			New:     true,
+			Changed: learntAt,
 		}
 		var wA2 wordAccepts
 		dec.Decode(&wA2)
@@ -463,8 +592,8 @@ func TestTrieSaveRetrieve(t *testing.T) {
 		t.Log("Should be able to read back a saved and accepted word", checkMark)
 
 		result3 := wordAccepts{
-			"aaabbb",
-			1,
+			Word:    "aaabbb",
+			Accepts: 1,
 		}
 		var wA3 wordAccepts
 		dec.Decode(&wA3)
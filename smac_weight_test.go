@@ -0,0 +1,162 @@
+// Copyright Piero de Salvia.
+// All Rights Reserved
+
+package smac
+
+import (
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func weightEngines(fileName string, options ...Option) (map[string]AutoComplete, error) {
+
+	lino, err := NewAutoCompleteLinoF(fileName, 2, 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	trie, err := NewAutoCompleteTrieF("abcdefghijklmnopqrstuvwxyz", fileName, 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	radix, err := NewAutoCompleteRadixF(fileName, 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	automaton, err := NewAutoCompleteFSTF(fileName, 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton}, nil
+}
+
+func TestWeights(t *testing.T) {
+
+	dir, err := ioutil.TempDir(os.TempDir(), "smac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsv := dir + "/weights.tsv"
+	ioutil.WriteFile(tsv, []byte("then\t100\nthe\t2000\nthey\t50\nthis\t500\nthat\t1e3\nthou\n"), 0644)
+	csv := dir + "/weights.csv"
+	ioutil.WriteFile(csv, []byte("then,100\n\"the\",2000,\"article, definite\"\nthey,50\nthis,500\nthat,1e3\nthou\n"), 0644)
+	bad := dir + "/bad.tsv"
+	ioutil.WriteFile(bad, []byte("the\t2000\nthat\tmany\n"), 0644)
+
+	t.Log("Given the need to rank words by their frequency in a corpus")
+	{
+		tsvEngines, err := weightEngines(tsv, WithWeightSeparator("\t"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		csvEngines, err := weightEngines(csv, WithCSV())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, autoComplete := range tsvEngines {
+			ac, _ := autoComplete.Complete("th")
+			if !reflect.DeepEqual(ac, []string{"the", "that", "this", "then", "they", "thou"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to rank words by the weights of a TSV dictionary on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank words by the weights of a TSV dictionary on a "+name+" autocompleter", checkMark)
+
+			ac, _ = csvEngines[name].Complete("th")
+			results, _ := csvEngines[name].CompleteScored("the", CompleteOptions{Limit: 1})
+			if !reflect.DeepEqual(ac, []string{"the", "that", "this", "then", "they", "thou"}) || results[0].Payload != "article, definite" {
+				t.Log(ac, results)
+				t.Fatal("Should be able to load weights and payloads from a CSV dictionary on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to load weights and payloads from a CSV dictionary on a "+name+" autocompleter", checkMark)
+
+			ac, _ = autoComplete.CompleteWith("th", CompleteOptions{NoBoost: true})
+			if len(ac) != 6 || ac[2] != "then" {
+				t.Log(ac)
+				t.Fatal("Should be able to ignore weights with NoBoost on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to ignore weights with NoBoost on a "+name+" autocompleter", checkMark)
+
+			autoComplete.Accept("they")
+			autoComplete.SetWeight("then", 5000)
+			autoComplete.Learn("thy")
+			autoComplete.SetWeight("thy", 700)
+			if autoComplete.SetWeight("thee", 1) == nil {
+				t.Fatal("Should not be able to set the weight of a word not in the dictionary on a "+name+" autocompleter", ballotX)
+			}
+			ac, _ = autoComplete.Complete("th")
+			if !reflect.DeepEqual(ac, []string{"they", "then", "the", "that", "thy", "this", "thou"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to rank accepted words first, then words by their weights on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank accepted words first, then words by their weights on a "+name+" autocompleter", checkMark)
+		}
+
+		retrieved, _ := weightEngines(tsv, WithWeightSeparator("\t"))
+		for name, autoComplete := range tsvEngines {
+			fileName := dir + "/" + name
+			if err = autoComplete.Save(fileName); err != nil {
+				t.Fatal(err)
+			}
+			f, _ := os.Open(fileName)
+			_, body, err := readSaveHeader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := gob.NewDecoder(body)
+			saved := make(map[string]wordAccepts)
+			for {
+				var wA wordAccepts
+				if err = dec.Decode(&wA); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				saved[wA.Word] = wA
+			}
+			f.Close()
+			if len(saved) != 3 || saved["they"].Accepts != 1 || saved["they"].Weight != nil || *saved["then"].Weight != 5000 || *saved["thy"].Weight != 700 {
+				t.Log(saved)
+				t.Fatal("Should be able to save only the weights set on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to save only the weights set on a "+name+" autocompleter", checkMark)
+
+			if err = retrieved[name].Retrieve(fileName); err != nil {
+				t.Fatal(err)
+			}
+			ac, _ := retrieved[name].Complete("th")
+			if !reflect.DeepEqual(ac, []string{"they", "then", "the", "that", "thy", "this", "thou"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to retrieve the weights set on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to retrieve the weights set on a "+name+" autocompleter", checkMark)
+		}
+
+		if _, err = weightEngines(bad, WithWeightSeparator("\t")); err == nil {
+			t.Fatal("Should not be able to load a dictionary with an invalid weight", ballotX)
+		}
+		t.Log("Should not be able to load a dictionary with an invalid weight", checkMark)
+
+		var records []Record
+		for _, word := range []string{"ta", "tb", "tc", "td", "te", "tf"} {
+			records = append(records, Record{Word: word, Weight: 1})
+		}
+		records = append(records, Record{Word: "the", Weight: 23135851162})
+		lino, _ := NewAutoCompleteLinoP(records, 2, 3, 10)
+		trie, _ := NewAutoCompleteTrieP("abcdefghijklmnopqrstuvwxyz", records, 3, 10)
+		radix, _ := NewAutoCompleteRadixP(records, 3, 10)
+		automaton, _ := NewAutoCompleteFSTP(records, 3, 10)
+		for name, autoComplete := range map[string]AutoComplete{"LiNo": &lino, "trie": &trie, "radix": &radix, "FST": &automaton} {
+			ac, _ := autoComplete.Complete("t")
+			if !reflect.DeepEqual(ac, []string{"the", "ta", "tb"}) {
+				t.Log(ac)
+				t.Fatal("Should be able to rank a heavy word found past the result size first on a "+name+" autocompleter", ballotX)
+			}
+			t.Log("Should be able to rank a heavy word found past the result size first on a "+name+" autocompleter", checkMark)
+		}
+	}
+}